@@ -0,0 +1,507 @@
+package ocfl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"maps"
+	"path"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	bagDeclaration       = "bagit.txt"
+	bagInfoFile          = "bag-info.txt"
+	bagPayloadDir        = "data"
+	bagManifestPrefix    = "manifest-"
+	bagTagManifestPrefix = "tagmanifest-"
+	bagTxtSuffix         = ".txt"
+	bagDateFormat        = "2006-01-02"
+)
+
+// ErrNotBagIt is returned when a directory is not a BagIt bag.
+var ErrNotBagIt = errors.New("not a BagIt bag")
+
+// StageBagIt builds a stage from the payload (the data directory) of the BagIt
+// bag in dir. Digests for the stage state are taken from the bag's payload
+// manifest for sha512 or sha256 (sha512 is preferred). Digests from other
+// payload manifests for known algorithms (e.g., md5 and sha1) are used as
+// fixity. The payload files are not read unless [BagItVerify] is used. An error
+// is returned if the bag's payload manifests and its data directory don't
+// include the same files.
+func StageBagIt(ctx context.Context, fsys ocflfs.FS, dir string, opts ...BagItOption) (*Stage, error) {
+	conf := newBagItConfig(opts...)
+	if _, err := ocflfs.StatFile(ctx, fsys, path.Join(dir, bagDeclaration)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			err = fmt.Errorf("%w: missing %s", ErrNotBagIt, bagDeclaration)
+		}
+		return nil, err
+	}
+	manifests, tagManifests, err := readBagManifests(ctx, fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	alg := conf.alg
+	if alg == nil {
+		switch {
+		case manifests[digest.SHA512.ID()] != nil:
+			alg = digest.SHA512
+		case manifests[digest.SHA256.ID()] != nil:
+			alg = digest.SHA256
+		default:
+			return nil, errors.New("bag doesn't include a sha512 or sha256 payload manifest")
+		}
+	}
+	if alg.ID() != digest.SHA512.ID() && alg.ID() != digest.SHA256.ID() {
+		return nil, fmt.Errorf("bag digest algorithm must be sha512 or sha256, not %q", alg.ID())
+	}
+	primary := manifests[alg.ID()]
+	if primary == nil {
+		return nil, fmt.Errorf("bag doesn't include a %s payload manifest", alg.ID())
+	}
+	payloadDir := path.Join(dir, bagPayloadDir)
+	files := make(map[string]*digest.FileRef, len(primary))
+	for name, dig := range primary {
+		files[name] = &digest.FileRef{
+			FileRef: ocflfs.FileRef{
+				FS:      fsys,
+				BaseDir: payloadDir,
+				Path:    name,
+			},
+			Digests: digest.Set{alg.ID(): dig},
+		}
+	}
+	for fixAlg, manifest := range manifests {
+		if fixAlg == alg.ID() {
+			continue
+		}
+		if len(manifest) != len(primary) {
+			return nil, fmt.Errorf("bag's %s and %s payload manifests have different files", alg.ID(), fixAlg)
+		}
+		for name, dig := range manifest {
+			file := files[name]
+			if file == nil {
+				return nil, fmt.Errorf("bag's %s payload manifest includes file not in %s manifest: %q", fixAlg, alg.ID(), name)
+			}
+			if file.Fixity == nil {
+				file.Fixity = digest.Set{}
+			}
+			file.Fixity[fixAlg] = dig
+		}
+	}
+	// check that the payload manifest and the data directory are consistent
+	found := 0
+	for ref, err := range ocflfs.WalkFiles(ctx, fsys, payloadDir) {
+		if err != nil {
+			return nil, err
+		}
+		file := files[ref.Path]
+		if file == nil {
+			return nil, fmt.Errorf("bag payload file is not in the %s manifest: %q", alg.ID(), ref.Path)
+		}
+		file.Info = ref.Info
+		found++
+	}
+	if found != len(files) {
+		for _, name := range slices.Sorted(maps.Keys(files)) {
+			if files[name].Info == nil {
+				return nil, &fs.PathError{Op: "stat", Path: files[name].FullPath(), Err: fs.ErrNotExist}
+			}
+		}
+	}
+	if conf.verify {
+		if err := verifyBagFiles(ctx, maps.Values(files), conf); err != nil {
+			return nil, err
+		}
+		for tagAlg, manifest := range tagManifests {
+			tagFiles := make([]*digest.FileRef, 0, len(manifest))
+			for name, dig := range manifest {
+				tagFiles = append(tagFiles, &digest.FileRef{
+					FileRef: ocflfs.FileRef{FS: fsys, BaseDir: dir, Path: name},
+					Digests: digest.Set{tagAlg: dig},
+				})
+			}
+			if err := verifyBagFiles(ctx, slices.Values(tagFiles), conf); err != nil {
+				return nil, err
+			}
+		}
+	}
+	stage, err := newStage(maps.Values(files), alg)
+	if err != nil {
+		return nil, err
+	}
+	return stage, nil
+}
+
+// verifyBagFiles validates the digests for all files and returns an error
+// for the first invalid file.
+func verifyBagFiles(ctx context.Context, files iter.Seq[*digest.FileRef], conf *bagItConfig) error {
+	for err := range digest.ValidateFilesBatch(ctx, files, digest.DefaultRegistry(), conf.gos) {
+		return fmt.Errorf("verifying bag: %w", err)
+	}
+	return nil
+}
+
+// readBagManifests reads all payload and tag manifests for known digest
+// algorithms in the bag in dir. Manifests are indexed by algorithm id and map
+// file paths to digests. Payload manifest paths are relative to the bag's data
+// directory.
+func readBagManifests(ctx context.Context, fsys ocflfs.FS, dir string) (payload, tag map[string]map[string]string, err error) {
+	entries, err := ocflfs.ReadDir(ctx, fsys, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	payload = map[string]map[string]string{}
+	tag = map[string]map[string]string{}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, bagTxtSuffix) {
+			continue
+		}
+		var algID string
+		var isTag bool
+		switch {
+		case strings.HasPrefix(name, bagManifestPrefix):
+			algID = strings.TrimSuffix(strings.TrimPrefix(name, bagManifestPrefix), bagTxtSuffix)
+		case strings.HasPrefix(name, bagTagManifestPrefix):
+			algID = strings.TrimSuffix(strings.TrimPrefix(name, bagTagManifestPrefix), bagTxtSuffix)
+			isTag = true
+		default:
+			continue
+		}
+		if _, err := digest.DefaultRegistry().Get(algID); err != nil {
+			// ignore manifests for unknown algorithms
+			continue
+		}
+		b, err := ocflfs.ReadAll(ctx, fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, nil, err
+		}
+		manifest, err := parseBagManifest(b)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+		if isTag {
+			tag[algID] = manifest
+			continue
+		}
+		payloadManifest := make(map[string]string, len(manifest))
+		for file, dig := range manifest {
+			rel, ok := strings.CutPrefix(file, bagPayloadDir+"/")
+			if !ok || !fs.ValidPath(rel) {
+				return nil, nil, fmt.Errorf("invalid payload path in %s: %q", name, file)
+			}
+			payloadManifest[rel] = dig
+		}
+		payload[algID] = payloadManifest
+	}
+	return payload, tag, nil
+}
+
+// parseBagManifest parses the contents of a BagIt manifest, returning a map of
+// (decoded) file paths to lowercase digest values.
+func parseBagManifest(b []byte) (map[string]string, error) {
+	manifest := map[string]string{}
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		// the digest ends at the first space or tab
+		sep := strings.IndexAny(line, " \t")
+		if sep < 1 {
+			return nil, fmt.Errorf("invalid manifest entry on line %d", i+1)
+		}
+		dig, name := line[:sep], strings.TrimLeft(line[sep+1:], " \t")
+		if name == "" {
+			return nil, fmt.Errorf("invalid manifest entry on line %d", i+1)
+		}
+		name = decodeBagPath(name)
+		if _, exists := manifest[name]; exists {
+			return nil, fmt.Errorf("duplicate manifest entry for %q", name)
+		}
+		manifest[name] = strings.ToLower(dig)
+	}
+	return manifest, nil
+}
+
+var (
+	bagPathEncoder = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	bagPathDecoder = strings.NewReplacer("%25", "%", "%0D", "\r", "%0A", "\n", "%0d", "\r", "%0a", "\n")
+)
+
+func encodeBagPath(name string) string { return bagPathEncoder.Replace(name) }
+func decodeBagPath(name string) string { return bagPathDecoder.Replace(name) }
+
+// BagInfo represents the tags in a BagIt bag's bag-info.txt file. Tag labels
+// are mapped to one or more values.
+type BagInfo map[string][]string
+
+// ReadBagInfo reads the bag-info.txt file for the BagIt bag in dir.
+func ReadBagInfo(ctx context.Context, fsys ocflfs.FS, dir string) (BagInfo, error) {
+	b, err := ocflfs.ReadAll(ctx, fsys, path.Join(dir, bagInfoFile))
+	if err != nil {
+		return nil, err
+	}
+	return parseBagInfo(b)
+}
+
+func parseBagInfo(b []byte) (BagInfo, error) {
+	info := BagInfo{}
+	var label string
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			// continuation of the previous value
+			if label == "" {
+				return nil, fmt.Errorf("invalid %s: unexpected continuation on line %d", bagInfoFile, i+1)
+			}
+			vals := info[label]
+			vals[len(vals)-1] += " " + strings.TrimSpace(line)
+			continue
+		}
+		var val string
+		var ok bool
+		label, val, ok = strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s: missing ':' on line %d", bagInfoFile, i+1)
+		}
+		label = strings.TrimSpace(label)
+		info[label] = append(info[label], strings.TrimSpace(val))
+	}
+	return info, nil
+}
+
+// Get returns the first value for the tag with the given label. Labels are
+// matched case-insensitively. If the tag isn't present, it returns an empty
+// string.
+func (info BagInfo) Get(label string) string {
+	if vals := info[label]; len(vals) > 0 {
+		return vals[0]
+	}
+	for l, vals := range info {
+		if strings.EqualFold(l, label) && len(vals) > 0 {
+			return vals[0]
+		}
+	}
+	return ""
+}
+
+// Message returns a version message from the External-Description or
+// Internal-Sender-Description tags.
+func (info BagInfo) Message() string {
+	if msg := info.Get("External-Description"); msg != "" {
+		return msg
+	}
+	return info.Get("Internal-Sender-Description")
+}
+
+// User returns a version user from the Contact-Name and Contact-Email tags.
+func (info BagInfo) User() User {
+	user := User{Name: info.Get("Contact-Name")}
+	if email := info.Get("Contact-Email"); email != "" {
+		user.Address = "mailto:" + email
+	}
+	return user
+}
+
+// Created returns the Bagging-Date tag value as a time. If the tag is missing
+// or invalid, it returns the zero value.
+func (info BagInfo) Created() time.Time {
+	created, err := time.Parse(bagDateFormat, info.Get("Bagging-Date"))
+	if err != nil {
+		return time.Time{}
+	}
+	return created
+}
+
+// UpdateOptions returns ObjectUpdateOptions using values from the bag info. If
+// the bag info includes a Bagging-Date, the result includes an
+// [UpdateWithVersionCreated] option.
+func (info BagInfo) UpdateOptions() []ObjectUpdateOption {
+	var opts []ObjectUpdateOption
+	if created := info.Created(); !created.IsZero() {
+		opts = append(opts, UpdateWithVersionCreated(created))
+	}
+	return opts
+}
+
+func (info BagInfo) marshal() []byte {
+	var buf bytes.Buffer
+	for _, label := range slices.Sorted(maps.Keys(info)) {
+		for _, val := range info[label] {
+			fmt.Fprintf(&buf, "%s: %s\n", label, val)
+		}
+	}
+	return buf.Bytes()
+}
+
+// ExportBagIt writes the contents of the object version with the given number
+// (1...HEAD) as a BagIt bag in dir. If v < 1, the most recent version is
+// exported. The directory dir must not exist or be empty. The bag's payload
+// manifest uses the object's digest algorithm; additional payload manifests are
+// included for fixity algorithms with values for all content in the version.
+// The bag-info.txt includes the object ID and the version's message and user.
+func (obj *Object) ExportBagIt(ctx context.Context, v int, fsys ocflfs.FS, dir string, opts ...BagItOption) error {
+	conf := newBagItConfig(opts...)
+	ctx = obj.context(ctx)
+	ver := obj.Version(v)
+	if ver == nil {
+		return errors.New("version not found")
+	}
+	entries, err := ocflfs.ReadDir(ctx, fsys, dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("bag directory is not empty: %q", dir)
+	}
+	state := ver.State()
+	alg := obj.DigestAlgorithm()
+	// fixity algorithms with values for all content in the version
+	var fixityAlgs []string
+	for _, fixAlg := range obj.FixityAlgorithms() {
+		if _, err := digest.DefaultRegistry().Get(fixAlg); err != nil {
+			continue
+		}
+		complete := true
+		for dig := range state {
+			if obj.GetFixity(dig)[fixAlg] == "" {
+				complete = false
+				break
+			}
+		}
+		if complete && fixAlg != alg.ID() {
+			fixityAlgs = append(fixityAlgs, fixAlg)
+		}
+	}
+	slices.Sort(fixityAlgs)
+	manifests := map[string]*bytes.Buffer{alg.ID(): {}}
+	for _, fixAlg := range fixityAlgs {
+		manifests[fixAlg] = &bytes.Buffer{}
+	}
+	// copy payload
+	var payloadSize atomic.Int64
+	pathMap := state.PathMap()
+	grp, grpCtx := errgroup.WithContext(ctx)
+	grp.SetLimit(conf.gos)
+	for _, name := range slices.Sorted(maps.Keys(pathMap)) {
+		dig := pathMap[name]
+		srcFS, src := obj.GetContent(dig)
+		if srcFS == nil {
+			grp.Wait()
+			return fmt.Errorf("missing content for %q", name)
+		}
+		payloadName := path.Join(bagPayloadDir, name)
+		fmt.Fprintf(manifests[alg.ID()], "%s  %s\n", dig, encodeBagPath(payloadName))
+		for _, fixAlg := range fixityAlgs {
+			fmt.Fprintf(manifests[fixAlg], "%s  %s\n", obj.GetFixity(dig)[fixAlg], encodeBagPath(payloadName))
+		}
+		grp.Go(func() error {
+			size, err := ocflfs.Copy(grpCtx, fsys, path.Join(dir, payloadName), srcFS, src)
+			payloadSize.Add(size)
+			return err
+		})
+	}
+	if err := grp.Wait(); err != nil {
+		return fmt.Errorf("exporting bag payload: %w", err)
+	}
+	info := BagInfo{
+		"Bagging-Date":        {time.Now().Format(bagDateFormat)},
+		"Payload-Oxum":        {strconv.FormatInt(payloadSize.Load(), 10) + "." + strconv.Itoa(len(pathMap))},
+		"External-Identifier": {obj.ID()},
+	}
+	if msg := ver.Message(); msg != "" {
+		info["External-Description"] = []string{msg}
+	}
+	if user := ver.User(); user != nil {
+		if user.Name != "" {
+			info["Contact-Name"] = []string{user.Name}
+		}
+		if email, ok := strings.CutPrefix(user.Address, "mailto:"); ok && email != "" {
+			info["Contact-Email"] = []string{email}
+		}
+	}
+	tagFiles := map[string][]byte{
+		bagDeclaration: []byte("BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n"),
+		bagInfoFile:    info.marshal(),
+	}
+	for manAlg, buf := range manifests {
+		tagFiles[bagManifestPrefix+manAlg+bagTxtSuffix] = buf.Bytes()
+	}
+	var tagManifest bytes.Buffer
+	for _, name := range slices.Sorted(maps.Keys(tagFiles)) {
+		digester := alg.Digester()
+		if _, err := digester.Write(tagFiles[name]); err != nil {
+			return err
+		}
+		fmt.Fprintf(&tagManifest, "%s  %s\n", digester.String(), name)
+		if _, err := ocflfs.Write(ctx, fsys, path.Join(dir, name), bytes.NewReader(tagFiles[name])); err != nil {
+			return fmt.Errorf("writing bag tag file: %w", err)
+		}
+	}
+	tagManifestName := path.Join(dir, bagTagManifestPrefix+alg.ID()+bagTxtSuffix)
+	if _, err := ocflfs.Write(ctx, fsys, tagManifestName, &tagManifest); err != nil {
+		return fmt.Errorf("writing bag tag manifest: %w", err)
+	}
+	return nil
+}
+
+// BagItOption is used to configure [StageBagIt] and [Object.ExportBagIt].
+type BagItOption func(*bagItConfig)
+
+// BagItDigestAlgorithm sets the digest algorithm (sha512 or sha256) used for
+// the stage's state in [StageBagIt]. The bag must include a payload manifest
+// for the algorithm.
+func BagItDigestAlgorithm(alg digest.Algorithm) BagItOption {
+	return func(conf *bagItConfig) {
+		conf.alg = alg
+	}
+}
+
+// BagItVerify is used with [StageBagIt] to verify that the digests in all
+// payload and tag manifests match the contents of the bag's files.
+func BagItVerify() BagItOption {
+	return func(conf *bagItConfig) {
+		conf.verify = true
+	}
+}
+
+// BagItGoLimit sets the maximum number of files that are digested or copied
+// concurrently. The default is the value from [runtime.GOMAXPROCS](0).
+func BagItGoLimit(gos int) BagItOption {
+	return func(conf *bagItConfig) {
+		if gos > 0 {
+			conf.gos = gos
+		}
+	}
+}
+
+type bagItConfig struct {
+	alg    digest.Algorithm
+	verify bool
+	gos    int
+}
+
+func newBagItConfig(opts ...BagItOption) *bagItConfig {
+	conf := &bagItConfig{gos: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return conf
+}
@@ -0,0 +1,143 @@
+package ocfl_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/local"
+)
+
+func TestBagIt(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	fsys, err := local.NewFS(tmpDir)
+	be.NilErr(t, err)
+	content := map[string][]byte{
+		"README.txt":      []byte("readme"),
+		"a/b/data.csv":    []byte("1,2,3"),
+		"a/b/copy.csv":    []byte("1,2,3"),
+		"name with %.txt": []byte("percent"),
+	}
+	stage, err := ocfl.StageBytes(content, digest.SHA512, digest.MD5)
+	be.NilErr(t, err)
+	obj, err := ocfl.NewObject(ctx, fsys, "object", ocfl.ObjectWithID("object-01"))
+	be.NilErr(t, err)
+	user := ocfl.User{Name: "Tester", Address: "mailto:tester@example.com"}
+	_, err = obj.Update(ctx, stage, "first version", user)
+	be.NilErr(t, err)
+
+	// export the object version as a bag
+	be.NilErr(t, obj.ExportBagIt(ctx, 1, fsys, "bag"))
+	err = obj.ExportBagIt(ctx, 1, fsys, "bag")
+	be.Nonzero(t, err) // directory isn't empty
+
+	info, err := ocfl.ReadBagInfo(ctx, fsys, "bag")
+	be.NilErr(t, err)
+	be.Equal(t, "first version", info.Message())
+	be.Equal(t, user, info.User())
+	be.Equal(t, "object-01", info.Get("External-Identifier"))
+	be.Equal(t, "23.4", info.Get("payload-oxum"))
+	be.False(t, info.Created().IsZero())
+
+	t.Run("stage from exported bag", func(t *testing.T) {
+		bagStage, err := ocfl.StageBagIt(ctx, fsys, "bag", ocfl.BagItVerify())
+		be.NilErr(t, err)
+		be.Equal(t, digest.SHA512.ID(), bagStage.DigestAlgorithm.ID())
+		be.True(t, bagStage.State.Eq(obj.Version(1).State()))
+		for dig := range bagStage.State {
+			be.Nonzero(t, bagStage.GetFixity(dig)[digest.MD5.ID()])
+		}
+		newObj, err := ocfl.NewObject(ctx, fsys, "object-from-bag", ocfl.ObjectWithID("object-02"))
+		be.NilErr(t, err)
+		_, err = newObj.Update(ctx, bagStage, info.Message(), info.User(), info.UpdateOptions()...)
+		be.NilErr(t, err)
+		be.NilErr(t, ocfl.ValidateObject(ctx, fsys, "object-from-bag").Err())
+		be.Equal(t, "first version", newObj.Version(0).Message())
+		be.Equal(t, info.Created(), newObj.Version(0).Created())
+	})
+
+	t.Run("not a bag", func(t *testing.T) {
+		_, err := ocfl.StageBagIt(ctx, fsys, "object")
+		be.True(t, errors.Is(err, ocfl.ErrNotBagIt))
+	})
+
+	t.Run("unlisted payload file", func(t *testing.T) {
+		extra := filepath.Join(tmpDir, "bag", "data", "extra.txt")
+		be.NilErr(t, os.WriteFile(extra, []byte("extra"), 0o644))
+		defer os.Remove(extra)
+		_, err := ocfl.StageBagIt(ctx, fsys, "bag")
+		be.Nonzero(t, err)
+	})
+
+	t.Run("modified payload file", func(t *testing.T) {
+		name := filepath.Join(tmpDir, "bag", "data", "README.txt")
+		be.NilErr(t, os.WriteFile(name, []byte("changed"), 0o644))
+		defer os.WriteFile(name, content["README.txt"], 0o644)
+		// without verify, the change isn't detected
+		_, err := ocfl.StageBagIt(ctx, fsys, "bag")
+		be.NilErr(t, err)
+		_, err = ocfl.StageBagIt(ctx, fsys, "bag", ocfl.BagItVerify())
+		var digestErr *digest.DigestError
+		be.True(t, errors.As(err, &digestErr))
+	})
+
+	t.Run("missing payload file", func(t *testing.T) {
+		name := filepath.Join(tmpDir, "bag", "data", "README.txt")
+		be.NilErr(t, os.Remove(name))
+		defer os.WriteFile(name, content["README.txt"], 0o644)
+		_, err := ocfl.StageBagIt(ctx, fsys, "bag")
+		be.True(t, errors.Is(err, os.ErrNotExist))
+	})
+
+	t.Run("invalid payload path", func(t *testing.T) {
+		dig := obj.Version(1).State().DigestFor("README.txt")
+		bag := ocflfs.NewWrapFS(fstest.MapFS{
+			"bag/bagit.txt":           &fstest.MapFile{Data: []byte("BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n")},
+			"bag/manifest-sha512.txt": &fstest.MapFile{Data: []byte(dig + "  other/README.txt\n")},
+			"bag/other/README.txt":    &fstest.MapFile{Data: content["README.txt"]},
+		})
+		_, err := ocfl.StageBagIt(ctx, bag, "bag")
+		be.Nonzero(t, err)
+		be.In(t, `manifest-sha512.txt: "other/README.txt"`, err.Error())
+	})
+
+	t.Run("tab separator and path with space", func(t *testing.T) {
+		dig := obj.Version(1).State().DigestFor("README.txt")
+		bag := ocflfs.NewWrapFS(fstest.MapFS{
+			"bag/bagit.txt":           &fstest.MapFile{Data: []byte("BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n")},
+			"bag/manifest-sha512.txt": &fstest.MapFile{Data: []byte(dig + "\tdata/read me.txt\n")},
+			"bag/data/read me.txt":    &fstest.MapFile{Data: content["README.txt"]},
+		})
+		bagStage, err := ocfl.StageBagIt(ctx, bag, "bag", ocfl.BagItVerify())
+		be.NilErr(t, err)
+		be.Equal(t, dig, bagStage.State.DigestFor("read me.txt"))
+	})
+}
+
+func TestReadBagInfo(t *testing.T) {
+	ctx := context.Background()
+	data := "Bagging-Date: 2024-02-03\n" +
+		"External-Description: a long description\n" +
+		"  that continues\n" +
+		"Contact-Name: Tester\n" +
+		"Keyword: one\n" +
+		"Keyword: two\n"
+	fsys := ocflfs.NewWrapFS(fstest.MapFS{
+		"bag/bag-info.txt": &fstest.MapFile{Data: []byte(data)},
+	})
+	info, err := ocfl.ReadBagInfo(ctx, fsys, "bag")
+	be.NilErr(t, err)
+	be.Equal(t, "a long description that continues", info.Message())
+	be.Equal(t, ocfl.User{Name: "Tester"}, info.User())
+	be.Equal(t, time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC), info.Created())
+	be.DeepEqual(t, []string{"one", "two"}, info["Keyword"])
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha3"
 	"crypto/sha512"
 	"encoding/hex"
 	"hash"
@@ -28,6 +29,9 @@ const (
 	BLAKE2B_384 = alg("blake2b-384")
 	SHA512_256  = alg("sha512/256")
 	SIZE        = alg("size")
+	SHA3_256    = alg("sha3-256")
+	SHA3_512    = alg("sha3-512")
+	BLAKE3      = alg("blake3")
 )
 
 // Algorithm is implemented by digest algorithms
@@ -51,6 +55,9 @@ var builtInDigesters = map[alg]func() Digester{
 	BLAKE2B_384: func() Digester { return &hashDigester{Hash: mustNewBlake2B(48)} },
 	SHA512_256:  func() Digester { return &hashDigester{Hash: sha512.New512_256()} },
 	SIZE:        func() Digester { return &sizeDigester{} },
+	SHA3_256:    func() Digester { return &hashDigester{Hash: sha3.New256()} },
+	SHA3_512:    func() Digester { return &hashDigester{Hash: sha3.New512()} },
+	BLAKE3:      func() Digester { return newBlake3Digester() },
 }
 
 // alg is a built-in Alg
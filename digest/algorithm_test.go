@@ -0,0 +1,66 @@
+package digest_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go/digest"
+	"lukechampine.com/blake3"
+)
+
+func TestAlgorithm_vectors(t *testing.T) {
+	// input used by the official BLAKE3 test vectors
+	blake3Input := func(size int) []byte {
+		b := make([]byte, size)
+		for i := range b {
+			b[i] = byte(i % 251)
+		}
+		return b
+	}
+	table := []struct {
+		alg    digest.Algorithm
+		input  []byte
+		expect string
+	}{
+		{digest.SHA3_256, []byte{}, "a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a"},
+		{digest.SHA3_256, []byte("abc"), "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"},
+		{digest.SHA3_512, []byte{}, "a69f73cca23a9ac5c8b567dc185a756e97c982164fe25859e0d1dcc1475c80a615b2123af1f5f94c11e3e9402c3ac558f500199d95b6d3e301758586281dcd26"},
+		{digest.SHA3_512, []byte("abc"), "b751850b1a57168a5693cd924b6b096e08f621827444f70d884f5d0240d2712e10e116e9192af3c91a7ec57647e3934057340b4cf408d5a56592f8274eec53f0"},
+		{digest.BLAKE3, blake3Input(0), "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"},
+		{digest.BLAKE3, blake3Input(1), "2d3adedff11b61f14c886e35afa036736dcd87a74d27b5c1510225d0f592e213"},
+		{digest.BLAKE3, blake3Input(1025), "d00278ae47eb27b34faecf67b4fe263f82d5412916c1ffd97c8cb7fb814b8444"},
+		{digest.BLAKE3, blake3Input(8192), "aae792484c8efe4f19e2ca7d371d8c467ffb10748d8a5a1ae579948f718a2a63"},
+		{digest.BLAKE3, blake3Input(31744), "62b6960e1a44bcc1eb1a611a8d6235b6b4b78f32e7abc4fb4c6cdcce94895c47"},
+		{digest.BLAKE3, blake3Input(100000), "d93c23eedaf165a7e0be908ba86f1a7a520d568d2d13cde787c8580c5c72cc54"},
+	}
+	for _, test := range table {
+		// single write and many small writes
+		for _, writeSize := range []int{len(test.input) + 1, 7} {
+			d := test.alg.Digester()
+			for i := 0; i < len(test.input); i += writeSize {
+				_, err := d.Write(test.input[i:min(i+writeSize, len(test.input))])
+				be.NilErr(t, err)
+			}
+			be.Equal(t, test.expect, d.String())
+		}
+		// algorithm is in the default registry
+		_, err := digest.DefaultRegistry().Get(test.alg.ID())
+		be.NilErr(t, err)
+	}
+}
+
+func TestAlgorithm_blake3Large(t *testing.T) {
+	// input larger than the blake3 digester's buffer
+	data := make([]byte, 9<<20+123)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	expect := blake3.Sum256(data)
+	d := digest.BLAKE3.Digester()
+	_, err := io.Copy(d, bytes.NewReader(data))
+	be.NilErr(t, err)
+	be.Equal(t, hex.EncodeToString(expect[:]), d.String())
+}
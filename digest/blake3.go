@@ -0,0 +1,55 @@
+package digest
+
+import (
+	"encoding/hex"
+
+	"lukechampine.com/blake3"
+)
+
+// blake3BufferSize is the number of bytes buffered by the blake3 digester
+// before they are hashed. The BLAKE3 implementation hashes large writes
+// using multiple goroutines, so buffering the relatively small writes
+// typical of io.Copy allows large files to be digested using multiple cores.
+const blake3BufferSize = 4 << 20
+
+// blake3Digester implements Digester for 256-bit BLAKE3 digests.
+type blake3Digester struct {
+	hash *blake3.Hasher
+	buf  []byte
+}
+
+func newBlake3Digester() *blake3Digester {
+	return &blake3Digester{hash: blake3.New(32, nil)}
+}
+
+func (d *blake3Digester) Write(p []byte) (int, error) {
+	l := len(p)
+	if len(d.buf) == 0 && l >= blake3BufferSize {
+		// large writes don't need to be buffered
+		return d.hash.Write(p)
+	}
+	for len(p) > 0 {
+		if d.buf == nil {
+			d.buf = make([]byte, 0, min(blake3BufferSize, max(l, 64*1024)))
+		}
+		n := min(len(p), blake3BufferSize-len(d.buf))
+		d.buf = append(d.buf, p[:n]...)
+		p = p[n:]
+		if len(d.buf) == blake3BufferSize {
+			d.flush()
+		}
+	}
+	return l, nil
+}
+
+func (d *blake3Digester) String() string {
+	d.flush()
+	return hex.EncodeToString(d.hash.Sum(nil))
+}
+
+func (d *blake3Digester) flush() {
+	if len(d.buf) > 0 {
+		d.hash.Write(d.buf)
+		d.buf = d.buf[:0]
+	}
+}
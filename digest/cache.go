@@ -0,0 +1,315 @@
+package digest
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+// Cache is used to store and retrieve digests for files that have been
+// digested previously. Digests are only reused if a file's key (FS, path,
+// size, modification time, and inode) are unchanged.
+type Cache interface {
+	// Get returns digests for the file with the given key. The returned
+	// bool is false if the key is not found.
+	Get(ctx context.Context, key CacheKey) (Set, bool)
+	// Put stores the digests for the file with the given key.
+	Put(ctx context.Context, key CacheKey, digests Set) error
+}
+
+// CacheKey identifies a file's contents in a [Cache].
+type CacheKey struct {
+	FS      string    `json:"fs"`              // identifies the file's FS (see NewCacheKey)
+	Path    string    `json:"path"`            // file's full path in its FS
+	Size    int64     `json:"size"`            // file size in bytes
+	ModTime time.Time `json:"modtime"`         // file modification time
+	Inode   uint64    `json:"inode,omitempty"` // inode number (local files on unix only)
+}
+
+// Eq returns true if k and other identify the same file contents.
+func (k CacheKey) Eq(other CacheKey) bool {
+	return k.FS == other.FS &&
+		k.Path == other.Path &&
+		k.Size == other.Size &&
+		k.ModTime.Equal(other.ModTime) &&
+		k.Inode == other.Inode
+}
+
+// NewCacheKey returns a CacheKey for the file referenced by ref, using its
+// Info. The key's FS identifies ref.FS: local file systems are identified by
+// their root directory (using a Root() method), S3 file systems by their
+// bucket (using a Bucket() method), and HTTP file systems by their base URL
+// (using a BaseURL() method). It returns false if ref.Info is nil or if ref.FS
+// has no stable identity, since keys for those file systems could not be
+// matched reliably after a restart.
+func NewCacheKey(ref *ocflfs.FileRef) (CacheKey, bool) {
+	if ref.Info == nil {
+		return CacheKey{}, false
+	}
+	fsID := cacheFSID(ref.FS)
+	if fsID == "" {
+		return CacheKey{}, false
+	}
+	return CacheKey{
+		FS:      fsID,
+		Path:    ref.FullPath(),
+		Size:    ref.Info.Size(),
+		ModTime: ref.Info.ModTime(),
+		Inode:   fileInode(ref.Info),
+	}, true
+}
+
+// cacheFSID returns the value used to identify fsys in cache keys. It returns
+// an empty string if fsys has no stable identity.
+func cacheFSID(fsys ocflfs.FS) string {
+	switch fsys := fsys.(type) {
+	case interface{ Root() string }:
+		if root := fsys.Root(); root != "" {
+			return "file://" + root
+		}
+	case interface{ Bucket() string }:
+		if bucket := fsys.Bucket(); bucket != "" {
+			return "s3://" + bucket
+		}
+	case interface{ BaseURL() string }:
+		return fsys.BaseURL()
+	}
+	return ""
+}
+
+type cacheCtxKey struct{}
+
+// NewCacheContext returns a new context that carries cache. Functions that
+// digest files, like [DigestFilesBatch], use the cache to look-up digests for
+// unchanged files and store new digests.
+func NewCacheContext(ctx context.Context, cache Cache) context.Context {
+	if cache == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, cacheCtxKey{}, cache)
+}
+
+// CacheFromContext returns the Cache carried by ctx, or nil if ctx doesn't
+// include a cache.
+func CacheFromContext(ctx context.Context) Cache {
+	cache, _ := ctx.Value(cacheCtxKey{}).(Cache)
+	return cache
+}
+
+// FileCache is a [Cache] backed by a file on the local filesystem. Entries are
+// appended to the file as they are added, so digests computed before an
+// interruption are available the next time the cache is opened. FileCache is
+// safe for concurrent use.
+type FileCache struct {
+	mx      sync.Mutex
+	file    *os.File
+	entries map[fileCacheID]fileCacheEntry
+}
+
+// fileCacheID identifies a file in a FileCache.
+type fileCacheID struct {
+	fs   string
+	path string
+}
+
+type fileCacheEntry struct {
+	CacheKey
+	Digests Set `json:"digests"`
+}
+
+func (e fileCacheEntry) id() fileCacheID {
+	return fileCacheID{fs: e.FS, path: e.Path}
+}
+
+// OpenFileCache opens the cache file with the given name, creating it if it
+// doesn't exist. Existing entries in the file are loaded into the cache. If
+// the file includes entries that have been replaced by later entries, or
+// invalid entries, it is compacted: the file is rewritten with only the
+// current entries. The returned *FileCache should be closed when no longer
+// needed.
+func OpenFileCache(name string) (*FileCache, error) {
+	entries, compact, err := readFileCache(name)
+	if err != nil {
+		return nil, fmt.Errorf("reading digest cache: %w", err)
+	}
+	if compact {
+		if err := writeFileCache(name, entries); err != nil {
+			return nil, fmt.Errorf("compacting digest cache: %w", err)
+		}
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileCache{file: f, entries: entries}, nil
+}
+
+// readFileCache reads entries from the cache file. The returned bool is true
+// if the file should be compacted because it includes replaced or invalid
+// entries, or doesn't end with a newline.
+func readFileCache(name string) (map[fileCacheID]fileCacheEntry, bool, error) {
+	entries := map[fileCacheID]fileCacheEntry{}
+	f, err := os.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return entries, false, nil
+		}
+		return nil, false, err
+	}
+	defer f.Close()
+	var compact bool
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var entry fileCacheEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				// the last entry may be incomplete if the process writing
+				// it was interrupted. It is ignored.
+				compact = true
+			} else {
+				if _, exists := entries[entry.id()]; exists {
+					// later entries replace earlier ones.
+					compact = true
+				}
+				entries[entry.id()] = entry
+			}
+			if line[len(line)-1] != '\n' {
+				compact = true
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return entries, compact, nil
+}
+
+// writeFileCache replaces the cache file with one that includes entries. The
+// new file is written to a temporary file that is renamed.
+func writeFileCache(name string, entries map[fileCacheID]fileCacheEntry) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err := tmp.Chmod(0o644); err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	ids := slices.SortedFunc(maps.Keys(entries), func(a, b fileCacheID) int {
+		return cmp.Or(cmp.Compare(a.fs, b.fs), cmp.Compare(a.path, b.path))
+	})
+	for _, id := range ids {
+		b, err := json.Marshal(entries[id])
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Get implements [Cache] for FileCache.
+func (c *FileCache) Get(_ context.Context, key CacheKey) (Set, bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	entry, ok := c.entries[fileCacheID{fs: key.FS, path: key.Path}]
+	if !ok || !entry.CacheKey.Eq(key) {
+		return nil, false
+	}
+	return entry.Digests, true
+}
+
+// Put implements [Cache] for FileCache. Digests are merged with any digests
+// already stored for the key. The new entry is written to the cache file
+// immediately.
+func (c *FileCache) Put(_ context.Context, key CacheKey, digests Set) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.file == nil {
+		return fs.ErrClosed
+	}
+	entry := fileCacheEntry{CacheKey: key, Digests: digests}
+	if prev, ok := c.entries[entry.id()]; ok && prev.CacheKey.Eq(key) {
+		// keep digests for algorithms that aren't in the new set
+		merged := maps.Clone(prev.Digests)
+		maps.Copy(merged, digests)
+		entry.Digests = merged
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := c.file.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("writing digest cache: %w", err)
+	}
+	c.entries[entry.id()] = entry
+	return nil
+}
+
+// Len returns the number of entries in the cache.
+func (c *FileCache) Len() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return len(c.entries)
+}
+
+// Close closes the cache file.
+func (c *FileCache) Close() error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.file == nil {
+		return errors.New("digest cache already closed")
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+// cachedDigests returns digests for all algs from the cache, if all are
+// available.
+func cachedDigests(ctx context.Context, cache Cache, key CacheKey, algs []Algorithm) (Set, bool) {
+	cached, ok := cache.Get(ctx, key)
+	if !ok {
+		return nil, false
+	}
+	result := make(Set, len(algs))
+	for _, alg := range algs {
+		val := cached[alg.ID()]
+		if val == "" {
+			return nil, false
+		}
+		result[alg.ID()] = val
+	}
+	return result, true
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package digest
+
+import "io/fs"
+
+// fileInode returns 0: inode numbers aren't used on this platform.
+func fileInode(fs.FileInfo) uint64 { return 0 }
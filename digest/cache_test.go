@@ -0,0 +1,216 @@
+package digest_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/local"
+)
+
+// putCounter is a digest.Cache that counts calls to Put.
+type putCounter struct {
+	digest.Cache
+	puts atomic.Int64
+}
+
+func (c *putCounter) Put(ctx context.Context, key digest.CacheKey, digests digest.Set) error {
+	c.puts.Add(1)
+	return c.Cache.Put(ctx, key, digests)
+}
+
+func TestFileCache(t *testing.T) {
+	ctx := context.Background()
+	contentDir := t.TempDir()
+	cacheFile := filepath.Join(t.TempDir(), "digests.jsonl")
+	content := map[string]string{
+		"a.txt":     "content a",
+		"b.txt":     "content b",
+		"sub/c.txt": "content c",
+	}
+	for name, data := range content {
+		fullName := filepath.Join(contentDir, filepath.FromSlash(name))
+		be.NilErr(t, os.MkdirAll(filepath.Dir(fullName), 0o755))
+		be.NilErr(t, os.WriteFile(fullName, []byte(data), 0o644))
+	}
+	fsys, err := local.NewFS(contentDir)
+	be.NilErr(t, err)
+
+	// digestAll digests all files in contentDir using the cache file and
+	// returns the number of new cache entries.
+	digestAll := func(t *testing.T) (map[string]digest.Set, int) {
+		t.Helper()
+		fileCache, err := digest.OpenFileCache(cacheFile)
+		be.NilErr(t, err)
+		defer func() { be.NilErr(t, fileCache.Close()) }()
+		cache := &putCounter{Cache: fileCache}
+		ctx := digest.NewCacheContext(ctx, cache)
+		files, walkErr := ocflfs.UntilErr(ocflfs.WalkFiles(ctx, fsys, "."))
+		results := map[string]digest.Set{}
+		for ref, err := range digest.DigestFilesBatch(ctx, files, 2, digest.SHA256, digest.MD5) {
+			be.NilErr(t, err)
+			results[ref.Path] = ref.Digests
+			be.Nonzero(t, ref.Fixity[digest.MD5.ID()])
+		}
+		be.NilErr(t, walkErr())
+		return results, int(cache.puts.Load())
+	}
+
+	first, puts := digestAll(t)
+	be.Equal(t, len(content), puts)
+	be.Equal(t, len(content), len(first))
+
+	// all digests are reused
+	second, puts := digestAll(t)
+	be.Equal(t, 0, puts)
+	be.DeepEqual(t, first, second)
+
+	// change one file
+	be.NilErr(t, os.WriteFile(filepath.Join(contentDir, "a.txt"), []byte("new content"), 0o644))
+	third, puts := digestAll(t)
+	be.Equal(t, 1, puts)
+	be.Unequal(t, first["a.txt"][digest.SHA256.ID()], third["a.txt"][digest.SHA256.ID()])
+	be.Equal(t, first["b.txt"][digest.SHA256.ID()], third["b.txt"][digest.SHA256.ID()])
+
+	// digests for a different algorithm aren't in the cache
+	fileCache, err := digest.OpenFileCache(cacheFile)
+	be.NilErr(t, err)
+	defer fileCache.Close()
+	be.Equal(t, len(content), fileCache.Len())
+	cache := &putCounter{Cache: fileCache}
+	files := ocflfs.Files(fsys, "b.txt")
+	for _, err := range digest.DigestFiles(digest.NewCacheContext(ctx, cache), files, digest.SHA512) {
+		be.NilErr(t, err)
+	}
+	be.Equal(t, 1, cache.puts.Load())
+}
+
+func TestOpenFileCache_truncated(t *testing.T) {
+	ctx := context.Background()
+	// an incomplete entry at the end of the cache file is ignored
+	cacheFile := filepath.Join(t.TempDir(), "digests.jsonl")
+	data := `{"path":"a.txt","size":1,"modtime":"2024-01-01T00:00:00Z","digests":{"sha256":"abc"}}` + "\n" + `{"path":"b.t`
+	be.NilErr(t, os.WriteFile(cacheFile, []byte(data), 0o644))
+	cache, err := digest.OpenFileCache(cacheFile)
+	be.NilErr(t, err)
+	be.Equal(t, 1, cache.Len())
+	be.NilErr(t, cache.Put(ctx, digest.CacheKey{Path: "c.txt", Size: 2}, digest.Set{"sha256": "def"}))
+	be.NilErr(t, cache.Close())
+	cache, err = digest.OpenFileCache(cacheFile)
+	be.NilErr(t, err)
+	defer cache.Close()
+	be.Equal(t, 2, cache.Len())
+	sums, ok := cache.Get(ctx, digest.CacheKey{Path: "c.txt", Size: 2})
+	be.True(t, ok)
+	be.Equal(t, "def", sums["sha256"])
+}
+
+func TestOpenFileCache_compact(t *testing.T) {
+	ctx := context.Background()
+	cacheFile := filepath.Join(t.TempDir(), "digests.jsonl")
+	cache, err := digest.OpenFileCache(cacheFile)
+	be.NilErr(t, err)
+	key := digest.CacheKey{FS: "file:///data", Path: "a.txt", Size: 1}
+	be.NilErr(t, cache.Put(ctx, key, digest.Set{"sha256": "abc"}))
+	be.NilErr(t, cache.Put(ctx, key, digest.Set{"sha256": "def"}))
+	// same path in another FS
+	otherKey := key
+	otherKey.FS = "file:///other"
+	be.NilErr(t, cache.Put(ctx, otherKey, digest.Set{"sha256": "ghi"}))
+	be.NilErr(t, cache.Close())
+	countLines := func() int {
+		b, err := os.ReadFile(cacheFile)
+		be.NilErr(t, err)
+		return strings.Count(string(b), "\n")
+	}
+	be.Equal(t, 3, countLines())
+	// replaced entries are removed when the cache is opened
+	cache, err = digest.OpenFileCache(cacheFile)
+	be.NilErr(t, err)
+	defer cache.Close()
+	be.Equal(t, 2, countLines())
+	be.Equal(t, 2, cache.Len())
+	sums, ok := cache.Get(ctx, key)
+	be.True(t, ok)
+	be.Equal(t, "def", sums["sha256"])
+	sums, ok = cache.Get(ctx, otherKey)
+	be.True(t, ok)
+	be.Equal(t, "ghi", sums["sha256"])
+}
+
+func TestNewCacheKey(t *testing.T) {
+	ctx := context.Background()
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, dir := range []string{dir1, dir2} {
+		name := filepath.Join(dir, "a.txt")
+		be.NilErr(t, os.WriteFile(name, []byte("content"), 0o644))
+		be.NilErr(t, os.Chtimes(name, modTime, modTime))
+	}
+	cacheKey := func(dir string) digest.CacheKey {
+		t.Helper()
+		fsys, err := local.NewFS(dir)
+		be.NilErr(t, err)
+		ref := &ocflfs.FileRef{FS: fsys, Path: "a.txt"}
+		be.NilErr(t, ref.Stat(ctx))
+		key, ok := digest.NewCacheKey(ref)
+		be.True(t, ok)
+		return key
+	}
+	// files with the same path in different file systems have different keys
+	key1, key2 := cacheKey(dir1), cacheKey(dir2)
+	be.Equal(t, key1.Path, key2.Path)
+	be.Unequal(t, key1.FS, key2.FS)
+	be.False(t, key1.Eq(key2))
+	be.True(t, key1.Eq(cacheKey(dir1)))
+
+	t.Run("DirFS", func(t *testing.T) {
+		// keys for DirFS are the same for separate FS values
+		newKey := func() digest.CacheKey {
+			ref := &ocflfs.FileRef{FS: ocflfs.DirFS(dir1), Path: "a.txt"}
+			be.NilErr(t, ref.Stat(ctx))
+			key, ok := digest.NewCacheKey(ref)
+			be.True(t, ok)
+			return key
+		}
+		be.True(t, newKey().Eq(newKey()))
+		be.Equal(t, key1.FS, newKey().FS)
+	})
+
+	t.Run("no stable ID", func(t *testing.T) {
+		fsys := ocflfs.NewWrapFS(os.DirFS(dir1))
+		ref := &ocflfs.FileRef{FS: fsys, Path: "a.txt"}
+		be.NilErr(t, ref.Stat(ctx))
+		_, ok := digest.NewCacheKey(ref)
+		be.False(t, ok)
+	})
+}
+
+func TestFileCache_mergeDigests(t *testing.T) {
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "cache.jsonl")
+	key := digest.CacheKey{FS: "file:///tmp", Path: "a.txt", Size: 7, ModTime: time.Unix(1000, 0)}
+	cache, err := digest.OpenFileCache(name)
+	be.NilErr(t, err)
+	be.NilErr(t, cache.Put(ctx, key, digest.Set{"sha512": "abc"}))
+	be.NilErr(t, cache.Put(ctx, key, digest.Set{"md5": "def"}))
+	want := digest.Set{"sha512": "abc", "md5": "def"}
+	got, ok := cache.Get(ctx, key)
+	be.True(t, ok)
+	be.DeepEqual(t, want, got)
+	be.NilErr(t, cache.Close())
+	// merged digests are persisted
+	cache, err = digest.OpenFileCache(name)
+	be.NilErr(t, err)
+	defer cache.Close()
+	got, ok = cache.Get(ctx, key)
+	be.True(t, ok)
+	be.DeepEqual(t, want, got)
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package digest
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileInode returns the inode number from info, if available.
+func fileInode(info fs.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
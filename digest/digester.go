@@ -5,12 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	iofs "io/fs"
 	"iter"
 	"maps"
 	"strings"
 
 	"github.com/srerickson/ocfl-go/fs"
 	"github.com/srerickson/ocfl-go/internal/pipeline"
+	"github.com/srerickson/ocfl-go/telemetry"
 )
 
 // Digester is an interface used for generating values.
@@ -146,7 +148,15 @@ func (fr *FileRef) Validate(ctx context.Context, reg AlgorithmRegistry) error {
 		return err
 	}
 	defer f.Close()
-	if err := Validate(f, digests, reg); err != nil {
+	size := openFileSize(f)
+	digester := newDigesterForSize(size, reg.GetAny(digests.Algorithms()...)...)
+	readSize, err := copyContent(digester, f, size)
+	telemetry.AddCount(ctx, telemetry.BytesRead, readSize)
+	if err != nil {
+		return err
+	}
+	telemetry.AddCount(ctx, telemetry.DigestsComputed, 1)
+	if err := checkDigests(digester.Sums(), digests); err != nil {
 		var digestErr *DigestError
 		if errors.As(err, &digestErr) {
 			digestErr.Path = fr.FullPath()
@@ -159,6 +169,44 @@ func (fr *FileRef) Validate(ctx context.Context, reg AlgorithmRegistry) error {
 	return nil
 }
 
+// ValidateStored compares fr's digests to digests stored by fr's FS (see
+// [fs.StoredDigestsFS]) without reading the file. It returns true if at least
+// one stored digest was compared to a value in fr. If a stored digest doesn't
+// match, the returned error is a *DigestError. If fr's FS doesn't implement
+// fs.StoredDigestsFS, it returns false and a nil error.
+func (fr *FileRef) ValidateStored(ctx context.Context) (bool, error) {
+	digests, err := fr.allDigests()
+	if err != nil {
+		return false, err
+	}
+	stored, err := fs.StoredDigests(ctx, fr.FS, fr.FullPath())
+	if err != nil {
+		if errors.Is(err, fs.ErrOpUnsupported) {
+			return false, nil
+		}
+		return false, err
+	}
+	var compared bool
+	for alg, storedVal := range stored {
+		expected, ok := digests[alg]
+		if !ok {
+			continue
+		}
+		compared = true
+		if !strings.EqualFold(storedVal, expected) {
+			_, isPrimaryAlg := fr.Digests[alg]
+			return true, &DigestError{
+				Path:     fr.FullPath(),
+				Alg:      alg,
+				Got:      storedVal,
+				Expected: expected,
+				IsFixity: !isPrimaryAlg,
+			}
+		}
+	}
+	return compared, nil
+}
+
 // allDigests returns a Set with all digests in fr (primary digest + fixity). An
 // error is only returned if the fr's fixity also includes a value for
 // fr.Algorithm and it doesn't match fr.Digest.
@@ -187,25 +235,54 @@ func DigestFiles(ctx context.Context, files iter.Seq[*fs.FileRef], alg Algorithm
 // DigestFilesBatch concurrently computes digests for each file in files. The
 // resulting iterator yields digest results or an error if the file could not be
 // digestsed. If numgos is < 1, the value from [runtime.GOMAXPROCS](0) is used.
+// If ctx carries a [Cache] (see [NewCacheContext]), digests for unchanged files
+// are taken from the cache and new digests are added to it.
 func DigestFilesBatch(ctx context.Context, files iter.Seq[*fs.FileRef], numgos int, alg Algorithm, fixityAlgs ...Algorithm) iter.Seq2[*FileRef, error] {
 	algs := make([]Algorithm, 1, 1+len(fixityAlgs))
 	algs[0] = alg
 	algs = append(algs, fixityAlgs...)
+	cache := CacheFromContext(ctx)
 	digestFn := func(ref *fs.FileRef) (*FileRef, error) {
-		f, err := ref.Open(ctx)
-		if err != nil {
-			return nil, err
+		var cacheKey CacheKey
+		var useCache bool
+		if cache != nil {
+			if ref.Info == nil {
+				if err := ref.Stat(ctx); err != nil {
+					return nil, err
+				}
+			}
+			cacheKey, useCache = NewCacheKey(ref)
 		}
-		defer f.Close()
-		digester := NewMultiDigester(algs...)
-		if _, err = io.Copy(digester, f); err != nil {
-			return nil, fmt.Errorf("digesting %s: %w", ref.FullPath(), err)
+		var sums Set
+		if useCache {
+			sums, _ = cachedDigests(ctx, cache, cacheKey, algs)
+		}
+		if sums == nil {
+			f, err := ref.Open(ctx)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			fileSize := openFileSize(f)
+			digester := newDigesterForSize(fileSize, algs...)
+			size, err := copyContent(digester, f, fileSize)
+			telemetry.AddCount(ctx, telemetry.BytesRead, size)
+			if err != nil {
+				return nil, fmt.Errorf("digesting %s: %w", ref.FullPath(), err)
+			}
+			telemetry.AddCount(ctx, telemetry.DigestsComputed, 1)
+			sums = digester.Sums()
+			if useCache {
+				if err := cache.Put(ctx, cacheKey, sums); err != nil {
+					return nil, err
+				}
+			}
 		}
 		fd := &FileRef{
 			FileRef: *ref,
 			Digests: Set{},
 		}
-		for resultAlg, resultSum := range digester.Sums() {
+		for resultAlg, resultSum := range sums {
 			switch resultAlg {
 			case alg.ID():
 				fd.Digests[resultAlg] = resultSum
@@ -244,6 +321,29 @@ func ValidateFilesBatch(ctx context.Context, digests iter.Seq[*FileRef], reg Alg
 	}
 }
 
+// ValidateStoredFilesBatch is like [ValidateFilesBatch], except that files are
+// validated using digests stored by their FS, if possible (see
+// [FileRef.ValidateStored]). Files without stored digests for any of their
+// algorithms are read and digested.
+func ValidateStoredFilesBatch(ctx context.Context, digests iter.Seq[*FileRef], reg AlgorithmRegistry, numgos int) iter.Seq[error] {
+	doDigest := func(f *FileRef) (*FileRef, error) {
+		validated, err := f.ValidateStored(ctx)
+		if validated || err != nil {
+			return f, err
+		}
+		return f, f.Validate(ctx, reg)
+	}
+	return func(yield func(error) bool) {
+		for result := range pipeline.Results(digests, doDigest, numgos) {
+			if result.Err != nil {
+				if !yield(result.Err) {
+					break
+				}
+			}
+		}
+	}
+}
+
 // Validate digests the reader using all algorithms in s found in reg.
 // An error is returned in the resulting digests values conflict with those
 // in s.
@@ -252,9 +352,24 @@ func Validate(r io.Reader, s Set, reg AlgorithmRegistry) error {
 	if _, err := io.Copy(digester, r); err != nil {
 		return err
 	}
-	results := digester.Sums()
-	for _, alg := range results.ConflictsWith(s) {
-		return &DigestError{Alg: alg, Expected: s[alg], Got: results[alg]}
+	return checkDigests(digester.Sums(), s)
+}
+
+// checkDigests returns a *DigestError if any values in results conflict with
+// the expected values.
+func checkDigests(results Set, expected Set) error {
+	for _, alg := range results.ConflictsWith(expected) {
+		return &DigestError{Alg: alg, Expected: expected[alg], Got: results[alg]}
 	}
 	return nil
 }
+
+// openFileSize returns the size of the open file f, or -1 if the size is
+// unknown.
+func openFileSize(f iofs.File) int64 {
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return -1
+	}
+	return info.Size()
+}
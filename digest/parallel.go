@@ -0,0 +1,168 @@
+package digest
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+const (
+	// files at least this large are digested using large buffers, parallel
+	// digesters, and concurrent ranged reads (if supported).
+	largeFileSize = 64 << 20
+	// buffer size used for reading large files
+	largeBufferSize = 4 << 20
+	// number of concurrent ranged reads for large files
+	largeFileReaders = 4
+	// minimum write size for which digesters are run in parallel
+	minParallelWrite = 256 << 10
+)
+
+// NewParallelMultiDigester returns a new MultiDigester that runs each digest
+// algorithm in a separate goroutine. Content written to the MultiDigester is
+// passed to all digesters concurrently, which reduces the time needed to
+// digest large files with multiple algorithms. Small writes are passed to
+// each digester sequentially.
+func NewParallelMultiDigester(algs ...Algorithm) *MultiDigester {
+	writers := make([]io.Writer, 0, len(algs))
+	digesters := make(map[string]Digester, len(algs))
+	for _, alg := range algs {
+		digester := alg.Digester()
+		digesters[alg.ID()] = digester
+		writers = append(writers, digester)
+	}
+	return &MultiDigester{
+		Writer:    &fanOutWriter{writers: writers},
+		digesters: digesters,
+	}
+}
+
+// fanOutWriter is an io.Writer that writes to all writers concurrently.
+type fanOutWriter struct {
+	writers []io.Writer
+}
+
+func (w *fanOutWriter) Write(p []byte) (int, error) {
+	if len(w.writers) < 2 || len(p) < minParallelWrite {
+		for _, wr := range w.writers {
+			if err := writeAll(wr, p); err != nil {
+				return 0, err
+			}
+		}
+		return len(p), nil
+	}
+	errs := make([]error, len(w.writers))
+	var wg sync.WaitGroup
+	for i, wr := range w.writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = writeAll(wr, p)
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func writeAll(w io.Writer, p []byte) error {
+	n, err := w.Write(p)
+	if err != nil {
+		return err
+	}
+	if n != len(p) {
+		return io.ErrShortWrite
+	}
+	return nil
+}
+
+// newDigesterForSize returns a MultiDigester for algs, using a parallel
+// digester for large content.
+func newDigesterForSize(size int64, algs ...Algorithm) *MultiDigester {
+	if size >= largeFileSize && len(algs) > 1 {
+		return NewParallelMultiDigester(algs...)
+	}
+	return NewMultiDigester(algs...)
+}
+
+// copyContent copies content from r, which has the given size, to w. If the
+// size is unknown, it should be -1. Large content is read with a large buffer
+// or, if r is an io.ReaderAt, with concurrent ranged reads.
+func copyContent(w io.Writer, r io.Reader, size int64) (int64, error) {
+	if size < largeFileSize {
+		return io.Copy(w, r)
+	}
+	if ra, ok := r.(io.ReaderAt); ok {
+		return copyReaderAt(w, ra, size, largeBufferSize, largeFileReaders)
+	}
+	// hide r's WriteTo method, which may not use the buffer.
+	readerOnly := struct{ io.Reader }{r}
+	return io.CopyBuffer(w, readerOnly, make([]byte, largeBufferSize))
+}
+
+// copyReaderAt copies size bytes from r to w. Blocks of blockSize bytes are
+// read from r using up to numReaders concurrent calls to ReadAt and written
+// to w in order.
+func copyReaderAt(w io.Writer, r io.ReaderAt, size int64, blockSize int, numReaders int) (int64, error) {
+	type block struct {
+		buf []byte
+		err error
+	}
+	free := make(chan []byte, numReaders)
+	for range numReaders {
+		free <- make([]byte, blockSize)
+	}
+	pending := make(chan chan block, numReaders)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer close(stop)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(pending)
+		for off := int64(0); off < size; off += int64(blockSize) {
+			var buf []byte
+			select {
+			case buf = <-free:
+			case <-stop:
+				return
+			}
+			result := make(chan block, 1)
+			select {
+			case pending <- result:
+			case <-stop:
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				buf = buf[:min(int64(blockSize), size-off)]
+				n, err := r.ReadAt(buf, off)
+				if n == len(buf) {
+					// io.EOF is allowed when reading the last block
+					err = nil
+				} else if err == nil || errors.Is(err, io.EOF) {
+					err = io.ErrUnexpectedEOF
+				}
+				result <- block{buf: buf, err: err}
+			}()
+		}
+	}()
+	var total int64
+	for result := range pending {
+		b := <-result
+		if b.err != nil {
+			return total, b.err
+		}
+		n, err := w.Write(b.buf)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+		free <- b.buf[:cap(b.buf)]
+	}
+	return total, nil
+}
@@ -0,0 +1,68 @@
+package digest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+)
+
+func TestNewParallelMultiDigester(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), minParallelWrite/5)
+	algs := []Algorithm{SHA512, SHA256, MD5, BLAKE3, SIZE}
+	expect := NewMultiDigester(algs...)
+	_, err := expect.Write(data)
+	be.NilErr(t, err)
+	// small and large writes
+	for _, writeSize := range []int{1000, len(data)} {
+		d := NewParallelMultiDigester(algs...)
+		for i := 0; i < len(data); i += writeSize {
+			n, err := d.Write(data[i:min(i+writeSize, len(data))])
+			be.NilErr(t, err)
+			be.Equal(t, min(writeSize, len(data)-i), n)
+		}
+		be.DeepEqual(t, expect.Sums(), d.Sums())
+	}
+}
+
+func TestCopyReaderAt(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 1001)
+	for _, blockSize := range []int{1, 7, 1000, len(data), len(data) + 1} {
+		for _, numReaders := range []int{1, 3} {
+			var out bytes.Buffer
+			n, err := copyReaderAt(&out, bytes.NewReader(data), int64(len(data)), blockSize, numReaders)
+			be.NilErr(t, err)
+			be.Equal(t, int64(len(data)), n)
+			be.DeepEqual(t, data, out.Bytes())
+		}
+	}
+	t.Run("short file", func(t *testing.T) {
+		var out bytes.Buffer
+		_, err := copyReaderAt(&out, bytes.NewReader(data), int64(len(data)+10), 1000, 2)
+		be.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+	})
+	t.Run("read error", func(t *testing.T) {
+		readErr := errors.New("read error")
+		r := errReaderAt{ReaderAt: bytes.NewReader(data), errOffset: 5000, err: readErr}
+		var out bytes.Buffer
+		n, err := copyReaderAt(&out, r, int64(len(data)), 1000, 2)
+		be.True(t, errors.Is(err, readErr))
+		be.Equal(t, int64(5000), n)
+	})
+}
+
+// errReaderAt returns an error for reads at or after errOffset.
+type errReaderAt struct {
+	io.ReaderAt
+	errOffset int64
+	err       error
+}
+
+func (r errReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= r.errOffset {
+		return 0, r.err
+	}
+	return r.ReaderAt.ReadAt(p, off)
+}
@@ -12,7 +12,7 @@ var (
 	ErrMissing = errors.New("missing an expected digest algorithm")
 
 	// built-in Alg register
-	defaultRegister = NewAlgorithmRegistry(SHA512, SHA256, SHA1, MD5, BLAKE2B)
+	defaultRegister = NewAlgorithmRegistry(SHA512, SHA256, SHA1, MD5, BLAKE2B, SHA3_256, SHA3_512, BLAKE3)
 )
 
 // AlgorithmRegistry is an immutable collection of Algorithm indexed by ID.
@@ -121,5 +121,5 @@ func (r AlgorithmRegistry) Len() int {
 }
 
 // DefaultRegistry returns a Register built-in digest algorithms: sha512, sha256,
-// sha1, md5, and blake2b.
+// sha1, md5, blake2b, sha3-256, sha3-512, and blake3.
 func DefaultRegistry() AlgorithmRegistry { return defaultRegister }
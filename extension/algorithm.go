@@ -41,6 +41,9 @@ type alg struct {
 	ext AlgorithmRegistry
 }
 
+// Extension implements Algorithm for alg
+func (a alg) Extension() AlgorithmRegistry { return a.ext }
+
 func getHash(name string) hash.Hash {
 	switch name {
 	case `sha512`:
@@ -0,0 +1,181 @@
+package ocfl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+// EnrichFixity adds fixity values for the digest algorithms with the given ids
+// to the object's inventory. Existing content in the object's manifest without
+// values for the algorithms is digested and a new version with an unchanged
+// state and an enriched fixity block is created. Content is also digested with
+// the object's primary digest algorithm: if the content doesn't match its
+// manifest entry, an error is returned and no version is created. If the
+// object's fixity already includes values for all algorithms, EnrichFixity
+// returns a nil *UpdatePlan and a nil error. Otherwise, the *UpdatePlan used
+// to create the new version is returned, even if an error occurs while
+// applying it. Use a context with a digest cache ([digest.NewCacheContext]) to
+// avoid re-digesting content if the update is interrupted and retried.
+func (obj *Object) EnrichFixity(ctx context.Context, algs []string, msg string, user User, opts ...FixityOption) (*UpdatePlan, error) {
+	conf := newFixityConfig(opts...)
+	if !obj.Exists() {
+		return nil, errors.New("object doesn't exist")
+	}
+	ctx = obj.context(ctx)
+	primary := obj.DigestAlgorithm()
+	registry := conf.registry
+	if registry.Len() == 0 {
+		registry = obj.Algorithms()
+	}
+	var newAlgs []digest.Algorithm
+	for _, id := range algs {
+		if id == primary.ID() {
+			continue
+		}
+		alg, err := registry.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		newAlgs = append(newAlgs, alg)
+	}
+	// content paths (first path only) for digests missing fixity values
+	missing := map[string]string{}
+	for dig, contentPaths := range obj.Manifest() {
+		fixity := obj.GetFixity(dig)
+		for _, alg := range newAlgs {
+			if fixity[alg.ID()] == "" {
+				missing[contentPaths[0]] = dig
+				break
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+	files := func(yield func(*ocflfs.FileRef) bool) {
+		for _, name := range slices.Sorted(maps.Keys(missing)) {
+			ref := &ocflfs.FileRef{FS: obj.fs, BaseDir: obj.path, Path: name}
+			if !yield(ref) {
+				return
+			}
+		}
+	}
+	newFixity := fixityMap{}
+	for ref, err := range digest.DigestFilesBatch(ctx, files, conf.gos, primary, newAlgs...) {
+		if err != nil {
+			return nil, fmt.Errorf("digesting object content: %w", err)
+		}
+		expected := missing[ref.Path]
+		if got := ref.Digests[primary.ID()]; !strings.EqualFold(got, expected) {
+			return nil, &digest.DigestError{
+				Path:     ref.FullPath(),
+				Alg:      primary.ID(),
+				Got:      got,
+				Expected: expected,
+			}
+		}
+		newFixity[expected] = ref.Fixity
+	}
+	stage := obj.VersionStage(0)
+	stage.FixitySource = fixitySources{obj, newFixity}
+	plan, err := obj.NewUpdatePlan(stage, msg, user,
+		UpdateWithUnchangedVersionState(),
+		UpdateWithGoLimit(conf.gos),
+		updateWithAlgorithms(registry),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return plan, obj.ApplyUpdatePlan(ctx, plan, stage.ContentSource)
+}
+
+// EnrichFixityResult is yielded by [Root.EnrichFixity] for each object in the
+// root.
+type EnrichFixityResult struct {
+	Object  *Object // The object (may be nil if the object could not be opened).
+	Updated bool    // If true, a new version was created for the object.
+}
+
+// EnrichFixity calls [Object.EnrichFixity] for every object in the root. The
+// returned iterator yields results and errors for each object. Objects are
+// read concurrently but updated one at a time: the files in each object are
+// digested concurrently (see [FixityWithGoLimit]). Objects that already have
+// fixity values for all algorithms are not updated, so an interrupted
+// EnrichFixity can be resumed by calling it again.
+func (r *Root) EnrichFixity(ctx context.Context, algs []string, msg string, user User, opts ...FixityOption) iter.Seq2[*EnrichFixityResult, error] {
+	conf := newFixityConfig(opts...)
+	return func(yield func(*EnrichFixityResult, error) bool) {
+		for obj, err := range r.ObjectsBatch(ctx, conf.gos) {
+			if err != nil {
+				if !yield(&EnrichFixityResult{}, err) {
+					return
+				}
+				continue
+			}
+			result := &EnrichFixityResult{Object: obj}
+			plan, err := obj.EnrichFixity(ctx, algs, msg, user, opts...)
+			if err != nil {
+				err = fmt.Errorf("enriching fixity for %q: %w", obj.ID(), err)
+			}
+			result.Updated = err == nil && plan != nil
+			if !yield(result, err) {
+				return
+			}
+		}
+	}
+}
+
+// FixityOption is used to configure [Object.EnrichFixity] and
+// [Root.EnrichFixity].
+type FixityOption func(*fixityConfig)
+
+// FixityWithRegistry sets the AlgorithmRegistry used to look-up fixity
+// algorithms. The default is the object's registry (see
+// [ObjectWithAlgorithms]).
+func FixityWithRegistry(reg digest.AlgorithmRegistry) FixityOption {
+	return func(conf *fixityConfig) {
+		conf.registry = reg
+	}
+}
+
+// FixityWithGoLimit sets the maximum number of files that are digested
+// concurrently. With [Root.EnrichFixity], it also sets the number of objects
+// that are read concurrently. The default is the value from
+// [runtime.GOMAXPROCS](0).
+func FixityWithGoLimit(gos int) FixityOption {
+	return func(conf *fixityConfig) {
+		if gos > 0 {
+			conf.gos = gos
+		}
+	}
+}
+
+type fixityConfig struct {
+	registry digest.AlgorithmRegistry
+	gos      int
+}
+
+func newFixityConfig(opts ...FixityOption) *fixityConfig {
+	conf := &fixityConfig{
+		gos: runtime.GOMAXPROCS(0),
+	}
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return conf
+}
+
+// fixityMap is a FixitySource backed by a map of primary digests to fixity
+// values.
+type fixityMap map[string]digest.Set
+
+func (m fixityMap) GetFixity(dig string) digest.Set { return m[dig] }
@@ -0,0 +1,97 @@
+package ocfl_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/digest"
+	"github.com/srerickson/ocfl-go/extension"
+	"github.com/srerickson/ocfl-go/fs/local"
+)
+
+func TestEnrichFixity(t *testing.T) {
+	ctx := context.Background()
+	fsys, err := local.NewFS(t.TempDir())
+	be.NilErr(t, err)
+	root, err := ocfl.NewRoot(ctx, fsys, "root", ocfl.InitRoot(ocfl.Spec1_1, "", extension.Ext0004()))
+	be.NilErr(t, err)
+	user := ocfl.User{Name: "Tester"}
+	for _, id := range []string{"object-1", "object-2"} {
+		obj, err := root.NewObject(ctx, id)
+		be.NilErr(t, err)
+		stage, err := ocfl.StageBytes(map[string][]byte{
+			"a.txt": []byte("content a " + id),
+			"b.txt": []byte("content b"),
+		}, digest.SHA512, digest.MD5)
+		be.NilErr(t, err)
+		_, err = obj.Update(ctx, stage, "v1", user)
+		be.NilErr(t, err)
+	}
+	algs := []string{digest.BLAKE2B.ID(), digest.SIZE.ID(), digest.MD5.ID()}
+	reg := digest.NewAlgorithmRegistry(digest.BLAKE2B, digest.SIZE, digest.MD5)
+
+	t.Run("unknown algorithm", func(t *testing.T) {
+		obj, err := root.NewObject(ctx, "object-1")
+		be.NilErr(t, err)
+		// size isn't in the default registry
+		_, err = obj.EnrichFixity(ctx, algs, "fixity", user)
+		be.Nonzero(t, err)
+	})
+
+	var updated int
+	for result, err := range root.EnrichFixity(ctx, algs, "add fixity", user, ocfl.FixityWithRegistry(reg), ocfl.FixityWithGoLimit(2)) {
+		be.NilErr(t, err)
+		be.True(t, result.Updated)
+		updated++
+		obj := result.Object
+		be.NilErr(t, ocfl.ValidateObject(ctx, fsys, obj.Path()).Err())
+		be.Equal(t, 2, obj.Head().Num())
+		be.True(t, obj.Version(1).State().Eq(obj.Version(2).State()))
+		for dig := range obj.Manifest() {
+			fixity := obj.GetFixity(dig)
+			for _, alg := range algs {
+				be.Nonzero(t, fixity[alg])
+			}
+		}
+	}
+	be.Equal(t, 2, updated)
+
+	// objects are skipped on subsequent runs
+	for result, err := range root.EnrichFixity(ctx, algs, "add fixity", user, ocfl.FixityWithRegistry(reg)) {
+		be.NilErr(t, err)
+		be.False(t, result.Updated)
+		be.Equal(t, 2, result.Object.Head().Num())
+	}
+}
+
+func TestEnrichFixity_extensionConfig(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	fsys, err := local.NewFS(tmpDir)
+	be.NilErr(t, err)
+	root, err := ocfl.NewRoot(ctx, fsys, "root", ocfl.InitRoot(ocfl.Spec1_1, "", extension.Ext0004()))
+	be.NilErr(t, err)
+	user := ocfl.User{Name: "Tester"}
+	obj, err := root.NewObject(ctx, "object-1")
+	be.NilErr(t, err)
+	stage, err := ocfl.StageBytes(map[string][]byte{"a.txt": []byte("content a")}, digest.SHA512)
+	be.NilErr(t, err)
+	_, err = obj.Update(ctx, stage, "v1", user)
+	be.NilErr(t, err)
+	// the size algorithm is defined by extension 0009
+	ext0009 := extension.Ext0009().(extension.AlgorithmRegistry)
+	reg := digest.DefaultRegistry().Append(ext0009.Algorithms().All()...)
+	algs := []string{digest.SIZE.ID()}
+	for result, err := range root.EnrichFixity(ctx, algs, "add fixity", user, ocfl.FixityWithRegistry(reg)) {
+		be.NilErr(t, err)
+		be.True(t, result.Updated)
+		confName := filepath.Join(tmpDir, filepath.FromSlash(result.Object.Path()), "extensions", ext0009.Name(), "config.json")
+		_, err = os.Stat(confName)
+		be.NilErr(t, err)
+		be.NilErr(t, ocfl.ValidateObject(ctx, fsys, result.Object.Path()).Err())
+	}
+}
@@ -0,0 +1,46 @@
+package fs
+
+import (
+	"context"
+	"io/fs"
+)
+
+// StoredDigestsFS is an FS that stores digests (checksums) for files, which
+// can be used to validate content without reading it.
+type StoredDigestsFS interface {
+	FS
+	// StoredDigests returns the digests stored for the named file, indexed by
+	// OCFL digest algorithm ids (e.g., "sha256"). Digest values are hex
+	// encoded. If the file exists but has no stored digests, the returned map
+	// is empty.
+	StoredDigests(ctx context.Context, name string) (map[string]string, error)
+}
+
+// StoredDigests calls StoredDigests if fsys implements StoredDigestsFS. If
+// fsys doesn't implement StoredDigestsFS, it returns an fs.PathError that
+// wraps ErrOpUnsupported.
+func StoredDigests(ctx context.Context, fsys FS, name string) (map[string]string, error) {
+	digestsFS, ok := fsys.(StoredDigestsFS)
+	if !ok {
+		return nil, &fs.PathError{Op: "stored_digests", Path: name, Err: ErrOpUnsupported}
+	}
+	return digestsFS.StoredDigests(ctx, name)
+}
+
+type contentDigestsCtxKey struct{}
+
+// NewContentDigestsContext returns a new context with digests for content that
+// is written using the context. Digests are indexed by OCFL digest algorithm
+// ids (e.g., "sha256") and values are hex encoded. A WriteFS may use the
+// digests to ensure content integrity during writes or to store the digests
+// with the written file (see [StoredDigestsFS]).
+func NewContentDigestsContext(ctx context.Context, digests map[string]string) context.Context {
+	return context.WithValue(ctx, contentDigestsCtxKey{}, digests)
+}
+
+// ContentDigestsFromContext returns the content digests set with
+// [NewContentDigestsContext] or nil.
+func ContentDigestsFromContext(ctx context.Context) map[string]string {
+	digests, _ := ctx.Value(contentDigestsCtxKey{}).(map[string]string)
+	return digests
+}
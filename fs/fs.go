@@ -10,6 +10,8 @@ import (
 	"path"
 	"slices"
 	"strings"
+
+	"github.com/srerickson/ocfl-go/telemetry"
 )
 
 var (
@@ -63,18 +65,41 @@ type CopyFS interface {
 	Copy(ctx context.Context, dst string, src string) (int64, error)
 }
 
-// Copy copies src in srcFS to dst in dstFS. If srcFS and dstFS are the same refererence
-// and it implements CopyFS, then Copy uses the fs's Copy() method.
+// CopyFromFS is a storage backend that supports copying files from other
+// FSs without reading the content (e.g., server-side copies between S3
+// buckets).
+type CopyFromFS interface {
+	WriteFS
+	// CopyFrom creates or updates the file at dst with the contents of src in
+	// srcFS. If dst exists, it should be overwritten. If copying from srcFS is
+	// not supported, the returned error should wrap ErrOpUnsupported.
+	CopyFrom(ctx context.Context, dst string, srcFS FS, src string) (int64, error)
+}
+
+// Copy copies src in srcFS to dst in dstFS. If srcFS and dstFS are the same
+// refererence and it implements CopyFS, then Copy uses the fs's Copy() method.
+// Otherwise, if dstFS implements CopyFromFS and supports copying from srcFS,
+// Copy uses dstFS's CopyFrom() method. If neither applies, the contents of src
+// are read and written to dst.
 func Copy(ctx context.Context, dstFS FS, dst string, srcFS FS, src string) (size int64, err error) {
-	cpFS, ok := dstFS.(CopyFS)
-	// FIXME: better way to compare src and dst FS
-	if ok && dstFS == srcFS {
+	if cpFS, ok := dstFS.(CopyFS); ok && dstFS == srcFS {
 		size, err = cpFS.Copy(ctx, dst, src)
 		if err != nil {
 			err = fmt.Errorf("during copy: %w", err)
 		}
+		telemetry.AddCount(ctx, telemetry.BytesWritten, size)
 		return
 	}
+	if cpFromFS, ok := dstFS.(CopyFromFS); ok {
+		size, err = cpFromFS.CopyFrom(ctx, dst, srcFS, src)
+		if !errors.Is(err, ErrOpUnsupported) {
+			if err != nil {
+				err = fmt.Errorf("during copy: %w", err)
+			}
+			telemetry.AddCount(ctx, telemetry.BytesWritten, size)
+			return
+		}
+	}
 	// otherwise, manual copy
 	var srcF fs.File
 	srcF, err = srcFS.OpenFile(ctx, src)
@@ -184,7 +209,9 @@ func Write(ctx context.Context, fsys FS, name string, r io.Reader) (int64, error
 	if !ok {
 		return 0, &fs.PathError{Op: "write", Path: name, Err: ErrOpUnsupported}
 	}
-	return writeFS.Write(ctx, name, r)
+	size, err := writeFS.Write(ctx, name, r)
+	telemetry.AddCount(ctx, telemetry.BytesWritten, size)
+	return size, err
 }
 
 // StatFile returns file information for the file name in fsys.
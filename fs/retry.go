@@ -0,0 +1,196 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 10 * time.Second
+	defaultRetryMultiplier     = 2.0
+)
+
+// RetryPolicy configures retries for operations that fail with transient
+// errors (e.g., network resets or S3 503 SlowDown responses; see
+// [IsTransientError]). Retries use exponential backoff with optional jitter.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts for an operation, including
+	// the first. If it is less than 2, operations aren't retried.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It defaults to
+	// 100ms.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum delay between attempts. It defaults to 10s.
+	MaxBackoff time.Duration
+	// Multiplier is the factor by which the delay increases after each
+	// attempt. It defaults to 2.
+	Multiplier float64
+	// Jitter is the fraction (0 to 1) of each delay that is randomized. For
+	// example, with a Jitter of 0.5, a 1s delay becomes a random delay
+	// between 0.5s and 1s.
+	Jitter float64
+	// Retryable classifies errors as retryable. If it is nil,
+	// [IsTransientError] is used.
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy returns a new *RetryPolicy with up to 5 attempts and
+// default backoff settings.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: defaultRetryInitialBackoff,
+		MaxBackoff:     defaultRetryMaxBackoff,
+		Multiplier:     defaultRetryMultiplier,
+		Jitter:         0.5,
+	}
+}
+
+// IsRetryable returns true if err should be retried according to the policy's
+// Retryable function. Context cancellation errors are never retried. If p is
+// nil, it returns false.
+func (p *RetryPolicy) IsRetryable(err error) bool {
+	if p == nil || err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsTransientError(err)
+}
+
+// ShouldRetry returns true if an operation that failed with err on the given
+// attempt (starting from 1) should be retried.
+func (p *RetryPolicy) ShouldRetry(err error, attempt int) bool {
+	return p != nil && attempt < p.MaxAttempts && p.IsRetryable(err)
+}
+
+// Backoff returns the delay before the retry following the given attempt
+// (starting from 1).
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	if p == nil || attempt < 1 {
+		return 0
+	}
+	delay := p.InitialBackoff
+	if delay <= 0 {
+		delay = defaultRetryInitialBackoff
+	}
+	maxDelay := p.MaxBackoff
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxBackoff
+	}
+	mult := p.Multiplier
+	if mult < 1 {
+		mult = defaultRetryMultiplier
+	}
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay = time.Duration(float64(delay) * mult)
+	}
+	delay = min(delay, maxDelay)
+	if jitter := min(p.Jitter, 1); jitter > 0 {
+		delay -= time.Duration(jitter * rand.Float64() * float64(delay))
+	}
+	return delay
+}
+
+// Wait blocks for the backoff delay following the given attempt. It returns
+// the context's error if ctx is canceled before the delay.
+func (p *RetryPolicy) Wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.Backoff(attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Do calls fn until it returns nil, it returns an error that isn't retryable,
+// or the maximum number of attempts is reached. It returns the number of
+// attempts and the last error from fn. If p is nil, fn is called once.
+func (p *RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) (int, error) {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if !p.ShouldRetry(err, attempt) {
+			return attempt, err
+		}
+		if waitErr := p.Wait(ctx, attempt); waitErr != nil {
+			return attempt, errors.Join(err, waitErr)
+		}
+	}
+}
+
+// error codes for throttling and temporary service errors returned by S3 and
+// other cloud storage APIs.
+var transientErrorCodes = map[string]bool{
+	"SlowDown":                      true,
+	"Throttling":                    true,
+	"ThrottlingException":           true,
+	"ThrottledException":            true,
+	"RequestThrottled":              true,
+	"RequestThrottledException":     true,
+	"TooManyRequestsException":      true,
+	"ProvisionedThroughputExceeded": true,
+	"RequestLimitExceeded":          true,
+	"RequestTimeout":                true,
+	"RequestTimeoutException":       true,
+	"InternalError":                 true,
+	"ServiceUnavailable":            true,
+}
+
+// IsTransientError returns true if err is likely to be temporary: for
+// example, timeouts, connection resets, errors that report themselves as
+// temporary or retryable, and throttling or service errors from storage APIs.
+// Errors from storage APIs are detected using the ErrorCode() and
+// HTTPStatusCode() methods implemented by AWS SDK errors: throttling error
+// codes (e.g., S3's "SlowDown") and HTTP status codes 429, 500, 502, 503, and
+// 504 are transient.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var retryable interface{ RetryableError() bool }
+	if errors.As(err, &retryable) {
+		return retryable.RetryableError()
+	}
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+	var temporary interface{ Temporary() bool }
+	if errors.As(err, &temporary) && temporary.Temporary() {
+		return true
+	}
+	var coded interface{ ErrorCode() string }
+	if errors.As(err, &coded) && transientErrorCodes[coded.ErrorCode()] {
+		return true
+	}
+	var status interface{ HTTPStatusCode() int }
+	if errors.As(err, &status) {
+		switch status.HTTPStatusCode() {
+		case http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE)
+}
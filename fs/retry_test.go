@@ -0,0 +1,111 @@
+package fs_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/carlmjohnson/be"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := &ocflfs.RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+	}
+	be.Equal(t, 100*time.Millisecond, policy.Backoff(1))
+	be.Equal(t, 200*time.Millisecond, policy.Backoff(2))
+	be.Equal(t, 800*time.Millisecond, policy.Backoff(4))
+	be.Equal(t, time.Second, policy.Backoff(5))
+	be.Equal(t, time.Second, policy.Backoff(50))
+	policy.Jitter = 0.5
+	for range 10 {
+		delay := policy.Backoff(2)
+		be.True(t, delay > 100*time.Millisecond && delay <= 200*time.Millisecond)
+	}
+	var nilPolicy *ocflfs.RetryPolicy
+	be.Equal(t, time.Duration(0), nilPolicy.Backoff(1))
+}
+
+func TestRetryPolicy_Do(t *testing.T) {
+	ctx := context.Background()
+	policy := &ocflfs.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	failing := func(n int, err error) func(context.Context) error {
+		return func(context.Context) error {
+			if n > 0 {
+				n--
+				return err
+			}
+			return nil
+		}
+	}
+	t.Run("transient error", func(t *testing.T) {
+		attempts, err := policy.Do(ctx, failing(2, io.ErrUnexpectedEOF))
+		be.NilErr(t, err)
+		be.Equal(t, 3, attempts)
+	})
+	t.Run("max attempts", func(t *testing.T) {
+		attempts, err := policy.Do(ctx, failing(3, io.ErrUnexpectedEOF))
+		be.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+		be.Equal(t, 3, attempts)
+	})
+	t.Run("not retryable", func(t *testing.T) {
+		attempts, err := policy.Do(ctx, failing(1, fs.ErrNotExist))
+		be.True(t, errors.Is(err, fs.ErrNotExist))
+		be.Equal(t, 1, attempts)
+	})
+	t.Run("custom classifier", func(t *testing.T) {
+		policy := *policy
+		policy.Retryable = func(err error) bool { return errors.Is(err, fs.ErrNotExist) }
+		attempts, err := policy.Do(ctx, failing(1, fs.ErrNotExist))
+		be.NilErr(t, err)
+		be.Equal(t, 2, attempts)
+	})
+	t.Run("nil policy", func(t *testing.T) {
+		var nilPolicy *ocflfs.RetryPolicy
+		attempts, err := nilPolicy.Do(ctx, failing(1, io.ErrUnexpectedEOF))
+		be.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+		be.Equal(t, 1, attempts)
+	})
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		policy := &ocflfs.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour}
+		attempts, err := policy.Do(ctx, failing(3, io.ErrUnexpectedEOF))
+		be.True(t, errors.Is(err, context.Canceled))
+		be.Equal(t, 1, attempts)
+	})
+}
+
+func TestIsTransientError(t *testing.T) {
+	// an S3 error as returned by the AWS SDK
+	s3Err := func(status int, code string) error {
+		return &smithy.OperationError{
+			ServiceID:     "S3",
+			OperationName: "PutObject",
+			Err: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+				Err:      &smithy.GenericAPIError{Code: code},
+			},
+		}
+	}
+	be.True(t, ocflfs.IsTransientError(s3Err(http.StatusServiceUnavailable, "SlowDown")))
+	be.True(t, ocflfs.IsTransientError(&smithy.GenericAPIError{Code: "SlowDown"}))
+	be.True(t, ocflfs.IsTransientError(s3Err(http.StatusInternalServerError, "InternalError")))
+	be.True(t, ocflfs.IsTransientError(s3Err(http.StatusTooManyRequests, "")))
+	be.False(t, ocflfs.IsTransientError(s3Err(http.StatusNotFound, "NoSuchKey")))
+	be.False(t, ocflfs.IsTransientError(s3Err(http.StatusForbidden, "AccessDenied")))
+	be.True(t, ocflfs.IsTransientError(io.ErrUnexpectedEOF))
+	be.False(t, ocflfs.IsTransientError(fs.ErrNotExist))
+	be.False(t, ocflfs.IsTransientError(context.Canceled))
+	// the default policy retries S3 throttling errors
+	be.True(t, ocflfs.DefaultRetryPolicy().ShouldRetry(s3Err(http.StatusServiceUnavailable, "SlowDown"), 1))
+}
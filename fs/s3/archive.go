@@ -0,0 +1,284 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+// matches version directory names
+var versionDirRE = regexp.MustCompile(`^v\d+$`)
+
+// ArchivedError is returned when reading or copying an object that is in an
+// archive storage class (e.g., GLACIER or DEEP_ARCHIVE) and hasn't been
+// restored. It is wrapped in an *fs.PathError with the object's key. Use
+// [BucketFS.RestoreFile] to restore archived objects.
+type ArchivedError struct {
+	// StorageClass is the object's storage class.
+	StorageClass types.StorageClass
+	// Restoring is true if a restore for the object is in progress.
+	Restoring bool
+}
+
+func (e *ArchivedError) Error() string {
+	msg := "object is archived"
+	if e.StorageClass != "" {
+		msg += " in " + string(e.StorageClass) + " storage"
+	}
+	if e.Restoring {
+		return msg + ": restore in progress"
+	}
+	return msg + ": it must be restored before it can be read"
+}
+
+// RestoreStatus indicates whether an object is archived and if its content can
+// be read.
+type RestoreStatus uint8
+
+const (
+	// NotArchived indicates the object isn't archived and can be read.
+	NotArchived RestoreStatus = iota
+	// Archived indicates the object is archived and can't be read until it
+	// is restored.
+	Archived
+	// Restoring indicates the object is archived and a restore is in
+	// progress.
+	Restoring
+	// Restored indicates the object is archived and a restored copy can be
+	// read.
+	Restored
+)
+
+func (s RestoreStatus) String() string {
+	switch s {
+	case NotArchived:
+		return "not archived"
+	case Archived:
+		return "archived"
+	case Restoring:
+		return "restoring"
+	case Restored:
+		return "restored"
+	default:
+		return fmt.Sprintf("RestoreStatus(%d)", uint8(s))
+	}
+}
+
+// RestoreStatus returns the restore status of the named object in the bucket.
+func (f *BucketFS) RestoreStatus(ctx context.Context, name string) (RestoreStatus, error) {
+	f.debugLog(ctx, "s3:restore_status", "bucket", f.bucket, "name", name)
+	head, err := headObject(ctx, f.api, f.bucket, "restore_status", name)
+	if err != nil {
+		return NotArchived, err
+	}
+	return restoreStatus(head), nil
+}
+
+// RestoreFile initiates a restore of the named object if it is archived. The
+// restored copy is available for the given number of days (days is ignored
+// for objects in Intelligent-Tiering archive tiers). Options can be used to
+// modify the RestoreObject request: to set the retrieval tier, for example.
+// If the object isn't archived, or if it has already been restored or is
+// being restored, no request is made. RestoreFile returns the object's status
+// following the restore request. If the object is archived and the BucketFS's
+// client doesn't implement [RestoreAPI], the returned error wraps
+// [ocflfs.ErrOpUnsupported].
+func (f *BucketFS) RestoreFile(ctx context.Context, name string, days int32, opts ...func(*s3.RestoreObjectInput)) (RestoreStatus, error) {
+	const op = "restore"
+	f.debugLog(ctx, "s3:restore", "bucket", f.bucket, "name", name)
+	head, err := headObject(ctx, f.api, f.bucket, op, name)
+	if err != nil {
+		return NotArchived, err
+	}
+	status := restoreStatus(head)
+	if status != Archived {
+		return status, nil
+	}
+	api, ok := f.restoreAPI()
+	if !ok {
+		return status, pathErr(op, name, ocflfs.ErrOpUnsupported)
+	}
+	params := &s3.RestoreObjectInput{
+		Bucket:         &f.bucket,
+		Key:            &name,
+		RestoreRequest: &types.RestoreRequest{},
+	}
+	if head.ArchiveStatus == "" {
+		params.RestoreRequest.Days = &days
+	}
+	for _, o := range opts {
+		if o != nil {
+			o(params)
+		}
+	}
+	if _, err := api.RestoreObject(ctx, params); err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "RestoreAlreadyInProgress" {
+			return Restoring, nil
+		}
+		return status, pathErr(op, name, err)
+	}
+	return Restoring, nil
+}
+
+// WaitRestored checks the restore status of the named objects at the given
+// interval until none of them are being restored. It returns an error if
+// ctx is canceled or if any of the objects are archived without a restore in
+// progress.
+func (f *BucketFS) WaitRestored(ctx context.Context, interval time.Duration, names ...string) error {
+	pending := slices.Clone(names)
+	for {
+		var restoring []string
+		for _, name := range pending {
+			f.debugLog(ctx, "s3:restore_status", "bucket", f.bucket, "name", name)
+			head, err := headObject(ctx, f.api, f.bucket, "restore", name)
+			if err != nil {
+				return err
+			}
+			switch restoreStatus(head) {
+			case Archived:
+				return pathErr("restore", name, &ArchivedError{StorageClass: head.StorageClass})
+			case Restoring:
+				restoring = append(restoring, name)
+			}
+		}
+		if len(restoring) == 0 {
+			return nil
+		}
+		pending = restoring
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ContentStorageClass returns a function for use with [WithStorageClass] that
+// returns class for files in OCFL object version content directories and an
+// empty storage class for all other files (inventories, sidecar files,
+// declarations, extensions, and logs). Files are identified as content using
+// their path relative to the object root, which must be in a subdirectory of a
+// version directory (e.g., "v1/content/file.txt" in "object/v1/content/file.txt").
+// Because the object root isn't known, names that could also be files in an
+// object or storage root that isn't content (e.g.,
+// "layout/v1/object/extensions/ext/config.json") get the empty storage class.
+func ContentStorageClass(class types.StorageClass) func(name string) types.StorageClass {
+	return func(name string) types.StorageClass {
+		if isVersionContent(name) {
+			return class
+		}
+		return ""
+	}
+}
+
+// isVersionContent returns true if name is the path to a file in an object
+// version content directory and it can't be the path to another file in an
+// object root or storage root.
+func isVersionContent(name string) bool {
+	segs := strings.Split(name, "/")
+	var content bool
+	for i := range segs {
+		// segs[:i] is a possible object root or storage root.
+		rel := segs[i:]
+		if isRootFile(rel) {
+			return false
+		}
+		if len(rel) > 2 && versionDirRE.MatchString(rel[0]) {
+			content = true
+		}
+	}
+	return content
+}
+
+// isRootFile returns true if rel, a path relative to an object root or
+// storage root, is an inventory, sidecar, declaration, or a file in an
+// extensions or logs directory.
+func isRootFile(rel []string) bool {
+	switch {
+	case len(rel) == 1:
+		return isInventoryOrDeclaration(rel[0])
+	case len(rel) == 2 && versionDirRE.MatchString(rel[0]):
+		return isInventoryOrDeclaration(rel[1])
+	default:
+		return rel[0] == "extensions" || rel[0] == "logs"
+	}
+}
+
+func isInventoryOrDeclaration(base string) bool {
+	return base == "inventory.json" || strings.HasPrefix(base, "inventory.json.") || strings.HasPrefix(base, "0=")
+}
+
+// restoreStatus returns the restore status for an object based on its
+// storage class, Intelligent-Tiering archive status, and restore header
+// from HeadObject.
+func restoreStatus(head *s3.HeadObjectOutput) RestoreStatus {
+	switch {
+	case head.ArchiveStatus != "":
+		// Intelligent-Tiering objects in archive tiers move back to the
+		// frequent access tier when they are restored.
+		if strings.Contains(aws.ToString(head.Restore), `ongoing-request="true"`) {
+			return Restoring
+		}
+		return Archived
+	case head.StorageClass == types.StorageClassGlacier,
+		head.StorageClass == types.StorageClassDeepArchive:
+		restore := aws.ToString(head.Restore)
+		switch {
+		case strings.Contains(restore, `ongoing-request="true"`):
+			return Restoring
+		case strings.Contains(restore, `ongoing-request="false"`):
+			return Restored
+		}
+		return Archived
+	}
+	return NotArchived
+}
+
+// archivedErr returns an *ArchivedError if err indicates that the object with
+// the given HeadObject output is archived. Otherwise, it returns err.
+func archivedErr(err error, head *s3.HeadObjectOutput) error {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "InvalidObjectState" {
+		return err
+	}
+	archErr := &ArchivedError{}
+	var stateErr *types.InvalidObjectState
+	if errors.As(err, &stateErr) {
+		archErr.StorageClass = stateErr.StorageClass
+	}
+	if head != nil {
+		if archErr.StorageClass == "" {
+			archErr.StorageClass = head.StorageClass
+		}
+		archErr.Restoring = restoreStatus(head) == Restoring
+	}
+	return archErr
+}
+
+// headObject calls HeadObject for the key, returning an *fs.PathError with
+// the given op if it fails.
+func headObject(ctx context.Context, api OpenFileAPI, buck string, op string, key string) (*s3.HeadObjectOutput, error) {
+	if !fs.ValidPath(key) || key == "." {
+		return nil, pathErr(op, key, fs.ErrInvalid)
+	}
+	head, err := api.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &buck, Key: &key})
+	if err != nil {
+		if errIsNotExist(err) {
+			err = fs.ErrNotExist
+		}
+		return nil, pathErr(op, key, err)
+	}
+	return head, nil
+}
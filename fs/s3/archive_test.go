@@ -0,0 +1,68 @@
+package s3_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go/fs/s3"
+	"github.com/srerickson/ocfl-go/fs/s3/internal/mock"
+)
+
+func TestWithStorageClass_Mock(t *testing.T) {
+	ctx := context.Background()
+	api := mock.New(bucket, &mock.Object{Key: "src", Body: []byte("content")})
+	fsys := s3.NewBucketFS(api, bucket,
+		s3.WithStorageClass(s3.ContentStorageClass(types.StorageClassDeepArchive)))
+	write := func(name string, opts ...func(*s3v2.PutObjectInput)) {
+		t.Helper()
+		_, err := fsys.WriteWithOptions(ctx, name, bytes.NewReader([]byte("content")), opts...)
+		be.NilErr(t, err)
+	}
+	write("object/v1/content/file.txt")
+	write("object/v1/inventory.json")
+	write("object/v1/inventory.json.sha512")
+	write("object/0=ocfl_object_1.1")
+	write("object/v2/content/file.txt", func(in *s3v2.PutObjectInput) {
+		in.StorageClass = types.StorageClassStandardIa
+	})
+	be.Equal(t, types.StorageClassDeepArchive, api.UpdatedStorageClasses["object/v1/content/file.txt"])
+	be.Equal(t, "", api.UpdatedStorageClasses["object/v1/inventory.json"])
+	be.Equal(t, "", api.UpdatedStorageClasses["object/v1/inventory.json.sha512"])
+	be.Equal(t, "", api.UpdatedStorageClasses["object/0=ocfl_object_1.1"])
+	be.Equal(t, types.StorageClassStandardIa, api.UpdatedStorageClasses["object/v2/content/file.txt"])
+	_, err := fsys.Copy(ctx, "object/v3/content/copy.txt", "src")
+	be.NilErr(t, err)
+	be.Equal(t, types.StorageClassDeepArchive, api.UpdatedStorageClasses["object/v3/content/copy.txt"])
+}
+
+func TestContentStorageClass(t *testing.T) {
+	class := s3.ContentStorageClass(types.StorageClassGlacier)
+	for name, isContent := range map[string]bool{
+		"v1/content/file.txt":                   true,
+		"root/object/v10/data/a/b/file.txt":     true,
+		"root/object/v1/inventory.json":         false,
+		"root/object/v1/inventory.json.sha512":  false,
+		"root/object/inventory.json":            false,
+		"root/object/0=ocfl_object_1.1":         false,
+		"root/object/extensions/ext/file.txt":   false,
+		"root/extensions/layout/config.json":    false,
+		"root/object/v1/file.txt":               false,
+		"root/object/v1/content/v2/file.txt":    true,
+		"root/v1/object/v1/content/file.txt":    true,
+		"root/v1/object/inventory.json":         false,
+		"root/v1/object/v2/inventory.json":      false,
+		"root/v1/object/extensions/ext/a.json":  false,
+		"root/v1/object/logs/log.txt":           false,
+		"root/v1/extensions/layout/config.json": false,
+	} {
+		expect := types.StorageClass("")
+		if isContent {
+			expect = types.StorageClassGlacier
+		}
+		be.Equal(t, expect, class(name))
+	}
+}
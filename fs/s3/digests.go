@@ -0,0 +1,308 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+const (
+	// DigestKeyPrefix is the prefix for object tag and metadata keys used to
+	// store digests with S3 objects. Keys include the digest algorithm id:
+	// "ocfl-digest-sha512", for example.
+	DigestKeyPrefix = "ocfl-digest-"
+	// DigestETagKey is the object tag key for the object's ETag at the time
+	// the digests in its tags were computed.
+	DigestETagKey = DigestKeyPrefix + "etag"
+
+	maxObjectTags = 10
+)
+
+// DigestOption is used to configure [BucketFS.DigestPrefix].
+type DigestOption func(*digestConfig)
+
+type digestConfig struct {
+	fixity          []digest.Algorithm
+	concurrency     int
+	storeDigests    bool
+	metadataDigests bool
+}
+
+// DigestWithFixity sets additional digest algorithms used to digest objects.
+func DigestWithFixity(algs ...digest.Algorithm) DigestOption {
+	return func(c *digestConfig) {
+		c.fixity = algs
+	}
+}
+
+// DigestWithConcurrency sets the number of S3 objects that are digested
+// concurrently. The default is the value from [runtime.GOMAXPROCS](0).
+func DigestWithConcurrency(n int) DigestOption {
+	return func(c *digestConfig) {
+		c.concurrency = n
+	}
+}
+
+// DigestWithStoreDigests sets whether digests are stored in S3 object tags.
+// Digests in an object's tags are used if they were computed for the object's
+// current ETag, and newly computed digests are added to the object's tags
+// with its ETag, so that changed objects are digested again. Existing object
+// tags are preserved. If an object has too many tags for the digests to be
+// added, digesting fails. This option adds a GetObjectTagging request for each
+// object and a PutObjectTagging request for each object that is digested. It
+// requires a client that implements [TaggingAPI].
+func DigestWithStoreDigests() DigestOption {
+	return func(c *digestConfig) {
+		c.storeDigests = true
+	}
+}
+
+// DigestWithMetadataDigests sets whether digests in S3 objects' user-defined
+// metadata (e.g., "x-amz-meta-ocfl-digest-sha512") are used instead of
+// reading the objects' content. This option adds a HeadObject request for each
+// object without usable digests in its tags.
+func DigestWithMetadataDigests() DigestOption {
+	return func(c *digestConfig) {
+		c.metadataDigests = true
+	}
+}
+
+// DigestPrefix returns an iterator that digests the objects under prefix in
+// f's bucket using alg and any fixity algorithms set with [DigestWithFixity].
+// Objects are digested concurrently and large objects are read with parallel
+// ranged requests. Digests stored with objects can be used instead of reading
+// their content (see [DigestWithStoreDigests] and
+// [DigestWithMetadataDigests]); by default, no requests are made for objects
+// besides listing and reading them. If ctx carries a digest cache (see
+// [digest.NewCacheContext]), it is also used. Objects with hidden names are
+// skipped. The iterator yields an error if the objects can't be listed or
+// digested.
+func (f *BucketFS) DigestPrefix(ctx context.Context, prefix string, alg digest.Algorithm, opts ...DigestOption) iter.Seq2[*digest.FileRef, error] {
+	return func(yield func(*digest.FileRef, error) bool) {
+		var cfg digestConfig
+		for _, opt := range opts {
+			if opt != nil {
+				opt(&cfg)
+			}
+		}
+		f.debugLog(ctx, "s3:digest_prefix", "bucket", f.bucket, "prefix", prefix)
+		cache := &objectDigestCache{
+			api:      f.api,
+			bucket:   f.bucket,
+			algs:     append([]digest.Algorithm{alg}, cfg.fixity...),
+			metadata: cfg.metadataDigests,
+			inner:    digest.CacheFromContext(ctx),
+			etags:    map[string]string{},
+			tags:     map[string][]types.Tag{},
+		}
+		if cfg.storeDigests {
+			tagAPI, ok := f.taggingAPI()
+			if !ok {
+				yield(nil, fmt.Errorf("storing digests in object tags: %w", ocflfs.ErrOpUnsupported))
+				return
+			}
+			cache.tagAPI = tagAPI
+		}
+		files, walkErr := ocflfs.UntilErr(f.WalkFiles(ctx, prefix))
+		files = ocflfs.FilterFiles(files, ocflfs.IsNotHidden)
+		digests := digest.DigestFilesBatch(
+			digest.NewCacheContext(ctx, cache),
+			cache.recordETags(files),
+			cfg.concurrency,
+			alg, cfg.fixity...,
+		)
+		for ref, err := range digests {
+			if !yield(ref, err) || err != nil {
+				return
+			}
+		}
+		if err := walkErr(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// objectDigestCache is a digest.Cache for S3 objects that uses digests stored
+// in object tags (if tagAPI is set) and metadata (if metadata is true). New
+// digests are stored in the object's tags if tagAPI is set.
+type objectDigestCache struct {
+	api      S3API
+	tagAPI   TaggingAPI
+	bucket   string
+	algs     []digest.Algorithm
+	metadata bool
+	inner    digest.Cache // optional cache from the context
+
+	mx    sync.Mutex
+	etags map[string]string      // object key -> ETag from listing
+	tags  map[string][]types.Tag // object key -> tags read by Get
+}
+
+// recordETags returns an iterator that records the ETags for objects in files
+// before yielding them.
+func (c *objectDigestCache) recordETags(files iter.Seq[*ocflfs.FileRef]) iter.Seq[*ocflfs.FileRef] {
+	return func(yield func(*ocflfs.FileRef) bool) {
+		for ref := range files {
+			if ref.Info != nil {
+				if obj, ok := ref.Info.Sys().(*types.Object); ok && obj.ETag != nil {
+					c.mx.Lock()
+					c.etags[ref.FullPath()] = *obj.ETag
+					c.mx.Unlock()
+				}
+			}
+			if !yield(ref) {
+				return
+			}
+		}
+	}
+}
+
+func (c *objectDigestCache) Get(ctx context.Context, key digest.CacheKey) (digest.Set, bool) {
+	if etag := c.etag(key.Path); etag != "" {
+		if c.tagAPI != nil {
+			tagParams := &s3.GetObjectTaggingInput{Bucket: &c.bucket, Key: &key.Path}
+			if tagOut, err := c.tagAPI.GetObjectTagging(ctx, tagParams); err == nil {
+				c.mx.Lock()
+				c.tags[key.Path] = tagOut.TagSet
+				c.mx.Unlock()
+				if digests := tagDigests(tagOut.TagSet, etag); c.hasAlgs(digests) {
+					return digests, true
+				}
+			}
+		}
+		if c.metadata {
+			headParams := &s3.HeadObjectInput{Bucket: &c.bucket, Key: &key.Path}
+			if headOut, err := c.api.HeadObject(ctx, headParams); err == nil && aws.ToString(headOut.ETag) == etag {
+				if digests := metadataDigests(headOut.Metadata); c.hasAlgs(digests) {
+					return digests, true
+				}
+			}
+		}
+	}
+	if c.inner != nil {
+		return c.inner.Get(ctx, key)
+	}
+	return nil, false
+}
+
+func (c *objectDigestCache) Put(ctx context.Context, key digest.CacheKey, digests digest.Set) error {
+	if c.inner != nil {
+		if err := c.inner.Put(ctx, key, digests); err != nil {
+			return err
+		}
+	}
+	etag := c.etag(key.Path)
+	if c.tagAPI == nil || etag == "" {
+		return nil
+	}
+	c.mx.Lock()
+	tags, haveTags := c.tags[key.Path]
+	c.mx.Unlock()
+	if !haveTags {
+		tagParams := &s3.GetObjectTaggingInput{Bucket: &c.bucket, Key: &key.Path}
+		tagOut, err := c.tagAPI.GetObjectTagging(ctx, tagParams)
+		if err != nil {
+			return pathErr("store_digests", key.Path, err)
+		}
+		tags = tagOut.TagSet
+	}
+	newTags := setDigestTags(tags, digests, etag)
+	if len(newTags) > maxObjectTags {
+		return pathErr("store_digests", key.Path, errors.New("too many object tags"))
+	}
+	_, err := c.tagAPI.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+		Bucket:  &c.bucket,
+		Key:     &key.Path,
+		Tagging: &types.Tagging{TagSet: newTags},
+	})
+	if err != nil {
+		return pathErr("store_digests", key.Path, err)
+	}
+	return nil
+}
+
+func (c *objectDigestCache) etag(key string) string {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.etags[key]
+}
+
+func (c *objectDigestCache) hasAlgs(digests digest.Set) bool {
+	for _, alg := range c.algs {
+		if digests[alg.ID()] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// tagDigests returns digests from object tags if the tags include an ETag
+// that matches etag.
+func tagDigests(tags []types.Tag, etag string) digest.Set {
+	digests := digest.Set{}
+	var etagMatch bool
+	for _, tag := range tags {
+		key, val := aws.ToString(tag.Key), aws.ToString(tag.Value)
+		switch {
+		case key == DigestETagKey:
+			etagMatch = val == tagETag(etag)
+		case strings.HasPrefix(key, DigestKeyPrefix):
+			digests[strings.TrimPrefix(key, DigestKeyPrefix)] = strings.ToLower(val)
+		}
+	}
+	if !etagMatch {
+		return nil
+	}
+	return digests
+}
+
+// metadataDigests returns digests from object metadata.
+func metadataDigests(meta map[string]string) digest.Set {
+	digests := digest.Set{}
+	for key, val := range meta {
+		key = strings.ToLower(key)
+		if key == DigestETagKey || !strings.HasPrefix(key, DigestKeyPrefix) {
+			continue
+		}
+		digests[strings.TrimPrefix(key, DigestKeyPrefix)] = strings.ToLower(val)
+	}
+	return digests
+}
+
+// setDigestTags returns a new tag set with existing digest tags in tags
+// replaced by tags for digests and etag. Other tags are unchanged.
+func setDigestTags(tags []types.Tag, digests digest.Set, etag string) []types.Tag {
+	newTags := make([]types.Tag, 0, len(tags)+len(digests)+1)
+	for _, tag := range tags {
+		if !strings.HasPrefix(aws.ToString(tag.Key), DigestKeyPrefix) {
+			newTags = append(newTags, tag)
+		}
+	}
+	newTags = append(newTags, types.Tag{
+		Key:   aws.String(DigestETagKey),
+		Value: aws.String(tagETag(etag)),
+	})
+	for _, alg := range slices.Sorted(maps.Keys(digests)) {
+		newTags = append(newTags, types.Tag{
+			Key:   aws.String(DigestKeyPrefix + alg),
+			Value: aws.String(digests[alg]),
+		})
+	}
+	return newTags
+}
+
+// tagETag returns etag without quotes, which aren't allowed in tag values.
+func tagETag(etag string) string {
+	return strings.Trim(etag, `"`)
+}
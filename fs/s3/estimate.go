@@ -0,0 +1,54 @@
+package s3
+
+import "github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+
+// maximum object size for a single CopyObject request (5 GiB). Larger objects
+// are copied with a multipart copy.
+const maxCopyObjectSize = 5 * 1024 * megabyte
+
+// EstimateUploadCalls adds the expected number of S3 API requests for
+// uploading a file with the given size using the BucketFS's uploader to calls,
+// which is indexed by operation name (e.g., "PutObject"). Files with unknown
+// (negative) sizes are counted as single requests. The estimate doesn't
+// include requests for retries.
+func (f *BucketFS) EstimateUploadCalls(calls map[string]int, size int64) {
+	partSize := f.uploader.PartSize
+	if partSize < manager.MinUploadPartSize {
+		partSize = manager.DefaultUploadPartSize
+	}
+	if size <= partSize {
+		calls["PutObject"]++
+		return
+	}
+	maxParts := f.uploader.MaxUploadParts
+	if maxParts < 1 {
+		maxParts = manager.MaxUploadParts
+	}
+	_, partCount := adjustPartSize(size, partSize, maxParts)
+	calls["CreateMultipartUpload"]++
+	calls["UploadPart"] += int(partCount)
+	calls["CompleteMultipartUpload"]++
+}
+
+// EstimateCopyCalls adds the expected number of S3 API requests for a
+// server-side copy of a file with the given size to calls, which is indexed by
+// operation name (e.g., "CopyObject"). Files with unknown (negative) sizes are
+// counted as single requests. The estimate doesn't include requests for
+// retries or for verifying copied content.
+func (f *BucketFS) EstimateCopyCalls(calls map[string]int, size int64) {
+	calls["HeadObject"]++
+	calls["CopyObject"]++
+	if size <= maxCopyObjectSize {
+		return
+	}
+	// CopyObject fails and the file is copied with a multipart copy.
+	copier := NewMultiCopier(nil, f.multiPartCopyOptions...)
+	partSize := copier.PartSize
+	if partSize < manager.MinUploadPartSize {
+		partSize = defaultCopyPartSize
+	}
+	_, partCount := adjustPartSize(size, partSize, manager.MaxUploadParts)
+	calls["CreateMultipartUpload"]++
+	calls["UploadPartCopy"] += int(partCount)
+	calls["CompleteMultipartUpload"]++
+}
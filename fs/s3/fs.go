@@ -7,22 +7,35 @@ import (
 	"iter"
 	"log/slog"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/telemetry"
 )
 
-// BucketFS implements ocfl.WriteFS, ocfl.CopyFS, and ocfl.ObjectRootIterator
-// for an S3 bucket.
+// BucketFS implements ocfl.WriteFS, ocfl.CopyFS, ocfl.CopyFromFS, and
+// ocfl.ObjectRootIterator for an S3 bucket.
 type BucketFS struct {
 	client               S3API
+	api                  S3API // client used for requests (may be instrumented)
 	bucket               string
 	logger               *slog.Logger
+	telemetry            telemetry.Provider
 	uploader             *manager.Uploader
 	uploaderOptions      []func(*manager.Uploader)
 	multiPartCopyOptions []func(*MultiCopier)
+	storageClass         func(name string) types.StorageClass
+	writeOptions         WriteOptions
+	sseKey               *sseCustomerKey
 }
 
+var (
+	_ ocflfs.StoredDigestsFS = (*BucketFS)(nil)
+	_ ocflfs.CopyFromFS      = (*BucketFS)(nil)
+)
+
 // NewBucketFS returns a new *BucketFS for the given bucket
 func NewBucketFS(client S3API, bucket string, opts ...func(*BucketFS)) *BucketFS {
 	fsys := &BucketFS{
@@ -34,7 +47,18 @@ func NewBucketFS(client S3API, bucket string, opts ...func(*BucketFS)) *BucketFS
 			o(fsys)
 		}
 	}
-	fsys.uploader = manager.NewUploader(client, fsys.uploaderOptions...)
+	fsys.api = client
+	if fsys.sseKey != nil {
+		fsys.api = &sseCustomerAPI{S3API: fsys.api, key: fsys.sseKey}
+	}
+	if fsys.telemetry != nil {
+		fsys.api = &instrumentedAPI{
+			S3API:     fsys.api,
+			bucket:    bucket,
+			telemetry: fsys.telemetry,
+		}
+	}
+	fsys.uploader = manager.NewUploader(fsys.api, fsys.uploaderOptions...)
 	return fsys
 }
 
@@ -47,13 +71,26 @@ func WithLogger(logger *slog.Logger) func(*BucketFS) {
 }
 
 // WithUploaderOptions sets options used to create the s3 manager.Uploader used
-// write files.
+// write files. The uploader's part size is also the size limit for objects
+// written with sha256 checksums (see [BucketFS.Write]).
 func WithUploaderOptions(opts ...func(*manager.Uploader)) func(*BucketFS) {
 	return func(bf *BucketFS) {
 		bf.uploaderOptions = opts
 	}
 }
 
+// WithStorageClass sets a function that returns the storage class used for
+// files written to the bucket (with Write, Copy, or CopyFrom). If the function
+// returns an empty storage class, the bucket's default storage class is used.
+// A storage class set with WriteWithOptions takes precedence. See
+// [ContentStorageClass] for a function that sets a storage class for object
+// content files only.
+func WithStorageClass(fn func(name string) types.StorageClass) func(*BucketFS) {
+	return func(bf *BucketFS) {
+		bf.storageClass = fn
+	}
+}
+
 func WithMultiPartCopyOption(opts ...func(*MultiCopier)) func(*BucketFS) {
 	return func(bf *BucketFS) {
 		bf.multiPartCopyOptions = opts
@@ -72,42 +109,80 @@ func (f *BucketFS) Bucket() string {
 
 func (f *BucketFS) OpenFile(ctx context.Context, name string) (fs.File, error) {
 	f.debugLog(ctx, "s3:openfile", "bucket", f.bucket, "name", name)
-	return openFile(ctx, f.client, f.bucket, name)
+	return openFile(ctx, f.api, f.bucket, name)
 }
 
 func (f *BucketFS) DirEntries(ctx context.Context, dir string) iter.Seq2[fs.DirEntry, error] {
 	f.debugLog(ctx, "s3:readdir", "bucket", f.bucket, "name", dir)
-	return dirEntries(ctx, f.client, f.bucket, dir)
+	return dirEntries(ctx, f.api, f.bucket, dir)
 }
 
+// Write writes the contents of r to the named object in the bucket. If the
+// content's size is known and it is small enough to be uploaded in a single
+// part, the object is written with a sha256 checksum: S3 verifies the checksum
+// and stores it with the object. The checksum is the sha256 value from ctx's
+// content digests (see [ocflfs.NewContentDigestsContext]) or, if there isn't
+// one, it is computed from the content. The size limit is the uploader's part
+// size (5 MiB by default; see [WithUploaderOptions]). Larger objects are
+// uploaded in multiple parts and are written without a checksum, because S3
+// only stores composite sha256 checksums (checksums of part checksums) for
+// multipart uploads. The size is known if r is an [fs.File], a
+// [*bytes.Reader], or an [*io.LimitedReader].
 func (f *BucketFS) Write(ctx context.Context, name string, r io.Reader) (int64, error) {
 	return f.WriteWithOptions(ctx, name, r)
 }
 
-// WriteWithOptions writes with custom optionss.
+// WriteWithOptions writes with custom optionss. The options are applied after
+// the BucketFS's write options (see [WithWriteOptions]), so they can be used to
+// override them.
 func (f *BucketFS) WriteWithOptions(ctx context.Context, name string, r io.Reader, opts ...func(*s3.PutObjectInput)) (int64, error) {
 	f.debugLog(ctx, "s3:write", "bucket", f.bucket, "name", name)
+	opts = append([]func(*s3.PutObjectInput){f.getWriteOptions(ctx, name).applyPut}, opts...)
 	return write(ctx, f.uploader, f.bucket, name, r, opts...)
 }
 
 func (f *BucketFS) Copy(ctx context.Context, dst, src string) (int64, error) {
 	f.debugLog(ctx, "s3:copy", "bucket", f.bucket, "dst", dst, "src", src)
-	return copy(ctx, f.client, f.bucket, dst, src, f.multiPartCopyOptions...)
+	return copy(ctx, f.api, f.bucket, dst, f.bucket, src, f.getWriteOptions(ctx, dst), f.multiPartCopyOptions...)
+}
+
+// CopyFrom copies src in srcFS to dst in f's bucket. If srcFS is a *BucketFS,
+// the object is copied server-side (using CopyObject or a multipart copy),
+// even if srcFS is for a different bucket: the content is never downloaded.
+// f's S3 client must be able to read objects in srcFS's bucket. If srcFS is
+// not a *BucketFS, CopyFrom returns an error wrapping ErrOpUnsupported from
+// the fs package.
+func (f *BucketFS) CopyFrom(ctx context.Context, dst string, srcFS ocflfs.FS, src string) (int64, error) {
+	srcBucketFS, ok := srcFS.(*BucketFS)
+	if !ok || !f.sseKey.equal(srcBucketFS.sseKey) {
+		return 0, &fs.PathError{Op: "copy", Path: src, Err: ocflfs.ErrOpUnsupported}
+	}
+	f.debugLog(ctx, "s3:copy", "bucket", f.bucket, "dst", dst, "src_bucket", srcBucketFS.bucket, "src", src)
+	return copy(ctx, f.api, f.bucket, dst, srcBucketFS.bucket, src, f.getWriteOptions(ctx, dst), f.multiPartCopyOptions...)
+}
+
+// StoredDigests returns digests from the full-object checksums stored with the
+// named object in the bucket. S3 stores sha256 checksums for objects written
+// with [BucketFS.Write] if they are no larger than the uploader's part size.
+// Digests aren't returned for other objects.
+func (f *BucketFS) StoredDigests(ctx context.Context, name string) (map[string]string, error) {
+	f.debugLog(ctx, "s3:stored_digests", "bucket", f.bucket, "name", name)
+	return storedDigests(ctx, f.api, f.bucket, name)
 }
 
 func (f *BucketFS) Remove(ctx context.Context, name string) error {
 	f.debugLog(ctx, "s3:remove", "bucket", f.bucket, "name", name)
-	return remove(ctx, f.client, f.bucket, name)
+	return remove(ctx, f.api, f.bucket, name)
 }
 
 func (f *BucketFS) RemoveAll(ctx context.Context, name string) error {
 	f.debugLog(ctx, "s3:remove_all", "bucket", f.bucket, "name", name)
-	return removeAll(ctx, f.client, f.bucket, name)
+	return removeAll(ctx, f.api, f.bucket, name)
 }
 
 func (f *BucketFS) WalkFiles(ctx context.Context, dir string) iter.Seq2[*ocflfs.FileRef, error] {
 	f.debugLog(ctx, "s3:walkfiles", "bucket", f.bucket, "prefix", dir)
-	files := walkFiles(ctx, f.client, f.bucket, dir)
+	files := walkFiles(ctx, f.api, f.bucket, dir)
 	// The values yielded by walkfiles don't include the FS, we need to
 	// add it here.
 	return func(yield func(*ocflfs.FileRef, error) bool) {
@@ -188,6 +263,81 @@ type FilesAPI interface {
 	ListObjectsV2(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
 }
 
+// TaggingAPI includes S3 methods needed for reading and writing object tags
+// (see [DigestWithStoreDigests]). It is optional: S3API implementations that
+// don't implement it can't be used to store digests in object tags.
+type TaggingAPI interface {
+	GetObjectTagging(context.Context, *s3.GetObjectTaggingInput, ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error)
+	PutObjectTagging(context.Context, *s3.PutObjectTaggingInput, ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error)
+}
+
+// RestoreAPI includes S3 methods needed for RestoreFile(). It is optional:
+// S3API implementations that don't implement it can't be used to restore
+// archived objects.
+type RestoreAPI interface {
+	HeadObject(context.Context, *s3.HeadObjectInput, ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	RestoreObject(context.Context, *s3.RestoreObjectInput, ...func(*s3.Options)) (*s3.RestoreObjectOutput, error)
+}
+
+// taggingAPI returns the TaggingAPI used for f's requests. It returns false if
+// f's client doesn't implement TaggingAPI.
+func (f *BucketFS) taggingAPI() (TaggingAPI, bool) {
+	if _, ok := f.client.(TaggingAPI); !ok {
+		return nil, false
+	}
+	api, ok := f.api.(TaggingAPI)
+	return api, ok
+}
+
+// restoreAPI returns the RestoreAPI used for f's requests. It returns false if
+// f's client doesn't implement RestoreAPI.
+func (f *BucketFS) restoreAPI() (RestoreAPI, bool) {
+	if _, ok := f.client.(RestoreAPI); !ok {
+		return nil, false
+	}
+	api, ok := f.api.(RestoreAPI)
+	return api, ok
+}
+
+// getObjectTagging calls GetObjectTagging if api implements TaggingAPI. It is
+// used by S3API wrappers.
+func getObjectTagging(ctx context.Context, api S3API, in *s3.GetObjectTaggingInput, opts ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	tagAPI, ok := api.(TaggingAPI)
+	if !ok {
+		return nil, pathErr("get_tagging", aws.ToString(in.Key), ocflfs.ErrOpUnsupported)
+	}
+	return tagAPI.GetObjectTagging(ctx, in, opts...)
+}
+
+// putObjectTagging calls PutObjectTagging if api implements TaggingAPI. It is
+// used by S3API wrappers.
+func putObjectTagging(ctx context.Context, api S3API, in *s3.PutObjectTaggingInput, opts ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error) {
+	tagAPI, ok := api.(TaggingAPI)
+	if !ok {
+		return nil, pathErr("put_tagging", aws.ToString(in.Key), ocflfs.ErrOpUnsupported)
+	}
+	return tagAPI.PutObjectTagging(ctx, in, opts...)
+}
+
+// restoreObject calls RestoreObject if api implements RestoreAPI. It is used
+// by S3API wrappers.
+func restoreObject(ctx context.Context, api S3API, in *s3.RestoreObjectInput, opts ...func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+	restoreAPI, ok := api.(RestoreAPI)
+	if !ok {
+		return nil, pathErr("restore", aws.ToString(in.Key), ocflfs.ErrOpUnsupported)
+	}
+	return restoreAPI.RestoreObject(ctx, in, opts...)
+}
+
+// getStorageClass returns the storage class for the named file or an empty
+// value.
+func (f *BucketFS) getStorageClass(name string) types.StorageClass {
+	if f.storageClass == nil {
+		return ""
+	}
+	return f.storageClass(name)
+}
+
 func (fs *BucketFS) debugLog(ctx context.Context, msg string, args ...any) {
 	if fs.logger != nil {
 		fs.logger.DebugContext(ctx, msg, args...)
@@ -4,6 +4,9 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"io"
 	"io/fs"
@@ -12,7 +15,9 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
@@ -41,6 +46,8 @@ var (
 	_ ocflfs.WriteFS      = (*s3.BucketFS)(nil)
 	_ ocflfs.FileWalker   = (*s3.BucketFS)(nil)
 
+	_ ocflfs.StoredDigestsFS = (*s3.BucketFS)(nil)
+
 	fixtures = filepath.Join("..", "..", "testdata", "content-fixture")
 )
 
@@ -463,6 +470,69 @@ func TestWrite_Mock(t *testing.T) {
 	}
 }
 
+func TestWriteChecksum_Mock(t *testing.T) {
+	content := []byte("some content")
+	sum := sha256.Sum256(content)
+	digests := map[string]string{"sha256": hex.EncodeToString(sum[:])}
+	ctx := ocflfs.NewContentDigestsContext(context.Background(), digests)
+	t.Run("checksum sent", func(t *testing.T) {
+		api := mock.New(bucket)
+		fsys := s3.NewBucketFS(api, bucket)
+		_, err := fsys.Write(ctx, "tmp", bytes.NewReader(content))
+		be.NilErr(t, err)
+		be.Equal(t, base64.StdEncoding.EncodeToString(sum[:]), api.UpdatedChecksums["tmp"])
+	})
+	t.Run("checksum mismatch", func(t *testing.T) {
+		api := mock.New(bucket)
+		fsys := s3.NewBucketFS(api, bucket)
+		_, err := fsys.Write(ctx, "tmp", bytes.NewReader([]byte("other content")))
+		be.Nonzero(t, err)
+	})
+	t.Run("no sha256 digest", func(t *testing.T) {
+		// the checksum is computed from the content
+		api := mock.New(bucket)
+		fsys := s3.NewBucketFS(api, bucket)
+		ctx := ocflfs.NewContentDigestsContext(context.Background(), map[string]string{"sha512": "abc"})
+		size, err := fsys.Write(ctx, "tmp", bytes.NewReader(content))
+		be.NilErr(t, err)
+		be.Equal(t, int64(len(content)), size)
+		be.Equal(t, base64.StdEncoding.EncodeToString(sum[:]), api.UpdatedChecksums["tmp"])
+	})
+	t.Run("unknown size", func(t *testing.T) {
+		api := mock.New(bucket)
+		fsys := s3.NewBucketFS(api, bucket)
+		_, err := fsys.Write(context.Background(), "tmp", strings.NewReader(string(content)))
+		be.NilErr(t, err)
+		be.Zero(t, api.UpdatedChecksums["tmp"])
+	})
+}
+
+func TestStoredDigests_Mock(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("content")
+	sum := sha256.Sum256(content)
+	withChecksum := &mock.Object{
+		Key:            "checksum.txt",
+		Body:           content,
+		LastModified:   time.Now(),
+		ChecksumSHA256: base64.StdEncoding.EncodeToString(sum[:]),
+	}
+	noChecksum := &mock.Object{
+		Key:          "no-checksum.txt",
+		Body:         content,
+		LastModified: time.Now(),
+	}
+	fsys := s3.NewBucketFS(mock.New(bucket, withChecksum, noChecksum), bucket)
+	digests, err := ocflfs.StoredDigests(ctx, fsys, withChecksum.Key)
+	be.NilErr(t, err)
+	be.Equal(t, hex.EncodeToString(sum[:]), digests["sha256"])
+	digests, err = ocflfs.StoredDigests(ctx, fsys, noChecksum.Key)
+	be.NilErr(t, err)
+	be.Zero(t, len(digests))
+	_, err = ocflfs.StoredDigests(ctx, fsys, "missing")
+	be.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
 func TestRemove_Mock(t *testing.T) {
 	ctx := context.Background()
 	type testCase struct {
@@ -626,6 +696,72 @@ func TestCopy_Mock(t *testing.T) {
 	}
 }
 
+func TestCopyFrom_Mock(t *testing.T) {
+	ctx := context.Background()
+	srcBucket := "src-bucket"
+	srcBody := mock.RandBytes(int64(51 * megabyte))
+	newAPI := func() *mock.S3API {
+		api := mock.New(bucket)
+		api.AddBucket(srcBucket,
+			&mock.Object{Key: "small", Body: []byte("some content")},
+			&mock.Object{Key: "large", Body: srcBody},
+		)
+		return api
+	}
+	// the source FS's client doesn't have the objects: copying must
+	// be server-side, using the destination FS's client.
+	srcFS := s3.NewBucketFS(mock.New(srcBucket), srcBucket)
+	t.Run("copy object", func(t *testing.T) {
+		api := newAPI()
+		fsys := s3.NewBucketFS(api, bucket)
+		size, err := fsys.CopyFrom(ctx, "dst-file", srcFS, "small")
+		be.NilErr(t, err)
+		be.Equal(t, int64(len("some content")), size)
+		be.Nonzero(t, api.UpdatedETags["dst-file"])
+		be.Equal(t, 0, api.PartCount())
+	})
+	t.Run("multipart copy", func(t *testing.T) {
+		api := newAPI()
+		api.CopyObjectFunc = func(_ context.Context, _ *s3v2.CopyObjectInput, _ ...func(*s3v2.Options)) (*s3v2.CopyObjectOutput, error) {
+			return nil, errors.New("copy source is larger than the maximum allowable size")
+		}
+		fsys := s3.NewBucketFS(api, bucket, s3.WithMultiPartCopyOption(func(mc *s3.MultiCopier) {
+			mc.PartSize = partSize
+		}))
+		size, err := fsys.CopyFrom(ctx, "dst-file", srcFS, "large")
+		be.NilErr(t, err)
+		be.Equal(t, int64(len(srcBody)), size)
+		be.Equal(t, mock.ETag(srcBody, partSize), api.UpdatedETags["dst-file"])
+	})
+	t.Run("with ocflfs.Copy", func(t *testing.T) {
+		api := newAPI()
+		fsys := s3.NewBucketFS(api, bucket)
+		size, err := ocflfs.Copy(ctx, fsys, "dst-file", srcFS, "small")
+		be.NilErr(t, err)
+		be.Equal(t, int64(len("some content")), size)
+		be.Nonzero(t, api.UpdatedETags["dst-file"])
+	})
+	t.Run("missing source", func(t *testing.T) {
+		fsys := s3.NewBucketFS(newAPI(), bucket)
+		_, err := fsys.CopyFrom(ctx, "dst-file", srcFS, "missing")
+		be.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+	t.Run("unsupported source", func(t *testing.T) {
+		api := newAPI()
+		fsys := s3.NewBucketFS(api, bucket)
+		localFS := ocflfs.NewWrapFS(fstest.MapFS{
+			"file": &fstest.MapFile{Data: []byte("local content")},
+		})
+		_, err := fsys.CopyFrom(ctx, "dst-file", localFS, "file")
+		be.True(t, errors.Is(err, ocflfs.ErrOpUnsupported))
+		// ocflfs.Copy falls back to reading and writing the file
+		size, err := ocflfs.Copy(ctx, fsys, "dst-file", localFS, "file")
+		be.NilErr(t, err)
+		be.Equal(t, int64(len("local content")), size)
+		be.Nonzero(t, api.UpdatedETags["dst-file"])
+	})
+}
+
 func TestWalkFiles_Mock(t *testing.T) {
 	ctx := context.Background()
 	type testCase struct {
@@ -897,6 +1033,43 @@ func TestSeekCurrent_Mock(t *testing.T) {
 	be.Equal(t, "FGHIJ", string(buf))
 }
 
+func TestReadAt_Mock(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	obj := &mock.Object{
+		Key:          "alphabet.txt",
+		Body:         content,
+		LastModified: time.Now(),
+	}
+	api := mock.New(bucket, obj)
+	fsys := s3.NewBucketFS(api, bucket)
+	f, err := fsys.OpenFile(ctx, obj.Key)
+	be.NilErr(t, err)
+	defer f.Close()
+	readerAt, ok := f.(io.ReaderAt)
+	be.True(t, ok)
+	buf := make([]byte, 5)
+	n, err := readerAt.ReadAt(buf, 10)
+	be.NilErr(t, err)
+	be.Equal(t, 5, n)
+	be.Equal(t, "KLMNO", string(buf))
+	// ReadAt doesn't change the offset for Read
+	n, err = f.Read(buf)
+	be.NilErr(t, err)
+	be.Equal(t, 5, n)
+	be.Equal(t, "ABCDE", string(buf))
+	// short read at end of file
+	n, err = readerAt.ReadAt(buf, 23)
+	be.Equal(t, 3, n)
+	be.True(t, errors.Is(err, io.EOF))
+	be.Equal(t, "XYZ", string(buf[:n]))
+	// past end of file
+	_, err = readerAt.ReadAt(buf, 26)
+	be.True(t, errors.Is(err, io.EOF))
+	_, err = readerAt.ReadAt(buf, -1)
+	be.Nonzero(t, err)
+}
+
 func TestSeekWithZip(t *testing.T) {
 	if !testutil.S3Enabled() {
 		t.Skip("s3 test service is not running")
@@ -972,3 +1145,69 @@ func (s *seekerReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
 	}
 	return s.rs.Read(p)
 }
+
+func TestMultiCopier_Retry(t *testing.T) {
+	ctx := context.Background()
+	srcBody := mock.RandBytes(int64(20 * megabyte))
+	slowDown := &smithy.GenericAPIError{Code: "SlowDown", Message: "Please reduce your request rate."}
+	copyWith := func(t *testing.T, policy *ocflfs.RetryPolicy, errFunc func(int32) error) (*mock.S3API, error) {
+		t.Helper()
+		api := mock.New(bucket, &mock.Object{Key: "src", Body: srcBody})
+		api.UploadPartCopyErrFunc = errFunc
+		copier := s3.NewMultiCopier(api, func(mc *s3.MultiCopier) {
+			mc.PartSize = partSize
+			mc.Retry = policy
+		})
+		_, err := copier.Copy(ctx, bucket, "dst", "src")
+		return api, err
+	}
+	// part 2 fails with a SlowDown error the first n times it's copied
+	failPart := func(n int) func(int32) error {
+		var mu sync.Mutex
+		return func(part int32) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if part == 2 && n > 0 {
+				n--
+				return slowDown
+			}
+			return nil
+		}
+	}
+	policy := &ocflfs.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	t.Run("without retry", func(t *testing.T) {
+		api, err := copyWith(t, nil, failPart(1))
+		be.True(t, errors.Is(err, slowDown))
+		be.True(t, api.MPUAborted)
+	})
+	t.Run("with retry", func(t *testing.T) {
+		api, err := copyWith(t, policy, failPart(2))
+		be.NilErr(t, err)
+		be.True(t, api.MPUComplete)
+		be.Equal(t, mock.ETag(srcBody, partSize), api.UpdatedETags["dst"])
+	})
+	t.Run("too many failures", func(t *testing.T) {
+		api, err := copyWith(t, policy, failPart(3))
+		be.True(t, errors.Is(err, slowDown))
+		be.True(t, api.MPUAborted)
+	})
+	t.Run("not retryable", func(t *testing.T) {
+		var calls int
+		_, err := copyWith(t, policy, func(part int32) error {
+			if part == 2 {
+				calls++
+				return errors.New("access denied")
+			}
+			return nil
+		})
+		be.Nonzero(t, err)
+		be.Equal(t, 1, calls)
+	})
+}
+
+func TestIsRetryableError(t *testing.T) {
+	be.True(t, s3.IsRetryableError(&smithy.GenericAPIError{Code: "SlowDown"}))
+	be.True(t, s3.IsRetryableError(io.ErrUnexpectedEOF))
+	be.False(t, s3.IsRetryableError(&smithy.GenericAPIError{Code: "AccessDenied"}))
+	be.False(t, s3.IsRetryableError(context.Canceled))
+}
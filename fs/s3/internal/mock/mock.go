@@ -4,21 +4,27 @@ import (
 	"bytes"
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/url"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	"github.com/srerickson/ocfl-go/fs/s3"
 )
 
@@ -29,10 +35,16 @@ var (
 
 func New(bucket string, objects ...*Object) *S3API {
 	api := &S3API{
-		bucket:       bucket,
-		objects:      make(map[string]*Object, len(objects)),
-		UpdatedETags: map[string]string{},
-		Deleted:      map[string]bool{},
+		bucket:                bucket,
+		objects:               make(map[string]*Object, len(objects)),
+		UpdatedETags:          map[string]string{},
+		UpdatedChecksums:      map[string]string{},
+		UpdatedStorageClasses: map[string]types.StorageClass{},
+		PutInputs:             map[string]*s3v2.PutObjectInput{},
+		CopyInputs:            map[string]*s3v2.CopyObjectInput{},
+		CreateMultipartInputs: map[string]*s3v2.CreateMultipartUploadInput{},
+		Deleted:               map[string]bool{},
+		RestoreRequested:      map[string]bool{},
 	}
 	for _, b := range objects {
 		api.objects[b.Key] = b
@@ -42,39 +54,89 @@ func New(bucket string, objects ...*Object) *S3API {
 
 type S3API struct {
 	UpdatedETags map[string]string
-	Deleted      map[string]bool
-	MPUCreated   bool
-	MPUAborted   bool
-	MPUComplete  bool
+	// sha256 checksums (base64) sent with PutObject
+	UpdatedChecksums map[string]string
+	// storage classes for objects written with PutObject, CopyObject, or
+	// multipart uploads.
+	UpdatedStorageClasses map[string]types.StorageClass
+	// inputs for PutObject, CopyObject, and CreateMultipartUpload requests,
+	// indexed by key.
+	PutInputs             map[string]*s3v2.PutObjectInput
+	CopyInputs            map[string]*s3v2.CopyObjectInput
+	CreateMultipartInputs map[string]*s3v2.CreateMultipartUploadInput
+	Deleted               map[string]bool
+	MPUCreated            bool
+	MPUAborted            bool
+	MPUComplete           bool
 
 	CopyObjectFunc func(context.Context, *s3v2.CopyObjectInput, ...func(*s3v2.Options)) (*s3v2.CopyObjectOutput, error)
-
-	parts   sync.Map
-	bucket  string
-	objects map[string]*Object
+	// UploadPartCopyErrFunc, if set, is called for each UploadPartCopy
+	// request. If it returns an error, the request fails with the error.
+	UploadPartCopyErrFunc func(partNum int32) error
+
+	// number of GetObject calls
+	GetObjectCount atomic.Int64
+	// number of HeadObject calls
+	HeadObjectCount atomic.Int64
+	// number of GetObjectTagging calls
+	GetObjectTaggingCount atomic.Int64
+	// number of HeadObject calls for an object being restored before the
+	// restore is complete.
+	RestorePolls int
+	// keys for objects with requested restores
+	RestoreRequested map[string]bool
+
+	mx           sync.Mutex
+	parts        sync.Map
+	bucket       string
+	objects      map[string]*Object
+	otherBuckets map[string]map[string]*Object
 }
 
 func (m *S3API) HeadObject(ctx context.Context, in *s3v2.HeadObjectInput, opts ...func(*s3v2.Options)) (*s3v2.HeadObjectOutput, error) {
-	if err := m.bucketOK(in.Bucket); err != nil {
+	m.HeadObjectCount.Add(1)
+	obj, err := m.getBucketObject(in.Bucket, in.Key)
+	if err != nil {
 		return nil, err
 	}
-	obj, err := m.getObject(in.Key)
-	if err != nil {
+	if err := checkSSECustomerKey(obj, in.SSECustomerKeyMD5); err != nil {
 		return nil, err
 	}
+	m.mx.Lock()
+	if obj.Restore == restoreOngoing {
+		obj.restorePolls--
+		if obj.restorePolls < 0 {
+			obj.Restore = restoreComplete
+		}
+	}
 	out := &s3v2.HeadObjectOutput{
 		ContentLength: aws.Int64(int64(len(obj.Body))),
 		LastModified:  aws.Time(obj.LastModified),
+		ETag:          aws.String(obj.ETag()),
+		Metadata:      obj.Metadata,
+		StorageClass:  obj.StorageClass,
+	}
+	if obj.Restore != "" {
+		out.Restore = aws.String(obj.Restore)
+	}
+	m.mx.Unlock()
+	if in.ChecksumMode == types.ChecksumModeEnabled && obj.ChecksumSHA256 != "" {
+		out.ChecksumSHA256 = aws.String(obj.ChecksumSHA256)
+		out.ChecksumType = types.ChecksumTypeFullObject
 	}
 	return out, nil
 }
 
 func (m *S3API) GetObject(ctx context.Context, in *s3v2.GetObjectInput, opts ...func(*s3v2.Options)) (*s3v2.GetObjectOutput, error) {
-	if err := m.bucketOK(in.Bucket); err != nil {
+	m.GetObjectCount.Add(1)
+	obj, err := m.getBucketObject(in.Bucket, in.Key)
+	if err != nil {
 		return nil, err
 	}
-	obj, err := m.getObject(in.Key)
-	if err != nil {
+	if err := m.checkArchived(obj); err != nil {
+		return nil, err
+	}
+	if err := checkSSECustomerKey(obj, in.SSECustomerKeyMD5); err != nil {
 		return nil, err
 	}
 	body := obj.Body
@@ -147,6 +209,7 @@ func (m *S3API) ListObjectsV2(ctx context.Context, in *s3v2.ListObjectsV2Input,
 				Key:          aws.String(key),
 				Size:         aws.Int64(object.ContentLength),
 				LastModified: aws.Time(object.LastModified),
+				ETag:         aws.String(object.ETag()),
 			}
 			out.Contents = append(out.Contents, cont)
 		}
@@ -174,7 +237,18 @@ func (m *S3API) PutObject(ctx context.Context, in *s3v2.PutObjectInput, opts ...
 	if in.Key == nil {
 		return nil, errors.New("key is required")
 	}
-	etag, err := md5hex(in.Body)
+	body, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	if in.ChecksumSHA256 != nil {
+		sum := sha256.Sum256(body)
+		if *in.ChecksumSHA256 != base64.StdEncoding.EncodeToString(sum[:]) {
+			return nil, errors.New("BadDigest: the sha256 checksum does not match the content")
+		}
+		m.UpdatedChecksums[*in.Key] = *in.ChecksumSHA256
+	}
+	etag, err := md5hex(bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
@@ -182,6 +256,8 @@ func (m *S3API) PutObject(ctx context.Context, in *s3v2.PutObjectInput, opts ...
 		ETag: &etag,
 	}
 	m.UpdatedETags[*in.Key] = `"` + etag + `"`
+	m.UpdatedStorageClasses[*in.Key] = in.StorageClass
+	m.PutInputs[*in.Key] = in
 	return out, nil
 }
 
@@ -198,6 +274,8 @@ func (m *S3API) CreateMultipartUpload(ctx context.Context, in *s3v2.CreateMultip
 		UploadId: &uploadID,
 	}
 	m.MPUCreated = true
+	m.UpdatedStorageClasses[*in.Key] = in.StorageClass
+	m.CreateMultipartInputs[*in.Key] = in
 	return out, nil
 }
 
@@ -223,6 +301,11 @@ func (m *S3API) UploadPart(ctx context.Context, in *s3v2.UploadPartInput, opts .
 }
 
 func (m *S3API) UploadPartCopy(ctx context.Context, in *s3v2.UploadPartCopyInput, opts ...func(*s3v2.Options)) (*s3v2.UploadPartCopyOutput, error) {
+	if m.UploadPartCopyErrFunc != nil && in.PartNumber != nil {
+		if err := m.UploadPartCopyErrFunc(*in.PartNumber); err != nil {
+			return nil, err
+		}
+	}
 	if err := m.bucketOK(in.Bucket); err != nil {
 		return nil, err
 	}
@@ -240,13 +323,16 @@ func (m *S3API) UploadPartCopy(ctx context.Context, in *s3v2.UploadPartCopyInput
 		return nil, fmt.Errorf("parsing copy source: %w", err)
 	}
 	srcBucket, srcKey, _ := strings.Cut(copySourceDecoded, "/")
-	if srcBucket != m.bucket {
-		return nil, &types.NoSuchBucket{}
-	}
-	srcObj, err := m.getObject(&srcKey)
+	srcObj, err := m.getBucketObject(&srcBucket, &srcKey)
 	if err != nil {
 		return nil, err
 	}
+	if err := m.checkArchived(srcObj); err != nil {
+		return nil, err
+	}
+	if err := checkSSECustomerKey(srcObj, in.CopySourceSSECustomerKeyMD5); err != nil {
+		return nil, err
+	}
 	if in.CopySourceRange == nil {
 		return nil, errors.New("CopySourceRange is required")
 	}
@@ -342,13 +428,16 @@ func (m *S3API) CopyObject(ctx context.Context, in *s3v2.CopyObjectInput, opts .
 		return nil, fmt.Errorf("parsing copy source: %w", err)
 	}
 	srcBucket, srcKey, _ := strings.Cut(copySourceDecoded, "/")
-	if srcBucket != m.bucket {
-		return nil, &types.NoSuchBucket{}
-	}
-	srcObj, err := m.getObject(&srcKey)
+	srcObj, err := m.getBucketObject(&srcBucket, &srcKey)
 	if err != nil {
 		return nil, err
 	}
+	if err := m.checkArchived(srcObj); err != nil {
+		return nil, err
+	}
+	if err := checkSSECustomerKey(srcObj, in.CopySourceSSECustomerKeyMD5); err != nil {
+		return nil, err
+	}
 	etag, err := md5hex(bytes.NewReader(srcObj.Body))
 	if err != nil {
 		return nil, err
@@ -357,6 +446,8 @@ func (m *S3API) CopyObject(ctx context.Context, in *s3v2.CopyObjectInput, opts .
 		CopyObjectResult: &types.CopyObjectResult{ETag: aws.String(etag)},
 	}
 	m.UpdatedETags[*in.Key] = `"` + etag + `"` // etag is quoted string
+	m.UpdatedStorageClasses[*in.Key] = in.StorageClass
+	m.CopyInputs[*in.Key] = in
 	return out, nil
 }
 
@@ -395,6 +486,90 @@ func (m *S3API) objectKeys() []string {
 	return keys
 }
 
+func (m *S3API) GetObjectTagging(ctx context.Context, in *s3v2.GetObjectTaggingInput, opts ...func(*s3v2.Options)) (*s3v2.GetObjectTaggingOutput, error) {
+	m.GetObjectTaggingCount.Add(1)
+	obj, err := m.getBucketObject(in.Bucket, in.Key)
+	if err != nil {
+		return nil, err
+	}
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	out := &s3v2.GetObjectTaggingOutput{TagSet: []types.Tag{}}
+	for _, k := range slices.Sorted(maps.Keys(obj.Tags)) {
+		out.TagSet = append(out.TagSet, types.Tag{Key: aws.String(k), Value: aws.String(obj.Tags[k])})
+	}
+	return out, nil
+}
+
+func (m *S3API) PutObjectTagging(ctx context.Context, in *s3v2.PutObjectTaggingInput, opts ...func(*s3v2.Options)) (*s3v2.PutObjectTaggingOutput, error) {
+	obj, err := m.getBucketObject(in.Bucket, in.Key)
+	if err != nil {
+		return nil, err
+	}
+	if in.Tagging == nil {
+		return nil, errors.New("tagging is required")
+	}
+	if len(in.Tagging.TagSet) > 10 {
+		return nil, errors.New("BadRequest: object tags cannot be greater than 10")
+	}
+	tags := make(map[string]string, len(in.Tagging.TagSet))
+	for _, tag := range in.Tagging.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	obj.Tags = tags
+	return &s3v2.PutObjectTaggingOutput{}, nil
+}
+
+func (m *S3API) RestoreObject(ctx context.Context, in *s3v2.RestoreObjectInput, opts ...func(*s3v2.Options)) (*s3v2.RestoreObjectOutput, error) {
+	obj, err := m.getBucketObject(in.Bucket, in.Key)
+	if err != nil {
+		return nil, err
+	}
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	switch {
+	case obj.StorageClass != types.StorageClassGlacier && obj.StorageClass != types.StorageClassDeepArchive:
+		return nil, &smithy.GenericAPIError{Code: "InvalidObjectState", Message: "restore is not allowed for the object's current storage class"}
+	case obj.Restore == restoreOngoing:
+		return nil, &smithy.GenericAPIError{Code: "RestoreAlreadyInProgress", Message: "object restore is already in progress"}
+	}
+	if in.RestoreRequest == nil || aws.ToInt32(in.RestoreRequest.Days) < 1 {
+		return nil, errors.New("restore request with days is required")
+	}
+	obj.Restore = restoreOngoing
+	obj.restorePolls = m.RestorePolls
+	m.RestoreRequested[*in.Key] = true
+	return &s3v2.RestoreObjectOutput{}, nil
+}
+
+const (
+	restoreOngoing  = `ongoing-request="true"`
+	restoreComplete = `ongoing-request="false", expiry-date="Fri, 21 Dec 2029 00:00:00 GMT"`
+)
+
+// checkSSECustomerKey returns an error if obj is encrypted with an SSE-C key
+// that doesn't match keyMD5.
+func checkSSECustomerKey(obj *Object, keyMD5 *string) error {
+	if obj.SSECustomerKeyMD5 != aws.ToString(keyMD5) {
+		return &smithy.GenericAPIError{Code: "BadRequest", Message: "the SSE-C key is missing or doesn't match the object's key"}
+	}
+	return nil
+}
+
+func (m *S3API) checkArchived(obj *Object) error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if obj.archived() {
+		return &types.InvalidObjectState{
+			Message:      aws.String("The operation is not valid for the object's storage class"),
+			StorageClass: obj.StorageClass,
+		}
+	}
+	return nil
+}
+
 func (m *S3API) bucketOK(b *string) error {
 	if !eql(m.bucket, b) {
 		return &types.NoSuchBucket{}
@@ -402,6 +577,40 @@ func (m *S3API) bucketOK(b *string) error {
 	return nil
 }
 
+// AddBucket adds another bucket with objects to the mock. Objects in the bucket
+// can be read with HeadObject and GetObject and used as copy sources.
+func (m *S3API) AddBucket(name string, objects ...*Object) {
+	if m.otherBuckets == nil {
+		m.otherBuckets = map[string]map[string]*Object{}
+	}
+	objs := make(map[string]*Object, len(objects))
+	for _, obj := range objects {
+		objs[obj.Key] = obj
+	}
+	m.otherBuckets[name] = objs
+}
+
+func (m *S3API) getBucketObject(b *string, k *string) (*Object, error) {
+	if b == nil {
+		return nil, errors.New("bucket is required")
+	}
+	if *b == m.bucket {
+		return m.getObject(k)
+	}
+	objs, ok := m.otherBuckets[*b]
+	if !ok {
+		return nil, &types.NoSuchBucket{}
+	}
+	if k == nil {
+		return nil, errors.New("object key is required")
+	}
+	obj, ok := objs[*k]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return obj, nil
+}
+
 func (m *S3API) getObject(k *string) (*Object, error) {
 	if k == nil {
 		return nil, errors.New("object key is required")
@@ -420,6 +629,36 @@ type Object struct {
 	Body          []byte
 	LastModified  time.Time
 	ContentLength int64
+	// base64-encoded sha256 checksum returned by HeadObject
+	ChecksumSHA256 string
+	// user-defined metadata returned by HeadObject
+	Metadata map[string]string
+	// object tags
+	Tags map[string]string
+	// storage class returned by HeadObject. Objects with GLACIER or
+	// DEEP_ARCHIVE storage classes can't be read unless they are restored.
+	StorageClass types.StorageClass
+	// value of the x-amz-restore header returned by HeadObject
+	Restore string
+	// base64-encoded md5 of the SSE-C key used to encrypt the object. If
+	// set, the key is required to read the object.
+	SSECustomerKeyMD5 string
+
+	restorePolls int
+}
+
+func (obj *Object) archived() bool {
+	switch obj.StorageClass {
+	case types.StorageClassGlacier, types.StorageClassDeepArchive:
+		return !strings.Contains(obj.Restore, `ongoing-request="false"`)
+	}
+	return false
+}
+
+// ETag returns the object's ETag: the quoted md5 of its body.
+func (obj *Object) ETag() string {
+	etag, _ := md5hex(bytes.NewReader(obj.Body))
+	return `"` + etag + `"`
 }
 
 // func GenObjects(seed uint64, objCount int, keyPrefix string, depth int, maxFileSize int64) map[string]*Object {
@@ -5,9 +5,12 @@ import (
 	"errors"
 	"net/url"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -26,6 +29,15 @@ type MultiCopier struct {
 	// Concurrency stes the number of goroutines
 	// per copy for copying object parts. defaults to 12.
 	Concurrency int
+	// WriteOptions are settings for copied objects (encryption, storage
+	// class, tags, etc.).
+	WriteOptions WriteOptions
+	// Retry is used to retry requests for creating and completing the upload
+	// and for copying individual parts if they fail with retryable errors.
+	// If the policy's Retryable function is nil, [IsRetryableError] is used.
+	// If Retry is nil, requests are not retried (other than by the S3
+	// client).
+	Retry *ocflfs.RetryPolicy
 
 	api MultiCopyAPI
 }
@@ -43,12 +55,19 @@ func NewMultiCopier(api MultiCopyAPI, opts ...func(*MultiCopier)) *MultiCopier {
 }
 
 func (c *MultiCopier) Copy(ctx context.Context, buck string, dst, src string, srcHeads ...*s3.HeadObjectOutput) (srcSize int64, err error) {
+	return c.CopyFromBucket(ctx, buck, dst, buck, src, srcHeads...)
+}
+
+// CopyFromBucket copies src in srcBuck to dst in buck using a multipart copy.
+// The source and destination buckets may be different. If given, the first
+// srcHeads value is used as the source object's metadata.
+func (c *MultiCopier) CopyFromBucket(ctx context.Context, buck string, dst string, srcBuck string, src string, srcHeads ...*s3.HeadObjectOutput) (srcSize int64, err error) {
 	var srcHead *s3.HeadObjectOutput
 	if len(srcHeads) > 0 {
 		srcHead = srcHeads[0]
 	}
 	if srcHead == nil {
-		headParams := &s3.HeadObjectInput{Bucket: &buck, Key: &src}
+		headParams := &s3.HeadObjectInput{Bucket: &srcBuck, Key: &src}
 		srcHead, err = c.api.HeadObject(ctx, headParams)
 		if err != nil {
 			err = pathErr("copy", src, err)
@@ -69,7 +88,14 @@ func (c *MultiCopier) Copy(ctx context.Context, buck string, dst, src string, sr
 	psize, partCount := adjustPartSize(srcSize, c.PartSize, manager.MaxUploadParts)
 	completedParts := make([]types.CompletedPart, partCount)
 	uploadParams := &s3.CreateMultipartUploadInput{Bucket: &buck, Key: &dst}
-	newUp, err := c.api.CreateMultipartUpload(ctx, uploadParams)
+	c.WriteOptions.applyCreateMultipart(uploadParams)
+	policy := c.retryPolicy()
+	var newUp *s3.CreateMultipartUploadOutput
+	_, err = policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		newUp, err = c.api.CreateMultipartUpload(ctx, uploadParams)
+		return err
+	})
 	if err != nil {
 		err = pathErr("copy", dst, err)
 		return
@@ -95,12 +121,15 @@ func (c *MultiCopier) Copy(ctx context.Context, buck string, dst, src string, sr
 				UploadId:        newUp.UploadId,
 				MultipartUpload: upload,
 			}
-			_, err = c.api.CompleteMultipartUpload(ctx, params)
+			_, err = policy.Do(ctx, func(ctx context.Context) error {
+				_, err := c.api.CompleteMultipartUpload(ctx, params)
+				return err
+			})
 		}
 	}()
 	grp, grpCtx := errgroup.WithContext(ctx)
 	grp.SetLimit(c.Concurrency)
-	copySource := url.QueryEscape(buck + "/" + src)
+	copySource := url.QueryEscape(srcBuck + "/" + src)
 	for i := range partCount {
 		grp.Go(func() error {
 			var err error
@@ -114,7 +143,12 @@ func (c *MultiCopier) Copy(ctx context.Context, buck string, dst, src string, sr
 				PartNumber:      &partNum,
 				CopySourceRange: &srcRange,
 			}
-			result, err := c.api.UploadPartCopy(grpCtx, params)
+			var result *s3.UploadPartCopyOutput
+			_, err = policy.Do(grpCtx, func(ctx context.Context) error {
+				var err error
+				result, err = c.api.UploadPartCopy(ctx, params)
+				return err
+			})
 			if err != nil {
 				return err
 			}
@@ -128,3 +162,24 @@ func (c *MultiCopier) Copy(ctx context.Context, buck string, dst, src string, sr
 	err = grp.Wait()
 	return
 }
+
+// retryPolicy returns c.Retry, using IsRetryableError if the policy doesn't
+// have a Retryable function.
+func (c *MultiCopier) retryPolicy() *ocflfs.RetryPolicy {
+	if c.Retry == nil || c.Retry.Retryable != nil {
+		return c.Retry
+	}
+	policy := *c.Retry
+	policy.Retryable = IsRetryableError
+	return &policy
+}
+
+// IsRetryableError returns true if err is a transient error (see
+// IsTransientError in the fs package) or an S3 error that the AWS SDK
+// considers retryable, including throttling errors like 503 SlowDown.
+func IsRetryableError(err error) bool {
+	if ocflfs.IsTransientError(err) {
+		return true
+	}
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
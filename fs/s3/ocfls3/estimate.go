@@ -0,0 +1,35 @@
+package ocfls3
+
+import (
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/fs/s3"
+)
+
+// EstimateUpdateCalls returns the expected number of S3 API requests, indexed
+// by operation name (e.g., "PutObject"), for applying the incomplete steps of
+// an update to an object in fsys's bucket, as described by summary. If
+// serverSideCopy is true, content is assumed to be copied from another
+// BucketFS with CopyObject; otherwise, it is assumed to be uploaded. Files with
+// unknown sizes are counted as single requests. The estimate doesn't include
+// requests for retries or for verifying copied content.
+func EstimateUpdateCalls(fsys *s3.BucketFS, summary *ocfl.UpdateSummary, serverSideCopy bool) map[string]int {
+	calls := map[string]int{}
+	for _, step := range summary.Steps {
+		if step.Completed {
+			continue
+		}
+		switch step.Op {
+		case ocfl.StepCopy:
+			if serverSideCopy {
+				fsys.EstimateCopyCalls(calls, step.Size)
+				break
+			}
+			fsys.EstimateUploadCalls(calls, step.Size)
+		case ocfl.StepWrite:
+			fsys.EstimateUploadCalls(calls, step.Size)
+		case ocfl.StepRemove:
+			calls["DeleteObject"]++
+		}
+	}
+	return calls
+}
@@ -0,0 +1,71 @@
+package ocfls3_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/fs/s3"
+	"github.com/srerickson/ocfl-go/fs/s3/internal/mock"
+	"github.com/srerickson/ocfl-go/fs/s3/ocfls3"
+)
+
+const (
+	megabyte = 1024 * 1024
+	partSize = 6 * megabyte
+)
+
+func TestEstimateUpdateCalls(t *testing.T) {
+	summary := &ocfl.UpdateSummary{
+		Steps: []ocfl.UpdateStepSummary{
+			{Name: "object root ", Size: -1},
+			{Name: "copy v1/content/small", Op: ocfl.StepCopy, Size: 10},
+			{Name: "copy v1/content/large", Op: ocfl.StepCopy, Size: 13 * megabyte},
+			{Name: "copy v1/content/unknown", Op: ocfl.StepCopy, Size: -1},
+			{Name: "copy v1/content/done", Op: ocfl.StepCopy, Size: 10, Completed: true},
+			{Name: "write inventory.json", Op: ocfl.StepWrite, Size: 1000},
+			{Name: "remove v1/content/old", Op: ocfl.StepRemove},
+		},
+	}
+	fsys := s3.NewBucketFS(mock.New(bucket), bucket, s3.WithUploaderOptions(func(u *manager.Uploader) {
+		u.PartSize = partSize
+	}))
+	t.Run("upload", func(t *testing.T) {
+		calls := ocfls3.EstimateUpdateCalls(fsys, summary, false)
+		be.DeepEqual(t, map[string]int{
+			"PutObject":               3,
+			"CreateMultipartUpload":   1,
+			"UploadPart":              3,
+			"CompleteMultipartUpload": 1,
+			"DeleteObject":            1,
+		}, calls)
+	})
+	t.Run("server-side copy", func(t *testing.T) {
+		calls := ocfls3.EstimateUpdateCalls(fsys, summary, true)
+		be.DeepEqual(t, map[string]int{
+			"HeadObject":   3,
+			"CopyObject":   3,
+			"PutObject":    1,
+			"DeleteObject": 1,
+		}, calls)
+	})
+	t.Run("multipart copy", func(t *testing.T) {
+		summary := &ocfl.UpdateSummary{
+			Steps: []ocfl.UpdateStepSummary{
+				{Name: "copy v1/content/huge", Op: ocfl.StepCopy, Size: 6 * 1024 * megabyte},
+			},
+		}
+		fsys := s3.NewBucketFS(mock.New(bucket), bucket, s3.WithMultiPartCopyOption(func(mc *s3.MultiCopier) {
+			mc.PartSize = 1024 * megabyte
+		}))
+		calls := ocfls3.EstimateUpdateCalls(fsys, summary, true)
+		be.DeepEqual(t, map[string]int{
+			"HeadObject":              1,
+			"CopyObject":              1,
+			"CreateMultipartUpload":   1,
+			"UploadPartCopy":          6,
+			"CompleteMultipartUpload": 1,
+		}, calls)
+	})
+}
@@ -0,0 +1,67 @@
+package ocfls3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/fs/s3"
+	"golang.org/x/sync/errgroup"
+)
+
+const defaultRestoreConcurrency = 8
+
+// RestoreVersion initiates restores for the archived content files needed
+// to read the version of obj with the given number (1...HEAD). If v < 1, the
+// most recent version is used. The object must be stored in a *s3.BucketFS.
+// The days and opts arguments are passed to [s3.BucketFS.RestoreFile] for
+// each content file. RestoreVersion returns the sorted names of content files
+// that are being restored. The names can be passed to
+// [s3.BucketFS.WaitRestored].
+func RestoreVersion(ctx context.Context, obj *ocfl.Object, v int, days int32, opts ...func(*s3v2.RestoreObjectInput)) ([]string, error) {
+	stage := obj.VersionStage(v)
+	if stage == nil {
+		return nil, errors.New("version not found")
+	}
+	fsys, ok := obj.FS().(*s3.BucketFS)
+	if !ok {
+		return nil, errors.New("object is not stored in an S3 bucket")
+	}
+	names := make([]string, 0, len(stage.State))
+	for dig := range stage.State {
+		contentFS, name := stage.GetContent(dig)
+		if contentFS == nil {
+			return nil, fmt.Errorf("missing content for digest: %s", dig)
+		}
+		names = append(names, name)
+	}
+	var (
+		mx        sync.Mutex
+		restoring []string
+	)
+	grp, grpCtx := errgroup.WithContext(ctx)
+	grp.SetLimit(defaultRestoreConcurrency)
+	for _, name := range names {
+		grp.Go(func() error {
+			status, err := fsys.RestoreFile(grpCtx, name, days, opts...)
+			if err != nil {
+				return err
+			}
+			if status == s3.Restoring {
+				mx.Lock()
+				restoring = append(restoring, name)
+				mx.Unlock()
+			}
+			return nil
+		})
+	}
+	if err := grp.Wait(); err != nil {
+		return nil, err
+	}
+	slices.Sort(restoring)
+	return restoring, nil
+}
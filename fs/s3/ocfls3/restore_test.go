@@ -0,0 +1,129 @@
+package ocfls3_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/s3"
+	"github.com/srerickson/ocfl-go/fs/s3/internal/mock"
+	"github.com/srerickson/ocfl-go/fs/s3/ocfls3"
+)
+
+const bucket = "ocfl-go-test"
+
+func TestArchivedObject_Mock(t *testing.T) {
+	ctx := context.Background()
+	objDir := filepath.Join("..", "..", "..", "testdata", "object-fixtures", "1.1", "good-objects", "spec-ex-full")
+	contentClass := s3.ContentStorageClass(types.StorageClassDeepArchive)
+	objects := fixtureObjects(t, objDir, "object")
+	for _, obj := range objects {
+		obj.StorageClass = contentClass(obj.Key)
+	}
+	api := mock.New(bucket, objects...)
+	api.RestorePolls = 2
+	fsys := s3.NewBucketFS(api, bucket)
+	content := "object/v1/content/foo/bar.xml"
+
+	// archived content can't be read
+	f, err := fsys.OpenFile(ctx, content)
+	be.NilErr(t, err)
+	_, err = io.ReadAll(f)
+	be.NilErr(t, f.Close())
+	var archErr *s3.ArchivedError
+	be.True(t, errors.As(err, &archErr))
+	be.Equal(t, types.StorageClassDeepArchive, archErr.StorageClass)
+	be.False(t, archErr.Restoring)
+	be.True(t, errors.As(ocfl.ValidateObject(ctx, fsys, "object").Err(), &archErr))
+	status, err := fsys.RestoreStatus(ctx, content)
+	be.NilErr(t, err)
+	be.Equal(t, s3.Archived, status)
+
+	// restore head version content
+	obj, err := ocfl.NewObject(ctx, fsys, "object")
+	be.NilErr(t, err)
+	restoring, err := ocfls3.RestoreVersion(ctx, obj, 0, 1)
+	be.NilErr(t, err)
+	be.Equal(t, obj.VersionStage(0).State.NumPaths(), len(restoring))
+	for _, name := range restoring {
+		be.True(t, api.RestoreRequested[name])
+	}
+	// restore requests aren't repeated
+	status, err = fsys.RestoreFile(ctx, restoring[0], 1)
+	be.NilErr(t, err)
+	be.Equal(t, s3.Restoring, status)
+	be.NilErr(t, fsys.WaitRestored(ctx, time.Millisecond, restoring...))
+	status, err = fsys.RestoreStatus(ctx, restoring[0])
+	be.NilErr(t, err)
+	be.Equal(t, s3.Restored, status)
+	be.NilErr(t, ocfl.ValidateObject(ctx, fsys, "object", ocfl.ValidationSkipDigest()).Err())
+
+	// files that aren't archived aren't restored
+	status, err = fsys.RestoreFile(ctx, "object/inventory.json", 1)
+	be.NilErr(t, err)
+	be.Equal(t, s3.NotArchived, status)
+	be.False(t, api.RestoreRequested["object/inventory.json"])
+
+	t.Run("client without restore", func(t *testing.T) {
+		// the client only implements S3API
+		fsys := s3.NewBucketFS(struct{ s3.S3API }{api}, bucket)
+		_, err := fsys.RestoreFile(ctx, content, 1)
+		be.True(t, errors.Is(err, ocflfs.ErrOpUnsupported))
+	})
+	t.Run("wait archived", func(t *testing.T) {
+		err := fsys.WaitRestored(ctx, time.Millisecond, content)
+		var archErr *s3.ArchivedError
+		be.True(t, errors.As(err, &archErr))
+		be.Equal(t, types.StorageClassDeepArchive, archErr.StorageClass)
+		be.False(t, archErr.Restoring)
+	})
+	t.Run("wait canceled", func(t *testing.T) {
+		name := content // not in the head version
+		status, err := fsys.RestoreFile(ctx, name, 1)
+		be.NilErr(t, err)
+		be.Equal(t, s3.Restoring, status)
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		err = fsys.WaitRestored(ctx, time.Hour, name)
+		be.True(t, errors.Is(err, context.Canceled))
+	})
+}
+
+// fixtureObjects returns mock objects for files in the local directory dir.
+// Object keys are prefixed with prefix.
+func fixtureObjects(t *testing.T, dir string, prefix string) []*mock.Object {
+	t.Helper()
+	var objects []*mock.Object
+	err := filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		body, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, &mock.Object{
+			Key:           path.Join(prefix, filepath.ToSlash(rel)),
+			Body:          body,
+			ContentLength: int64(len(body)),
+			LastModified:  time.Now(),
+		})
+		return nil
+	})
+	be.NilErr(t, err)
+	return objects
+}
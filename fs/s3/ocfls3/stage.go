@@ -0,0 +1,81 @@
+// Package ocfls3 provides helpers for OCFL operations with objects and content
+// stored in S3 buckets. It is separate from the s3 package so that the s3
+// package doesn't depend on the root ocfl package.
+package ocfls3
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/s3"
+)
+
+// StagePrefix builds a stage from the objects under prefix in fsys's bucket.
+// It is like [ocfl.StageDir], but objects are digested with
+// [s3.BucketFS.DigestPrefix], which is configured with opts. Objects with
+// hidden names are ignored. The alg argument must be sha512 or sha256.
+func StagePrefix(ctx context.Context, fsys *s3.BucketFS, prefix string, alg digest.Algorithm, opts ...s3.DigestOption) (*ocfl.Stage, error) {
+	if alg.ID() != digest.SHA512.ID() && alg.ID() != digest.SHA256.ID() {
+		return nil, fmt.Errorf("at least one algorithm (sha512 or sha256) must be provided")
+	}
+	stage := &ocfl.Stage{
+		State:           ocfl.DigestMap{},
+		DigestAlgorithm: alg,
+	}
+	content := &prefixContent{
+		fsys:   fsys,
+		keys:   map[string]string{},
+		fixity: map[string]digest.Set{},
+	}
+	for ref, err := range fsys.DigestPrefix(ctx, prefix, alg, opts...) {
+		if err != nil {
+			return nil, err
+		}
+		dig := ref.Digests[alg.ID()]
+		if dig == "" {
+			return nil, fmt.Errorf("missing expected %s for %s", alg.ID(), ref.FullPath())
+		}
+		stage.State[dig] = append(stage.State[dig], ref.Path)
+		if _, exists := content.keys[dig]; !exists {
+			content.keys[dig] = ref.FullPath()
+		}
+		if len(ref.Fixity) > 0 {
+			if content.fixity[dig] == nil {
+				content.fixity[dig] = digest.Set{}
+			}
+			for fixAlg, fixDig := range ref.Fixity {
+				content.fixity[dig][fixAlg] = fixDig
+			}
+		}
+	}
+	for _, paths := range stage.State {
+		slices.Sort(paths)
+	}
+	stage.ContentSource = content
+	stage.FixitySource = content
+	return stage, nil
+}
+
+// prefixContent is the content and fixity source for stages created with
+// StagePrefix.
+type prefixContent struct {
+	fsys   *s3.BucketFS
+	keys   map[string]string     // digest -> object key
+	fixity map[string]digest.Set // digest -> fixity
+}
+
+func (c *prefixContent) GetContent(dig string) (ocflfs.FS, string) {
+	key := c.keys[dig]
+	if key == "" {
+		return nil, ""
+	}
+	return c.fsys, key
+}
+
+func (c *prefixContent) GetFixity(dig string) digest.Set {
+	return c.fixity[dig]
+}
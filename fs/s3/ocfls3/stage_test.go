@@ -0,0 +1,133 @@
+package ocfls3_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/s3"
+	"github.com/srerickson/ocfl-go/fs/s3/internal/mock"
+	"github.com/srerickson/ocfl-go/fs/s3/ocfls3"
+)
+
+func TestStagePrefix_Mock(t *testing.T) {
+	ctx := context.Background()
+	newObj := func(key string, body string) *mock.Object {
+		return &mock.Object{
+			Key:           key,
+			Body:          []byte(body),
+			ContentLength: int64(len(body)),
+			LastModified:  time.Now(),
+		}
+	}
+	newAPI := func() (*mock.S3API, *mock.Object) {
+		changing := newObj("content/b/c.txt", "content c")
+		changing.Tags = map[string]string{"project": "test"}
+		return mock.New(bucket,
+			newObj("content/a.txt", "content a"),
+			newObj("content/a-copy.txt", "content a"),
+			newObj("content/.hidden", "hidden"),
+			newObj("other/d.txt", "not staged"),
+			changing,
+		), changing
+	}
+	t.Run("store and reuse digests", func(t *testing.T) {
+		api, changing := newAPI()
+		fsys := s3.NewBucketFS(api, bucket)
+		expect, err := ocfl.StageDir(ctx, fsys, "content", digest.SHA256, digest.MD5)
+		be.NilErr(t, err)
+		api.GetObjectCount.Store(0)
+		stage, err := ocfls3.StagePrefix(ctx, fsys, "content", digest.SHA256,
+			s3.DigestWithFixity(digest.MD5),
+			s3.DigestWithStoreDigests(),
+			s3.DigestWithConcurrency(2))
+		be.NilErr(t, err)
+		be.True(t, stage.State.Eq(expect.State))
+		be.Equal(t, 3, stage.State.NumPaths())
+		be.Nonzero(t, api.GetObjectCount.Load())
+		for dig := range stage.State {
+			contentFS, contentPath := stage.GetContent(dig)
+			be.Equal(t, fsys, contentFS.(*s3.BucketFS))
+			be.True(t, strings.HasPrefix(contentPath, "content/"))
+			be.Equal(t, expect.GetFixity(dig)[digest.MD5.ID()], stage.GetFixity(dig)[digest.MD5.ID()])
+		}
+		// existing tags are preserved
+		be.Equal(t, "test", changing.Tags["project"])
+		be.Equal(t, strings.Trim(changing.ETag(), `"`), changing.Tags[s3.DigestETagKey])
+		be.Equal(t, expect.State.DigestFor("b/c.txt"), changing.Tags[s3.DigestKeyPrefix+"sha256"])
+
+		// staging again uses the stored digests
+		api.GetObjectCount.Store(0)
+		stage, err = ocfls3.StagePrefix(ctx, fsys, "content", digest.SHA256,
+			s3.DigestWithFixity(digest.MD5),
+			s3.DigestWithStoreDigests())
+		be.NilErr(t, err)
+		be.True(t, stage.State.Eq(expect.State))
+		be.Equal(t, int64(0), api.GetObjectCount.Load())
+
+		// digests stored for a previous ETag aren't used
+		changing.Body = []byte("new content c")
+		changing.ContentLength = int64(len(changing.Body))
+		api.GetObjectCount.Store(0)
+		stage, err = ocfls3.StagePrefix(ctx, fsys, "content", digest.SHA256, s3.DigestWithStoreDigests())
+		be.NilErr(t, err)
+		be.Equal(t, int64(1), api.GetObjectCount.Load())
+		be.Nonzero(t, stage.State.DigestFor("b/c.txt"))
+		be.Unequal(t, expect.State.DigestFor("b/c.txt"), stage.State.DigestFor("b/c.txt"))
+	})
+	t.Run("digests from metadata", func(t *testing.T) {
+		api, changing := newAPI()
+		fakeDigest := strings.Repeat("a", 64)
+		changing.Metadata = map[string]string{s3.DigestKeyPrefix + "sha256": fakeDigest}
+		fsys := s3.NewBucketFS(api, bucket)
+		stage, err := ocfls3.StagePrefix(ctx, fsys, "content", digest.SHA256, s3.DigestWithMetadataDigests())
+		be.NilErr(t, err)
+		be.Equal(t, fakeDigest, stage.State.DigestFor("b/c.txt"))
+		be.Equal(t, int64(2), api.GetObjectCount.Load())
+		// tags weren't written
+		be.Zero(t, changing.Tags[s3.DigestETagKey])
+	})
+	t.Run("no extra requests by default", func(t *testing.T) {
+		api, changing := newAPI()
+		changing.Metadata = map[string]string{s3.DigestKeyPrefix + "sha256": strings.Repeat("a", 64)}
+		fsys := s3.NewBucketFS(api, bucket)
+		stage, err := ocfls3.StagePrefix(ctx, fsys, "content", digest.SHA256)
+		be.NilErr(t, err)
+		be.Equal(t, 3, stage.State.NumPaths())
+		be.Unequal(t, changing.Metadata[s3.DigestKeyPrefix+"sha256"], stage.State.DigestFor("b/c.txt"))
+		be.Equal(t, int64(3), api.GetObjectCount.Load())
+		// HeadObject is only called when objects are opened for reading
+		be.Equal(t, api.GetObjectCount.Load(), api.HeadObjectCount.Load())
+		be.Equal(t, int64(0), api.GetObjectTaggingCount.Load())
+	})
+	t.Run("client without tagging", func(t *testing.T) {
+		api, _ := newAPI()
+		// the client only implements S3API
+		fsys := s3.NewBucketFS(struct{ s3.S3API }{api}, bucket)
+		_, err := ocfls3.StagePrefix(ctx, fsys, "content", digest.SHA256, s3.DigestWithStoreDigests())
+		be.True(t, errors.Is(err, ocflfs.ErrOpUnsupported))
+		stage, err := ocfls3.StagePrefix(ctx, fsys, "content", digest.SHA256)
+		be.NilErr(t, err)
+		be.Equal(t, 3, stage.State.NumPaths())
+	})
+	t.Run("empty prefix", func(t *testing.T) {
+		api, _ := newAPI()
+		fsys := s3.NewBucketFS(api, bucket)
+		stage, err := ocfls3.StagePrefix(ctx, fsys, "missing", digest.SHA512)
+		be.NilErr(t, err)
+		be.Equal(t, 0, stage.State.NumPaths())
+		be.Equal(t, digest.SHA512.ID(), stage.DigestAlgorithm.ID())
+	})
+	t.Run("invalid algorithm", func(t *testing.T) {
+		api, _ := newAPI()
+		fsys := s3.NewBucketFS(api, bucket)
+		_, err := ocfls3.StagePrefix(ctx, fsys, "content", digest.MD5)
+		be.Nonzero(t, err)
+	})
+}
@@ -0,0 +1,269 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"maps"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const sseCustomerAlgorithm = "AES256"
+
+// WriteOptions are settings for objects written to a bucket with Write,
+// Copy, CopyFrom, or a multipart copy. Empty values are not used.
+type WriteOptions struct {
+	// ServerSideEncryption is the server-side encryption algorithm used to
+	// store objects (e.g., "aws:kms").
+	ServerSideEncryption types.ServerSideEncryption
+	// SSEKMSKeyID is the id of the KMS key used for aws:kms encryption.
+	SSEKMSKeyID string
+	// BucketKeyEnabled sets whether an S3 bucket key is used for aws:kms
+	// encryption.
+	BucketKeyEnabled *bool
+	// StorageClass is the storage class for objects. It takes precedence
+	// over the storage class set with [WithStorageClass].
+	StorageClass types.StorageClass
+	// ACL is the canned ACL for objects.
+	ACL types.ObjectCannedACL
+	// Tags are tags for objects.
+	Tags map[string]string
+	// Metadata is user-defined metadata for objects. Metadata and tags
+	// replace those of the source object in copies.
+	Metadata map[string]string
+}
+
+// WithWriteOptions sets default options for objects written to the bucket.
+// The defaults can be overridden for individual writes using a context
+// created with [NewWriteOptionsContext].
+func WithWriteOptions(opts WriteOptions) func(*BucketFS) {
+	return func(bf *BucketFS) {
+		bf.writeOptions = opts
+	}
+}
+
+// WithSSECustomerKey sets a customer-provided key used to encrypt (using
+// SSE-C) all objects written to the bucket. The key, which should be 32 bytes,
+// is also sent with all requests to read objects, so all objects in the bucket
+// must be encrypted with it.
+func WithSSECustomerKey(key []byte) func(*BucketFS) {
+	return func(bf *BucketFS) {
+		sum := md5.Sum(key)
+		bf.sseKey = &sseCustomerKey{
+			key:    base64.StdEncoding.EncodeToString(key),
+			keyMD5: base64.StdEncoding.EncodeToString(sum[:]),
+		}
+	}
+}
+
+type writeOptionsCtxKey struct{}
+
+// NewWriteOptionsContext returns a new context with write options that
+// override a BucketFS's default write options for objects written using the
+// context. Non-empty values in opts replace default values; tags and metadata
+// are merged with the defaults. For example, to use a different KMS key for
+// files written during an object update, pass the context to
+// [ocfl.Object.Update].
+func NewWriteOptionsContext(ctx context.Context, opts WriteOptions) context.Context {
+	return context.WithValue(ctx, writeOptionsCtxKey{}, opts)
+}
+
+// WriteOptionsFromContext returns the write options set with
+// [NewWriteOptionsContext]. The returned bool is false if ctx doesn't have
+// write options.
+func WriteOptionsFromContext(ctx context.Context) (WriteOptions, bool) {
+	opts, ok := ctx.Value(writeOptionsCtxKey{}).(WriteOptions)
+	return opts, ok
+}
+
+// merge returns a copy of opts with values replaced by non-empty values from
+// override. Tags and metadata are merged.
+func (opts WriteOptions) merge(override WriteOptions) WriteOptions {
+	if override.ServerSideEncryption != "" {
+		opts.ServerSideEncryption = override.ServerSideEncryption
+	}
+	if override.SSEKMSKeyID != "" {
+		opts.SSEKMSKeyID = override.SSEKMSKeyID
+	}
+	if override.BucketKeyEnabled != nil {
+		opts.BucketKeyEnabled = override.BucketKeyEnabled
+	}
+	if override.StorageClass != "" {
+		opts.StorageClass = override.StorageClass
+	}
+	if override.ACL != "" {
+		opts.ACL = override.ACL
+	}
+	opts.Tags = mergeMaps(opts.Tags, override.Tags)
+	opts.Metadata = mergeMaps(opts.Metadata, override.Metadata)
+	return opts
+}
+
+// tagging returns the object tags as a URL-encoded query string.
+func (opts WriteOptions) tagging() *string {
+	if len(opts.Tags) == 0 {
+		return nil
+	}
+	vals := url.Values{}
+	for k, v := range opts.Tags {
+		vals.Set(k, v)
+	}
+	return aws.String(vals.Encode())
+}
+
+func (opts WriteOptions) applyPut(in *s3.PutObjectInput) {
+	in.ServerSideEncryption = opts.ServerSideEncryption
+	in.SSEKMSKeyId = optString(opts.SSEKMSKeyID)
+	in.BucketKeyEnabled = opts.BucketKeyEnabled
+	in.StorageClass = opts.StorageClass
+	in.ACL = opts.ACL
+	in.Tagging = opts.tagging()
+	in.Metadata = opts.Metadata
+}
+
+func (opts WriteOptions) applyCopy(in *s3.CopyObjectInput) {
+	in.ServerSideEncryption = opts.ServerSideEncryption
+	in.SSEKMSKeyId = optString(opts.SSEKMSKeyID)
+	in.BucketKeyEnabled = opts.BucketKeyEnabled
+	in.StorageClass = opts.StorageClass
+	in.ACL = opts.ACL
+	if tagging := opts.tagging(); tagging != nil {
+		in.Tagging = tagging
+		in.TaggingDirective = types.TaggingDirectiveReplace
+	}
+	if len(opts.Metadata) > 0 {
+		in.Metadata = opts.Metadata
+		in.MetadataDirective = types.MetadataDirectiveReplace
+	}
+}
+
+func (opts WriteOptions) applyCreateMultipart(in *s3.CreateMultipartUploadInput) {
+	in.ServerSideEncryption = opts.ServerSideEncryption
+	in.SSEKMSKeyId = optString(opts.SSEKMSKeyID)
+	in.BucketKeyEnabled = opts.BucketKeyEnabled
+	in.StorageClass = opts.StorageClass
+	in.ACL = opts.ACL
+	in.Tagging = opts.tagging()
+	in.Metadata = opts.Metadata
+}
+
+// getWriteOptions returns the options for writing the named object with ctx.
+func (f *BucketFS) getWriteOptions(ctx context.Context, name string) WriteOptions {
+	opts := f.writeOptions
+	if class := f.getStorageClass(name); class != "" && opts.StorageClass == "" {
+		opts.StorageClass = class
+	}
+	if override, ok := WriteOptionsFromContext(ctx); ok {
+		opts = opts.merge(override)
+	}
+	return opts
+}
+
+// sseCustomerKey is a base64-encoded key for SSE-C and its md5 digest.
+type sseCustomerKey struct {
+	key    string
+	keyMD5 string
+}
+
+func (k *sseCustomerKey) equal(other *sseCustomerKey) bool {
+	if k == nil || other == nil {
+		return k == other
+	}
+	return *k == *other
+}
+
+// sseCustomerAPI wraps an S3API, adding an SSE-C key to all requests that
+// read or write object content. Copy sources are assumed to be encrypted with
+// the same key.
+type sseCustomerAPI struct {
+	S3API
+	key *sseCustomerKey
+}
+
+var (
+	_ S3API      = (*sseCustomerAPI)(nil)
+	_ TaggingAPI = (*sseCustomerAPI)(nil)
+	_ RestoreAPI = (*sseCustomerAPI)(nil)
+)
+
+func (api *sseCustomerAPI) HeadObject(ctx context.Context, in *s3.HeadObjectInput, opts ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = api.key.params()
+	return api.S3API.HeadObject(ctx, in, opts...)
+}
+
+func (api *sseCustomerAPI) GetObject(ctx context.Context, in *s3.GetObjectInput, opts ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = api.key.params()
+	return api.S3API.GetObject(ctx, in, opts...)
+}
+
+func (api *sseCustomerAPI) PutObject(ctx context.Context, in *s3.PutObjectInput, opts ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = api.key.params()
+	return api.S3API.PutObject(ctx, in, opts...)
+}
+
+func (api *sseCustomerAPI) CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, opts ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = api.key.params()
+	return api.S3API.CreateMultipartUpload(ctx, in, opts...)
+}
+
+func (api *sseCustomerAPI) UploadPart(ctx context.Context, in *s3.UploadPartInput, opts ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = api.key.params()
+	return api.S3API.UploadPart(ctx, in, opts...)
+}
+
+func (api *sseCustomerAPI) CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, opts ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = api.key.params()
+	return api.S3API.CompleteMultipartUpload(ctx, in, opts...)
+}
+
+func (api *sseCustomerAPI) UploadPartCopy(ctx context.Context, in *s3.UploadPartCopyInput, opts ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = api.key.params()
+	in.CopySourceSSECustomerAlgorithm, in.CopySourceSSECustomerKey, in.CopySourceSSECustomerKeyMD5 = api.key.params()
+	return api.S3API.UploadPartCopy(ctx, in, opts...)
+}
+
+func (api *sseCustomerAPI) CopyObject(ctx context.Context, in *s3.CopyObjectInput, opts ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	in.SSECustomerAlgorithm, in.SSECustomerKey, in.SSECustomerKeyMD5 = api.key.params()
+	in.CopySourceSSECustomerAlgorithm, in.CopySourceSSECustomerKey, in.CopySourceSSECustomerKeyMD5 = api.key.params()
+	return api.S3API.CopyObject(ctx, in, opts...)
+}
+
+func (api *sseCustomerAPI) GetObjectTagging(ctx context.Context, in *s3.GetObjectTaggingInput, opts ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	return getObjectTagging(ctx, api.S3API, in, opts...)
+}
+
+func (api *sseCustomerAPI) PutObjectTagging(ctx context.Context, in *s3.PutObjectTaggingInput, opts ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error) {
+	return putObjectTagging(ctx, api.S3API, in, opts...)
+}
+
+func (api *sseCustomerAPI) RestoreObject(ctx context.Context, in *s3.RestoreObjectInput, opts ...func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+	return restoreObject(ctx, api.S3API, in, opts...)
+}
+
+// params returns values for SSE-C request parameters
+func (k *sseCustomerKey) params() (alg *string, key *string, keyMD5 *string) {
+	return aws.String(sseCustomerAlgorithm), aws.String(k.key), aws.String(k.keyMD5)
+}
+
+func optString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func mergeMaps(base, override map[string]string) map[string]string {
+	if len(override) == 0 {
+		return base
+	}
+	merged := maps.Clone(base)
+	if merged == nil {
+		merged = make(map[string]string, len(override))
+	}
+	maps.Copy(merged, override)
+	return merged
+}
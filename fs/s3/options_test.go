@@ -0,0 +1,155 @@
+package s3_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	s3v2 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/carlmjohnson/be"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/s3"
+	"github.com/srerickson/ocfl-go/fs/s3/internal/mock"
+)
+
+func TestWithWriteOptions_Mock(t *testing.T) {
+	ctx := context.Background()
+	defaults := s3.WriteOptions{
+		ServerSideEncryption: types.ServerSideEncryptionAwsKms,
+		SSEKMSKeyID:          "default-key",
+		ACL:                  types.ObjectCannedACLBucketOwnerFullControl,
+		Tags:                 map[string]string{"project": "ocfl"},
+		Metadata:             map[string]string{"owner": "library"},
+	}
+	newFS := func(api *mock.S3API) *s3.BucketFS {
+		return s3.NewBucketFS(api, bucket,
+			s3.WithWriteOptions(defaults),
+			s3.WithMultiPartCopyOption(func(mc *s3.MultiCopier) {
+				mc.PartSize = partSize
+			}))
+	}
+	t.Run("write", func(t *testing.T) {
+		api := mock.New(bucket)
+		fsys := newFS(api)
+		_, err := fsys.Write(ctx, "file", bytes.NewReader([]byte("content")))
+		be.NilErr(t, err)
+		in := api.PutInputs["file"]
+		be.Equal(t, types.ServerSideEncryptionAwsKms, in.ServerSideEncryption)
+		be.Equal(t, "default-key", aws.ToString(in.SSEKMSKeyId))
+		be.Equal(t, types.ObjectCannedACLBucketOwnerFullControl, in.ACL)
+		be.Equal(t, "project=ocfl", aws.ToString(in.Tagging))
+		be.Equal(t, "library", in.Metadata["owner"])
+	})
+	t.Run("write with context override", func(t *testing.T) {
+		api := mock.New(bucket)
+		fsys := newFS(api)
+		ctx := s3.NewWriteOptionsContext(ctx, s3.WriteOptions{
+			SSEKMSKeyID: "object-key",
+			Tags:        map[string]string{"object": "id"},
+		})
+		_, err := fsys.Write(ctx, "file", bytes.NewReader([]byte("content")))
+		be.NilErr(t, err)
+		in := api.PutInputs["file"]
+		be.Equal(t, types.ServerSideEncryptionAwsKms, in.ServerSideEncryption)
+		be.Equal(t, "object-key", aws.ToString(in.SSEKMSKeyId))
+		be.Equal(t, "object=id&project=ocfl", aws.ToString(in.Tagging))
+		// defaults are unchanged
+		be.Equal(t, 1, len(defaults.Tags))
+	})
+	t.Run("write with options", func(t *testing.T) {
+		api := mock.New(bucket)
+		fsys := newFS(api)
+		_, err := fsys.WriteWithOptions(ctx, "file", bytes.NewReader([]byte("content")), func(in *s3v2.PutObjectInput) {
+			in.ACL = types.ObjectCannedACLPrivate
+		})
+		be.NilErr(t, err)
+		in := api.PutInputs["file"]
+		be.Equal(t, types.ObjectCannedACLPrivate, in.ACL)
+		be.Equal(t, "default-key", aws.ToString(in.SSEKMSKeyId))
+	})
+	t.Run("multipart write", func(t *testing.T) {
+		api := mock.New(bucket)
+		fsys := newFS(api)
+		body := mock.RandBytes(12 * megabyte)
+		_, err := fsys.Write(ctx, "file", bytes.NewReader(body))
+		be.NilErr(t, err)
+		be.True(t, api.MPUComplete)
+		in := api.CreateMultipartInputs["file"]
+		be.Equal(t, "default-key", aws.ToString(in.SSEKMSKeyId))
+		be.Equal(t, "project=ocfl", aws.ToString(in.Tagging))
+	})
+	t.Run("copy", func(t *testing.T) {
+		api := mock.New(bucket, &mock.Object{Key: "src", Body: []byte("content")})
+		fsys := newFS(api)
+		_, err := fsys.Copy(ctx, "dst", "src")
+		be.NilErr(t, err)
+		in := api.CopyInputs["dst"]
+		be.Equal(t, "default-key", aws.ToString(in.SSEKMSKeyId))
+		be.Equal(t, types.ObjectCannedACLBucketOwnerFullControl, in.ACL)
+		be.Equal(t, "project=ocfl", aws.ToString(in.Tagging))
+		be.Equal(t, types.TaggingDirectiveReplace, in.TaggingDirective)
+		be.Equal(t, types.MetadataDirectiveReplace, in.MetadataDirective)
+	})
+	t.Run("multipart copy", func(t *testing.T) {
+		api := mock.New(bucket, &mock.Object{Key: "src", Body: mock.RandBytes(13 * megabyte)})
+		api.CopyObjectFunc = func(_ context.Context, _ *s3v2.CopyObjectInput, _ ...func(*s3v2.Options)) (*s3v2.CopyObjectOutput, error) {
+			return nil, errors.New("copy source is larger than the maximum allowable size")
+		}
+		fsys := newFS(api)
+		_, err := fsys.Copy(ctx, "dst", "src")
+		be.NilErr(t, err)
+		in := api.CreateMultipartInputs["dst"]
+		be.Equal(t, types.ServerSideEncryptionAwsKms, in.ServerSideEncryption)
+		be.Equal(t, "default-key", aws.ToString(in.SSEKMSKeyId))
+		be.Equal(t, "library", in.Metadata["owner"])
+	})
+}
+
+func TestWithSSECustomerKey_Mock(t *testing.T) {
+	ctx := context.Background()
+	key := bytes.Repeat([]byte{7}, 32)
+	keySum := md5.Sum(key)
+	keyMD5 := base64.StdEncoding.EncodeToString(keySum[:])
+	newAPI := func() *mock.S3API {
+		return mock.New(bucket, &mock.Object{
+			Key:               "encrypted",
+			Body:              []byte("secret content"),
+			SSECustomerKeyMD5: keyMD5,
+		})
+	}
+	t.Run("read", func(t *testing.T) {
+		fsys := s3.NewBucketFS(newAPI(), bucket, s3.WithSSECustomerKey(key))
+		f, err := fsys.OpenFile(ctx, "encrypted")
+		be.NilErr(t, err)
+		defer f.Close()
+		b, err := io.ReadAll(f)
+		be.NilErr(t, err)
+		be.Equal(t, "secret content", string(b))
+		// without key
+		_, err = s3.NewBucketFS(newAPI(), bucket).OpenFile(ctx, "encrypted")
+		be.Nonzero(t, err)
+	})
+	t.Run("write and copy", func(t *testing.T) {
+		api := newAPI()
+		fsys := s3.NewBucketFS(api, bucket, s3.WithSSECustomerKey(key))
+		_, err := fsys.Write(ctx, "file", bytes.NewReader([]byte("content")))
+		be.NilErr(t, err)
+		be.Equal(t, keyMD5, aws.ToString(api.PutInputs["file"].SSECustomerKeyMD5))
+		be.Equal(t, base64.StdEncoding.EncodeToString(key), aws.ToString(api.PutInputs["file"].SSECustomerKey))
+		_, err = fsys.Copy(ctx, "copy", "encrypted")
+		be.NilErr(t, err)
+		be.Equal(t, keyMD5, aws.ToString(api.CopyInputs["copy"].SSECustomerKeyMD5))
+	})
+	t.Run("copy from bucket without key", func(t *testing.T) {
+		fsys := s3.NewBucketFS(newAPI(), bucket, s3.WithSSECustomerKey(key))
+		srcFS := s3.NewBucketFS(mock.New("other"), "other")
+		_, err := fsys.CopyFrom(ctx, "copy", srcFS, "file")
+		be.True(t, errors.Is(err, ocflfs.ErrOpUnsupported))
+	})
+}
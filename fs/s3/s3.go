@@ -3,6 +3,9 @@ package s3
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -167,13 +170,99 @@ func write(ctx context.Context, uploader *manager.Uploader, buck string, key str
 			putInput.ContentLength = &size
 		}
 	}
+	if putInput.ChecksumSHA256 == nil && putInput.ContentLength != nil && *putInput.ContentLength <= uploader.PartSize {
+		// Send a sha256 checksum, so S3 verifies and stores it. Checksums are
+		// only sent for single-part uploads: multipart uploads have composite
+		// checksums. Without a sha256 value in the context, the checksum is
+		// computed from the content, which is no larger than one part.
+		sum := checksumSHA256(ocflfs.ContentDigestsFromContext(ctx))
+		if sum == "" {
+			var err error
+			sum, countReader.Reader, err = readChecksumSHA256(r, uploader.PartSize)
+			if err != nil {
+				return 0, &fs.PathError{Op: "write", Path: key, Err: err}
+			}
+		}
+		if sum != "" {
+			putInput.ChecksumSHA256 = &sum
+		}
+	}
 	if _, err := uploader.Upload(ctx, &putInput); err != nil {
 		return 0, &fs.PathError{Op: "write", Path: key, Err: err}
 	}
 	return countReader.size, nil
 }
 
-func copy(ctx context.Context, api CopyAPI, buck string, dst, src string, opts ...func(*MultiCopier)) (int64, error) {
+// storedDigests returns hex-encoded digests from the full-object checksums
+// stored with the object.
+func storedDigests(ctx context.Context, api OpenFileAPI, buck string, name string) (map[string]string, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, pathErr("stored_digests", name, fs.ErrInvalid)
+	}
+	headOut, err := api.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       &buck,
+		Key:          &name,
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		fsErr := &fs.PathError{Op: "stored_digests", Path: name, Err: err}
+		if errIsNotExist(err) {
+			fsErr.Err = fs.ErrNotExist
+		}
+		return nil, fsErr
+	}
+	digests := map[string]string{}
+	if headOut.ChecksumType == types.ChecksumTypeComposite {
+		// composite checksums are checksums of part checksums.
+		return digests, nil
+	}
+	checksums := map[string]*string{
+		"sha256": headOut.ChecksumSHA256,
+		"sha1":   headOut.ChecksumSHA1,
+	}
+	for alg, sum := range checksums {
+		if sum == nil || strings.Contains(*sum, "-") {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(*sum)
+		if err != nil {
+			continue
+		}
+		digests[alg] = hex.EncodeToString(b)
+	}
+	return digests, nil
+}
+
+// checksumSHA256 returns the base64-encoded sha256 value from digests or an
+// empty string.
+func checksumSHA256(digests map[string]string) string {
+	b, err := hex.DecodeString(digests["sha256"])
+	if err != nil || len(b) != sha256.Size {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// readChecksumSHA256 reads up to limit bytes from r and returns their
+// base64-encoded sha256 value and a reader for the same content. If r has
+// more than limit bytes, the returned value is empty and the returned reader
+// is for all of r's content.
+func readChecksumSHA256(r io.Reader, limit int64) (string, io.Reader, error) {
+	buf, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if int64(len(buf)) > limit {
+		return "", io.MultiReader(bytes.NewReader(buf), r), nil
+	}
+	sum := sha256.Sum256(buf)
+	return base64.StdEncoding.EncodeToString(sum[:]), bytes.NewReader(buf), nil
+}
+
+// copy copies src in srcBuck to dst in buck using CopyObject or, for large
+// objects, a multipart copy. The buckets may be the same.
+// The write options are used for dst.
+func copy(ctx context.Context, api CopyAPI, buck string, dst string, srcBuck string, src string, wopts WriteOptions, opts ...func(*MultiCopier)) (int64, error) {
 	if !fs.ValidPath(src) || src == "." {
 		return 0, pathErr("copy", src, fs.ErrInvalid)
 	}
@@ -181,7 +270,7 @@ func copy(ctx context.Context, api CopyAPI, buck string, dst, src string, opts .
 		return 0, pathErr("copy", dst, fs.ErrInvalid)
 	}
 	srcHead, err := api.HeadObject(ctx, &s3.HeadObjectInput{
-		Bucket: &buck,
+		Bucket: &srcBuck,
 		Key:    &src,
 	})
 	if err != nil {
@@ -195,21 +284,24 @@ func copy(ctx context.Context, api CopyAPI, buck string, dst, src string, opts .
 		}
 		return 0, fsErr
 	}
-	escapedSrc := url.QueryEscape(buck + "/" + src)
+	escapedSrc := url.QueryEscape(srcBuck + "/" + src)
 	params := &s3.CopyObjectInput{
 		Bucket:     &buck,
 		CopySource: &escapedSrc, // value must be URL-encoded
 		Key:        &dst,
 	}
+	wopts.applyCopy(params)
 	if _, err := api.CopyObject(ctx, params); err != nil {
 		// if the source is too large, try multipart copy.
 		// this error doesn't seem to have a specific type
 		// associated with it.
 		if strings.Contains(err.Error(), copySrcTooLarge) {
 			// source is too large for basic copy -- try multipart copy
-			return NewMultiCopier(api, opts...).Copy(ctx, buck, dst, src, srcHead)
+			copier := NewMultiCopier(api, opts...)
+			copier.WriteOptions = wopts
+			return copier.CopyFromBucket(ctx, buck, dst, srcBuck, src, srcHead)
 		}
-		return 0, pathErr("copy", src, err)
+		return 0, pathErr("copy", src, archivedErr(err, srcHead))
 	}
 	return *srcHead.ContentLength, nil
 }
@@ -295,6 +387,7 @@ func walkFiles(ctx context.Context, api FilesAPI, buck string, dir string) iter.
 						size:    *s3obj.Size,
 						mode:    fileMode,
 						modTime: *s3obj.LastModified,
+						sys:     &s3obj,
 					},
 				}
 				if !yield(info, nil) {
@@ -349,7 +442,7 @@ func (f *s3File) Read(p []byte) (int, error) {
 		}
 		obj, err := f.api.GetObject(f.ctx, params)
 		if err != nil {
-			return 0, err
+			return 0, pathErr("read", f.key, archivedErr(err, f.info))
 		}
 		f.body = obj.Body
 	}
@@ -358,6 +451,41 @@ func (f *s3File) Read(p []byte) (int, error) {
 	return n, err
 }
 
+// ReadAt implements io.ReaderAt using a ranged GetObject request. It does not
+// change the offset used by Read and Seek. It is safe to call ReadAt
+// concurrently, which allows large files to be read in parallel ranges.
+func (f *s3File) ReadAt(p []byte, off int64) (int, error) {
+	size := *f.info.ContentLength
+	if off < 0 {
+		return 0, errors.New("s3: negative offset")
+	}
+	if off >= size {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	end := min(off+int64(len(p)), size)
+	rangeStr := fmt.Sprintf("bytes=%d-%d", off, end-1)
+	obj, err := f.api.GetObject(f.ctx, &s3.GetObjectInput{
+		Bucket: &f.bucket,
+		Key:    &f.key,
+		Range:  &rangeStr,
+		// ensure unchanged since open
+		IfMatch:           f.info.ETag,
+		IfUnmodifiedSince: f.info.LastModified,
+	})
+	if err != nil {
+		return 0, pathErr("read", f.key, archivedErr(err, f.info))
+	}
+	defer obj.Body.Close()
+	n, err := io.ReadFull(obj.Body, p[:end-off])
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
 func (f *s3File) Close() error {
 	if f.body == nil {
 		return nil
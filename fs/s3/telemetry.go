@@ -0,0 +1,129 @@
+package s3
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/srerickson/ocfl-go/telemetry"
+)
+
+// WithTelemetry sets a telemetry provider used to create spans and update
+// request counters for each S3 API call made by the BucketFS.
+func WithTelemetry(p telemetry.Provider) func(*BucketFS) {
+	return func(bf *BucketFS) {
+		bf.telemetry = p
+	}
+}
+
+// instrumentedAPI wraps an S3API, creating a span and incrementing a request
+// counter for each API call.
+type instrumentedAPI struct {
+	S3API
+	bucket    string
+	telemetry telemetry.Provider
+}
+
+var (
+	_ S3API      = (*instrumentedAPI)(nil)
+	_ TaggingAPI = (*instrumentedAPI)(nil)
+	_ RestoreAPI = (*instrumentedAPI)(nil)
+)
+
+// start starts a span for the named operation and increments the request
+// counter.
+func (api *instrumentedAPI) start(ctx context.Context, op string, key *string) (context.Context, func(error)) {
+	attrs := []slog.Attr{
+		slog.String("s3.operation", op),
+		slog.String("s3.bucket", api.bucket),
+	}
+	api.telemetry.AddCount(ctx, telemetry.Requests, 1, attrs...)
+	if key != nil {
+		attrs = append(attrs, slog.String("s3.key", *key))
+	}
+	return api.telemetry.StartSpan(ctx, "s3."+op, attrs...)
+}
+
+func (api *instrumentedAPI) HeadObject(ctx context.Context, in *s3.HeadObjectInput, opts ...func(*s3.Options)) (out *s3.HeadObjectOutput, err error) {
+	ctx, end := api.start(ctx, "HeadObject", in.Key)
+	defer func() { end(err) }()
+	return api.S3API.HeadObject(ctx, in, opts...)
+}
+
+func (api *instrumentedAPI) GetObject(ctx context.Context, in *s3.GetObjectInput, opts ...func(*s3.Options)) (out *s3.GetObjectOutput, err error) {
+	ctx, end := api.start(ctx, "GetObject", in.Key)
+	defer func() { end(err) }()
+	return api.S3API.GetObject(ctx, in, opts...)
+}
+
+func (api *instrumentedAPI) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, opts ...func(*s3.Options)) (out *s3.ListObjectsV2Output, err error) {
+	ctx, end := api.start(ctx, "ListObjectsV2", in.Prefix)
+	defer func() { end(err) }()
+	return api.S3API.ListObjectsV2(ctx, in, opts...)
+}
+
+func (api *instrumentedAPI) PutObject(ctx context.Context, in *s3.PutObjectInput, opts ...func(*s3.Options)) (out *s3.PutObjectOutput, err error) {
+	ctx, end := api.start(ctx, "PutObject", in.Key)
+	defer func() { end(err) }()
+	return api.S3API.PutObject(ctx, in, opts...)
+}
+
+func (api *instrumentedAPI) UploadPart(ctx context.Context, in *s3.UploadPartInput, opts ...func(*s3.Options)) (out *s3.UploadPartOutput, err error) {
+	ctx, end := api.start(ctx, "UploadPart", in.Key)
+	defer func() { end(err) }()
+	return api.S3API.UploadPart(ctx, in, opts...)
+}
+
+func (api *instrumentedAPI) CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, opts ...func(*s3.Options)) (out *s3.CreateMultipartUploadOutput, err error) {
+	ctx, end := api.start(ctx, "CreateMultipartUpload", in.Key)
+	defer func() { end(err) }()
+	return api.S3API.CreateMultipartUpload(ctx, in, opts...)
+}
+
+func (api *instrumentedAPI) CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, opts ...func(*s3.Options)) (out *s3.CompleteMultipartUploadOutput, err error) {
+	ctx, end := api.start(ctx, "CompleteMultipartUpload", in.Key)
+	defer func() { end(err) }()
+	return api.S3API.CompleteMultipartUpload(ctx, in, opts...)
+}
+
+func (api *instrumentedAPI) AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, opts ...func(*s3.Options)) (out *s3.AbortMultipartUploadOutput, err error) {
+	ctx, end := api.start(ctx, "AbortMultipartUpload", in.Key)
+	defer func() { end(err) }()
+	return api.S3API.AbortMultipartUpload(ctx, in, opts...)
+}
+
+func (api *instrumentedAPI) UploadPartCopy(ctx context.Context, in *s3.UploadPartCopyInput, opts ...func(*s3.Options)) (out *s3.UploadPartCopyOutput, err error) {
+	ctx, end := api.start(ctx, "UploadPartCopy", in.Key)
+	defer func() { end(err) }()
+	return api.S3API.UploadPartCopy(ctx, in, opts...)
+}
+
+func (api *instrumentedAPI) CopyObject(ctx context.Context, in *s3.CopyObjectInput, opts ...func(*s3.Options)) (out *s3.CopyObjectOutput, err error) {
+	ctx, end := api.start(ctx, "CopyObject", in.Key)
+	defer func() { end(err) }()
+	return api.S3API.CopyObject(ctx, in, opts...)
+}
+
+func (api *instrumentedAPI) DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, opts ...func(*s3.Options)) (out *s3.DeleteObjectOutput, err error) {
+	ctx, end := api.start(ctx, "DeleteObject", in.Key)
+	defer func() { end(err) }()
+	return api.S3API.DeleteObject(ctx, in, opts...)
+}
+
+func (api *instrumentedAPI) GetObjectTagging(ctx context.Context, in *s3.GetObjectTaggingInput, opts ...func(*s3.Options)) (out *s3.GetObjectTaggingOutput, err error) {
+	ctx, end := api.start(ctx, "GetObjectTagging", in.Key)
+	defer func() { end(err) }()
+	return getObjectTagging(ctx, api.S3API, in, opts...)
+}
+
+func (api *instrumentedAPI) PutObjectTagging(ctx context.Context, in *s3.PutObjectTaggingInput, opts ...func(*s3.Options)) (out *s3.PutObjectTaggingOutput, err error) {
+	ctx, end := api.start(ctx, "PutObjectTagging", in.Key)
+	defer func() { end(err) }()
+	return putObjectTagging(ctx, api.S3API, in, opts...)
+}
+
+func (api *instrumentedAPI) RestoreObject(ctx context.Context, in *s3.RestoreObjectInput, opts ...func(*s3.Options)) (out *s3.RestoreObjectOutput, err error) {
+	ctx, end := api.start(ctx, "RestoreObject", in.Key)
+	defer func() { end(err) }()
+	return restoreObject(ctx, api.S3API, in, opts...)
+}
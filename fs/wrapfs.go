@@ -6,19 +6,33 @@ import (
 	"io/fs"
 	"iter"
 	"os"
+	"path/filepath"
 )
 
 // NewWrapFS returns a *WrapFS for accessing files in fsys.
 func NewWrapFS(fsys fs.FS) *WrapFS { return &WrapFS{FS: fsys} }
 
-// DirFS is shorthand for NewFS(os.DirFS(dir))
-func DirFS(dir string) *WrapFS { return NewWrapFS(os.DirFS(dir)) }
+// DirFS is shorthand for NewFS(os.DirFS(dir)). The returned *WrapFS also
+// reports dir as its [WrapFS.Root].
+func DirFS(dir string) *WrapFS {
+	fsys := NewWrapFS(os.DirFS(dir))
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	fsys.root = dir
+	return fsys
+}
 
 // WrapFS wraps an [io/fs.FS] and implements [DirEntriesFS].
 type WrapFS struct {
 	fs.FS
+	root string // set by DirFS
 }
 
+// Root returns the absolute path of the local directory for a *WrapFS created
+// with [DirFS]. It returns an empty string otherwise.
+func (fsys *WrapFS) Root() string { return fsys.root }
+
 // OpenFile implementes FS for WrapFS
 func (fsys *WrapFS) OpenFile(ctx context.Context, name string) (fs.File, error) {
 	if err := ctx.Err(); err != nil {
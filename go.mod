@@ -12,6 +12,8 @@ require (
 	github.com/hashicorp/go-multierror v1.1.1
 	golang.org/x/crypto v0.47.0
 	golang.org/x/sync v0.19.0
+	gopkg.in/yaml.v3 v3.0.1
+	lukechampine.com/blake3 v1.4.1
 )
 
 require (
@@ -31,5 +33,6 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.35.13 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sts v1.41.6 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
+	github.com/klauspost/cpuid/v2 v2.0.9 // indirect
 	golang.org/x/sys v0.40.0 // indirect
 )
@@ -0,0 +1,160 @@
+package ocfl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+// Ingest is used to stream content directly into a new version of an object,
+// without staging it in a separate location first. Content added to the Ingest
+// is digested as it is written to the new version's content directory. When
+// the Ingest is committed, the new version is created using the computed
+// digests. Files written to the content directory are not copied again by the
+// update plan. An Ingest is created with [Object.NewIngest]. It is safe to call
+// Add concurrently.
+type Ingest struct {
+	obj     *Object
+	head    VNum
+	mx      sync.Mutex
+	builder *StageBuilder
+	// content paths (relative to the object root) written by the ingest: true
+	// if the content was added to the new version.
+	written map[string]bool
+	adding  map[string]bool // content paths being written
+}
+
+// NewIngest returns a new *Ingest for streaming content into the object's next
+// version. The new version's initial state, content, and fixity are taken from
+// base, which may be nil (an empty state). To create a new version that
+// includes all files in the current version, use [Object.VersionStage](0) as
+// the base. Content added to the Ingest is digested with the object's digest
+// algorithm and the fixity algorithms.
+func (obj *Object) NewIngest(base *Stage, fixity ...digest.Algorithm) (*Ingest, error) {
+	if err := obj.ReadOnly(); err != nil {
+		return nil, fmt.Errorf("%q cannot be updated: %w", obj.ID(), err)
+	}
+	alg := obj.DigestAlgorithm()
+	if base != nil && base.DigestAlgorithm != nil && base.DigestAlgorithm.ID() != alg.ID() {
+		return nil, fmt.Errorf("base stage's digest algorithm (%s) doesn't match the object's (%s)", base.DigestAlgorithm.ID(), alg.ID())
+	}
+	head := V(1)
+	if obj.Exists() {
+		var err error
+		head, err = obj.Head().Next()
+		if err != nil {
+			return nil, err
+		}
+	}
+	builder := NewStageBuilder(base, fixity...)
+	builder.alg = alg
+	return &Ingest{
+		obj:     obj,
+		head:    head,
+		builder: builder,
+		written: map[string]bool{},
+		adding:  map[string]bool{},
+	}, nil
+}
+
+// Add writes content read from r to the new version's content directory using
+// the logical path name. If name already exists in the base state, it is
+// replaced. Content can only be added once for each name: if name was already
+// added to the ingest, Add returns an error wrapping [fs.ErrExist]. (The
+// content file may be the source for other names with the same content, so it
+// is never overwritten.)
+func (in *Ingest) Add(ctx context.Context, name string, r io.Reader) error {
+	if !fs.ValidPath(name) || name == "." {
+		return fmt.Errorf("invalid ingest path: %q", name)
+	}
+	contentPath := path.Join(in.head.String(), in.obj.ContentDirectory(), name)
+	fullPath := path.Join(in.obj.path, contentPath)
+	in.mx.Lock()
+	if in.written[contentPath] || in.adding[contentPath] {
+		in.mx.Unlock()
+		return &fs.PathError{Op: "ingest", Path: name, Err: fs.ErrExist}
+	}
+	in.adding[contentPath] = true
+	in.mx.Unlock()
+	digester := digest.NewMultiDigester(in.builder.algs()...)
+	ctx = in.obj.context(ctx)
+	_, err := ocflfs.Write(ctx, in.obj.fs, fullPath, io.TeeReader(r, digester))
+	in.mx.Lock()
+	defer in.mx.Unlock()
+	delete(in.adding, contentPath)
+	if err != nil {
+		// the file may be partially written: it is removed by Commit.
+		in.written[contentPath] = false
+		return fmt.Errorf("ingesting %q: %w", name, err)
+	}
+	in.written[contentPath] = true
+	in.builder.addContent(name, in.obj.fs, fullPath, digester.Sums())
+	return nil
+}
+
+// Stage returns a *Stage representing the new version's state, including
+// content added to the ingest.
+func (in *Ingest) Stage() (*Stage, error) {
+	in.mx.Lock()
+	defer in.mx.Unlock()
+	return in.builder.Finalize()
+}
+
+// Commit creates the new object version. The *UpdatePlan used to create the
+// version is returned even if an error occurs while applying it. If the update
+// succeeds, files written by the Ingest that aren't needed by the new version
+// (because the content already exists in the object) are removed.
+func (in *Ingest) Commit(ctx context.Context, msg string, user User, opts ...ObjectUpdateOption) (*UpdatePlan, error) {
+	stage, err := in.Stage()
+	if err != nil {
+		return nil, err
+	}
+	plan, err := in.obj.NewUpdatePlan(stage, msg, user, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if plan.NextHead() != in.head {
+		return nil, fmt.Errorf("update plan's new version (%s) doesn't match ingest's (%s)", plan.NextHead(), in.head)
+	}
+	if err := in.obj.ApplyUpdatePlan(ctx, plan, stage.ContentSource); err != nil {
+		return plan, err
+	}
+	// remove files that aren't in the new version's manifest.
+	ctx = in.obj.context(ctx)
+	in.mx.Lock()
+	defer in.mx.Unlock()
+	manifestPaths := map[string]bool{}
+	for p := range in.obj.inventory.Manifest.Paths() {
+		manifestPaths[p] = true
+	}
+	var errs []error
+	for p := range in.written {
+		if manifestPaths[p] {
+			continue
+		}
+		if err := ocflfs.Remove(ctx, in.obj.fs, path.Join(in.obj.path, p)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return plan, fmt.Errorf("removing unused ingest content: %w", err)
+	}
+	return plan, nil
+}
+
+// Abort removes the new version directory and all content written by the
+// ingest. It should not be called after a successful Commit.
+func (in *Ingest) Abort(ctx context.Context) error {
+	in.mx.Lock()
+	defer in.mx.Unlock()
+	clear(in.written)
+	verDir := path.Join(in.obj.path, in.head.String())
+	return ocflfs.RemoveAll(in.obj.context(ctx), in.obj.fs, verDir)
+}
@@ -0,0 +1,78 @@
+package ocfl_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/local"
+)
+
+func TestIngest(t *testing.T) {
+	ctx := context.Background()
+	fsys, err := local.NewFS(t.TempDir())
+	be.NilErr(t, err)
+	obj, err := ocfl.NewObject(ctx, fsys, "object", ocfl.ObjectWithID("object-01"))
+	be.NilErr(t, err)
+	user := ocfl.User{Name: "Tester"}
+
+	// v1
+	ingest, err := obj.NewIngest(nil, digest.MD5)
+	be.NilErr(t, err)
+	be.NilErr(t, ingest.Add(ctx, "a.txt", strings.NewReader("content a")))
+	be.NilErr(t, ingest.Add(ctx, "dir/b.txt", strings.NewReader("content b")))
+	be.NilErr(t, ingest.Add(ctx, "dir/dup.txt", strings.NewReader("content b")))
+	_, err = ingest.Commit(ctx, "v1", user)
+	be.NilErr(t, err)
+	be.NilErr(t, ocfl.ValidateObject(ctx, fsys, "object").Err())
+	be.Equal(t, 3, obj.Version(1).State().NumPaths())
+	for dig := range obj.Version(1).State() {
+		be.Nonzero(t, obj.GetFixity(dig)[digest.MD5.ID()])
+	}
+
+	// v2 includes v1 content and new content that already exists in the
+	// object.
+	ingest, err = obj.NewIngest(obj.VersionStage(0))
+	be.NilErr(t, err)
+	be.NilErr(t, ingest.Add(ctx, "c.txt", strings.NewReader("content a")))
+	be.NilErr(t, ingest.Add(ctx, "d.txt", strings.NewReader("content d")))
+	_, err = ingest.Commit(ctx, "v2", user)
+	be.NilErr(t, err)
+	be.NilErr(t, ocfl.ValidateObject(ctx, fsys, "object").Err())
+	be.Equal(t, 5, obj.Version(2).State().NumPaths())
+	_, err = ocflfs.StatFile(ctx, fsys, "object/v2/content/c.txt")
+	be.True(t, errors.Is(err, fs.ErrNotExist))
+
+	t.Run("abort", func(t *testing.T) {
+		ingest, err := obj.NewIngest(obj.VersionStage(0))
+		be.NilErr(t, err)
+		be.NilErr(t, ingest.Add(ctx, "e.txt", strings.NewReader("content e")))
+		be.NilErr(t, ingest.Abort(ctx))
+		_, err = ocflfs.StatFile(ctx, fsys, "object/v3/content/e.txt")
+		be.True(t, errors.Is(err, fs.ErrNotExist))
+		be.NilErr(t, ocfl.ValidateObject(ctx, fsys, "object").Err())
+	})
+	t.Run("duplicate name", func(t *testing.T) {
+		fsys, err := local.NewFS(t.TempDir())
+		be.NilErr(t, err)
+		obj, err := ocfl.NewObject(ctx, fsys, "object", ocfl.ObjectWithID("object-02"))
+		be.NilErr(t, err)
+		ingest, err := obj.NewIngest(nil)
+		be.NilErr(t, err)
+		be.NilErr(t, ingest.Add(ctx, "a.txt", strings.NewReader("x")))
+		be.NilErr(t, ingest.Add(ctx, "b.txt", strings.NewReader("x")))
+		// a.txt's content file is the source for b.txt
+		err = ingest.Add(ctx, "a.txt", strings.NewReader("y"))
+		be.True(t, errors.Is(err, fs.ErrExist))
+		_, err = ingest.Commit(ctx, "v1", user)
+		be.NilErr(t, err)
+		be.NilErr(t, ocfl.ValidateObject(ctx, fsys, "object").Err())
+		be.Equal(t, 2, obj.Version(1).State().NumPaths())
+	})
+}
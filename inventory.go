@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"path"
 	"regexp"
 	"slices"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/srerickson/ocfl-go/digest"
 	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/telemetry"
 )
 
 var (
@@ -157,7 +159,9 @@ func NewStoredInventory(r io.Reader) (*StoredInventory, error) {
 
 // ReadInventory reads the inventory.json file in dir and validates it. It returns
 // an error if the inventory can't be parsed or if it is invalid.
-func ReadInventory(ctx context.Context, fsys ocflfs.FS, dir string) (*StoredInventory, error) {
+func ReadInventory(ctx context.Context, fsys ocflfs.FS, dir string) (inv *StoredInventory, err error) {
+	ctx, end := telemetry.StartSpan(ctx, "ocfl.ReadInventory", slog.String("path", dir))
+	defer func() { end(err) }()
 	f, err := fsys.OpenFile(ctx, path.Join(dir, inventoryBase))
 	if err != nil {
 		return nil, err
@@ -268,10 +272,15 @@ func ValidateInventoryBytes(byts []byte) (*StoredInventory, *Validation) {
 	return imp.ValidateInventoryBytes(byts)
 }
 
+// inventorySidecar returns the contents of a sidecar file for an inventory with
+// the given digest.
+func inventorySidecar(invDigest string) string {
+	return invDigest + " " + inventoryBase + "\n"
+}
+
 func writeInventorySidecar(ctx context.Context, fsys ocflfs.FS, dir string, invDigest string, alg string) error {
-	sidecarContent := invDigest + " " + inventoryBase + "\n"
 	sideFile := path.Join(dir, inventoryBase+"."+alg)
-	if _, err := ocflfs.Write(ctx, fsys, sideFile, strings.NewReader(sidecarContent)); err != nil {
+	if _, err := ocflfs.Write(ctx, fsys, sideFile, strings.NewReader(inventorySidecar(invDigest))); err != nil {
 		return fmt.Errorf("writing inventory sidecar file: %w", err)
 	}
 	return nil
@@ -305,6 +314,8 @@ type InventoryBuilder struct {
 	}
 	contentPath  PathMutation
 	fixitySource FixitySource
+	rekeyAlg     digest.Algorithm
+	rekeyDigests map[string]string
 }
 
 // Create a new inventory builder. If prev is not nil, the builder's initial
@@ -380,6 +391,17 @@ func (b *InventoryBuilder) FixitySource(source FixitySource) *InventoryBuilder {
 	return b
 }
 
+// Rekey changes the digest algorithm used by the existing inventory's manifest
+// and version states to alg. The newDigests map existing digests to digests
+// of the same content using alg; digests are compared case-insensitively. The
+// existing digests are added to the inventory's fixity. Rekey is ignored if
+// the builder was not initialized with an existing inventory.
+func (b *InventoryBuilder) Rekey(alg digest.Algorithm, newDigests map[string]string) *InventoryBuilder {
+	b.rekeyAlg = alg
+	b.rekeyDigests = newDigests
+	return b
+}
+
 // ID sets the inventory's ID
 func (b *InventoryBuilder) ID(id string) *InventoryBuilder {
 	b.id = id
@@ -450,9 +472,53 @@ func (b *InventoryBuilder) initialInventory() (*Inventory, error) {
 			return nil, fmt.Errorf("in existing inventory %s fixity: %w", alg, err)
 		}
 	}
+	if b.rekeyAlg != nil && b.rekeyAlg.ID() != inv.DigestAlgorithm {
+		if err := b.rekey(inv); err != nil {
+			return nil, err
+		}
+	}
 	return inv, nil
 }
 
+// rekey changes inv's digest algorithm, replacing digests in the manifest and
+// version states with values from b.rekeyDigests.
+func (b *InventoryBuilder) rekey(inv *Inventory) error {
+	newDigests := make(map[string]string, len(b.rekeyDigests))
+	for dig, newDig := range b.rekeyDigests {
+		newDigests[normalizeDigest(dig)] = newDig
+	}
+	rekeyMap := func(m DigestMap) (DigestMap, error) {
+		newMap := make(DigestMap, len(m))
+		for dig, paths := range m {
+			newDig := normalizeDigest(newDigests[normalizeDigest(dig)])
+			if newDig == "" {
+				return nil, fmt.Errorf("no %s digest for existing %s digest %q", b.rekeyAlg.ID(), inv.DigestAlgorithm, dig)
+			}
+			if _, exists := newMap[newDig]; exists {
+				return nil, fmt.Errorf("multiple digests have the same %s digest: %q", b.rekeyAlg.ID(), newDig)
+			}
+			newMap[newDig] = slices.Clone(paths)
+		}
+		return newMap, nil
+	}
+	newManifest, err := rekeyMap(inv.Manifest)
+	if err != nil {
+		return fmt.Errorf("changing manifest digest algorithm: %w", err)
+	}
+	for vnum, ver := range inv.Versions {
+		ver.State, err = rekeyMap(ver.State)
+		if err != nil {
+			return fmt.Errorf("changing %s state digest algorithm: %w", vnum, err)
+		}
+	}
+	// existing manifest is preserved as fixity.
+	inv.Fixity[inv.DigestAlgorithm] = inv.Manifest
+	delete(inv.Fixity, b.rekeyAlg.ID())
+	inv.Manifest = newManifest
+	inv.DigestAlgorithm = b.rekeyAlg.ID()
+	return nil
+}
+
 func (b *InventoryBuilder) buildVersions(inv *Inventory) error {
 	for _, versionInput := range b.addedVersions {
 		newHead, err := inv.Head.Next()
@@ -0,0 +1,227 @@
+package ocfl
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"path"
+	"strings"
+	"time"
+
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+const (
+	// name of the directory in the storage root's extensions directory where
+	// update plans are journaled (see [RootWithUpdateJournal]).
+	updateJournalDir = "ocfl-go-update-journal"
+	// file extension for journal entries
+	updateJournalExt = ".json"
+)
+
+// RootWithUpdateJournal enables the update journal for objects in the root.
+// Before an object's UpdatePlan is applied, the plan is written to a journal
+// file in the storage root's extensions directory. The file is removed when
+// the update completes; if the update fails, it is rewritten with the state
+// of the plan's steps. Interrupted updates can be found with
+// [Root.PendingUpdates]. If the process applying the update is interrupted
+// (e.g., by a crash), the journal doesn't record which steps ran, so pending
+// updates are resumed or reverted using all of the plan's steps.
+func RootWithUpdateJournal() RootOption {
+	return func(root *Root) {
+		root.journal = true
+	}
+}
+
+// PendingUpdate is an interrupted or failed object update found in the root's
+// update journal (see [RootWithUpdateJournal]).
+type PendingUpdate struct {
+	ObjectID   string      // ID of the object being updated
+	ObjectPath string      // path of the object relative to the root
+	Modified   time.Time   // time the journal entry was last written
+	Plan       *UpdatePlan // the object's update plan
+
+	root *Root
+}
+
+// Resume applies the incomplete steps in the pending update's plan, using src
+// for new content, and returns the updated object. The update is removed from
+// the journal if it completes. If the update fails, the update's journal entry
+// is rewritten with the state of the plan's steps.
+func (p *PendingUpdate) Resume(ctx context.Context, src ContentSource) (*Object, error) {
+	completed, err := p.completed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		if _, err := p.root.applyJournaled(ctx, p.ObjectPath, p.Plan, src); err != nil {
+			return nil, err
+		}
+	} else if err := p.Discard(ctx); err != nil {
+		// interrupted after the update completed
+		return nil, err
+	}
+	return p.root.NewObjectDir(ctx, p.ObjectPath, ObjectWithID(p.ObjectID))
+}
+
+// Revert reverts all steps in the pending update's plan, including steps that
+// aren't marked as completed (they may have run before an interruption), and
+// removes the update from the journal. If the update has completed, Revert
+// returns [ErrRevertUpdate] and the update remains in the journal.
+func (p *PendingUpdate) Revert(ctx context.Context, src ContentSource) error {
+	completed, err := p.completed(ctx)
+	if err != nil {
+		return err
+	}
+	if completed {
+		return ErrRevertUpdate
+	}
+	objFS, objDir := p.root.fs, path.Join(p.root.dir, p.ObjectPath)
+	if err := p.Plan.revertAll(ctx, objFS, objDir, src); err != nil {
+		return errors.Join(err, p.root.writeJournal(ctx, p.ObjectPath, p.Plan))
+	}
+	return p.Discard(ctx)
+}
+
+// Discard removes the update from the journal without changing the object.
+func (p *PendingUpdate) Discard(ctx context.Context) error {
+	return p.root.removeJournal(ctx, p.ObjectPath)
+}
+
+// completed returns true if the update completed: either all of the plan's
+// steps are marked as completed or the object's root inventory sidecar has
+// the digest of the plan's new inventory (the sidecar is written last).
+func (p *PendingUpdate) completed(ctx context.Context) (bool, error) {
+	if p.Plan.Completed() {
+		return true, nil
+	}
+	objDir := path.Join(p.root.dir, p.ObjectPath)
+	alg := p.Plan.newInv.DigestAlgorithm
+	sidecarDigest, err := ReadInventorySidecar(ctx, p.root.fs, objDir, alg)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, ErrInventorySidecarContents) {
+			return false, nil
+		}
+		return false, fmt.Errorf("reading object's inventory sidecar: %w", err)
+	}
+	return strings.EqualFold(sidecarDigest, p.Plan.NextInventoryDigest()), nil
+}
+
+// PendingUpdates returns an iterator that yields updates in the root's update
+// journal: updates that were interrupted or failed (see
+// [RootWithUpdateJournal]). The journal is read even if the root wasn't
+// created with [RootWithUpdateJournal].
+func (r *Root) PendingUpdates(ctx context.Context) iter.Seq2[*PendingUpdate, error] {
+	return func(yield func(*PendingUpdate, error) bool) {
+		journalDir := r.journalDir()
+		entries, err := ocflfs.ReadDir(ctx, r.fs, journalDir)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				yield(nil, fmt.Errorf("reading update journal: %w", err))
+			}
+			return
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || !strings.HasSuffix(entry.Name(), updateJournalExt) {
+				continue
+			}
+			update, err := r.readJournal(ctx, path.Join(journalDir, entry.Name()))
+			if !yield(update, err) {
+				return
+			}
+		}
+	}
+}
+
+// applyJournaled applies plan to the object at objPath, which is relative to
+// the root. The plan is written to the journal before it is applied and is
+// removed from the journal after it completes. If the plan fails, the journal
+// is rewritten with the state of the plan's steps. The returned
+// *StoredInventory is not nil if the plan completed.
+func (r *Root) applyJournaled(ctx context.Context, objPath string, plan *UpdatePlan, src ContentSource) (*StoredInventory, error) {
+	if err := r.writeJournal(ctx, objPath, plan); err != nil {
+		return nil, err
+	}
+	newInv, err := plan.Apply(ctx, r.fs, path.Join(r.dir, objPath), src)
+	if err != nil {
+		return nil, errors.Join(err, r.writeJournal(ctx, objPath, plan))
+	}
+	return newInv, r.removeJournal(ctx, objPath)
+}
+
+// journalEntry is the contents of an update journal file. Plan is the JSON
+// encoding of the UpdatePlan (see UpdatePlan.MarshalJSON).
+type journalEntry struct {
+	ObjectPath string          `json:"object_path"`
+	Modified   time.Time       `json:"modified"`
+	Plan       json.RawMessage `json:"plan"`
+}
+
+func (r *Root) journalDir() string {
+	return path.Join(r.dir, extensionsDir, updateJournalDir)
+}
+
+// journalName returns the name of the journal file for the object at objPath,
+// which is relative to the root.
+func (r *Root) journalName(objPath string) string {
+	sum := sha256.Sum256([]byte(objPath))
+	return path.Join(r.journalDir(), hex.EncodeToString(sum[:])+updateJournalExt)
+}
+
+// writeJournal writes plan for the object at objPath to the journal.
+func (r *Root) writeJournal(ctx context.Context, objPath string, plan *UpdatePlan) error {
+	planBytes, err := plan.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("encoding update plan for journal: %w", err)
+	}
+	entryBytes, err := json.MarshalIndent(journalEntry{
+		ObjectPath: objPath,
+		Modified:   time.Now().UTC(),
+		Plan:       planBytes,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding update journal entry: %w", err)
+	}
+	if _, err := ocflfs.Write(ctx, r.fs, r.journalName(objPath), bytes.NewReader(entryBytes)); err != nil {
+		return fmt.Errorf("writing update journal entry: %w", err)
+	}
+	return nil
+}
+
+// removeJournal removes the journal file for the object at objPath.
+func (r *Root) removeJournal(ctx context.Context, objPath string) error {
+	err := ocflfs.Remove(ctx, r.fs, r.journalName(objPath))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing update journal entry: %w", err)
+	}
+	return nil
+}
+
+// readJournal reads the journal file name.
+func (r *Root) readJournal(ctx context.Context, name string) (*PendingUpdate, error) {
+	entryBytes, err := ocflfs.ReadAll(ctx, r.fs, name)
+	if err != nil {
+		return nil, fmt.Errorf("reading update journal entry: %w", err)
+	}
+	var entry journalEntry
+	if err := json.Unmarshal(entryBytes, &entry); err != nil {
+		return nil, fmt.Errorf("decoding update journal entry %q: %w", name, err)
+	}
+	plan := &UpdatePlan{}
+	if err := plan.UnmarshalJSON(entry.Plan); err != nil {
+		return nil, fmt.Errorf("decoding update plan in journal entry %q: %w", name, err)
+	}
+	return &PendingUpdate{
+		ObjectID:   plan.ObjectID(),
+		ObjectPath: entry.ObjectPath,
+		Modified:   entry.Modified,
+		Plan:       plan,
+		root:       r,
+	}, nil
+}
@@ -0,0 +1,189 @@
+package ocfl_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/digest"
+	"github.com/srerickson/ocfl-go/extension"
+	"github.com/srerickson/ocfl-go/fs/local"
+)
+
+func TestRootWithUpdateJournal(t *testing.T) {
+	ctx := context.Background()
+	content := map[string][]byte{
+		"a.txt": []byte("content a"),
+		"b.txt": []byte("content b"),
+	}
+	// setup returns a root with the update journal and a failed update for
+	// object-1.
+	setup := func(t *testing.T) (*ocfl.Root, *ocfl.Stage, string) {
+		t.Helper()
+		tmpDir := t.TempDir()
+		localFS, err := local.NewFS(tmpDir)
+		be.NilErr(t, err)
+		fsys := &flakyFS{FS: localFS, name: "a.txt", fail: 1}
+		root, err := ocfl.NewRoot(ctx, fsys, "root",
+			ocfl.InitRoot(ocfl.Spec1_1, "", extension.Ext0004()),
+			ocfl.RootWithUpdateJournal())
+		be.NilErr(t, err)
+		obj, err := root.NewObject(ctx, "object-1")
+		be.NilErr(t, err)
+		stage, err := ocfl.StageBytes(content, digest.SHA512)
+		be.NilErr(t, err)
+		_, err = obj.Update(ctx, stage, "v1", ocfl.User{Name: "Tester"})
+		be.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+		return root, stage, tmpDir
+	}
+	pending := func(t *testing.T, root *ocfl.Root) []*ocfl.PendingUpdate {
+		t.Helper()
+		var updates []*ocfl.PendingUpdate
+		for update, err := range root.PendingUpdates(ctx) {
+			be.NilErr(t, err)
+			updates = append(updates, update)
+		}
+		return updates
+	}
+	t.Run("completed update", func(t *testing.T) {
+		root, stage, _ := setup(t)
+		obj, err := root.NewObject(ctx, "object-2")
+		be.NilErr(t, err)
+		_, err = obj.Update(ctx, stage, "v1", ocfl.User{Name: "Tester"})
+		be.NilErr(t, err)
+		updates := pending(t, root)
+		be.Equal(t, 1, len(updates))
+		be.Equal(t, "object-1", updates[0].ObjectID)
+	})
+	t.Run("resume", func(t *testing.T) {
+		root, stage, _ := setup(t)
+		updates := pending(t, root)
+		be.Equal(t, 1, len(updates))
+		update := updates[0]
+		objPath, err := root.ResolveID("object-1")
+		be.NilErr(t, err)
+		be.Equal(t, objPath, update.ObjectPath)
+		be.False(t, update.Modified.IsZero())
+		be.False(t, update.Plan.Completed())
+		be.Nonzero(t, update.Plan.Err())
+		obj, err := update.Resume(ctx, stage)
+		be.NilErr(t, err)
+		be.True(t, obj.Exists())
+		be.NilErr(t, root.ValidateObject(ctx, "object-1").Err())
+		be.Equal(t, 0, len(pending(t, root)))
+	})
+	t.Run("revert", func(t *testing.T) {
+		root, stage, tmpDir := setup(t)
+		updates := pending(t, root)
+		be.Equal(t, 1, len(updates))
+		be.NilErr(t, updates[0].Revert(ctx, stage))
+		be.Equal(t, 0, len(pending(t, root)))
+		entries, err := os.ReadDir(filepath.Join(tmpDir, "root", filepath.FromSlash(updates[0].ObjectPath)))
+		if err == nil {
+			be.Equal(t, 0, len(entries))
+		}
+	})
+	t.Run("crash", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		localFS, err := local.NewFS(tmpDir)
+		be.NilErr(t, err)
+		fsys := &crashFS{FS: localFS}
+		root, err := ocfl.NewRoot(ctx, fsys, "root",
+			ocfl.InitRoot(ocfl.Spec1_1, "", extension.Ext0004()),
+			ocfl.RootWithUpdateJournal())
+		be.NilErr(t, err)
+		obj, err := root.NewObject(ctx, "object-1")
+		be.NilErr(t, err)
+		stage, err := ocfl.StageBytes(content, digest.SHA512)
+		be.NilErr(t, err)
+		_, err = obj.Update(ctx, stage, "v1", ocfl.User{Name: "Tester"})
+		be.NilErr(t, err)
+		// crash runs an update with new content that is interrupted by a
+		// crash while writing or removing a file with the given suffix.
+		crash := func(t *testing.T, newContent string, write, remove string) (*ocfl.PendingUpdate, *ocfl.Stage) {
+			t.Helper()
+			stage, err := ocfl.StageBytes(map[string][]byte{"c.txt": []byte(newContent)}, digest.SHA512)
+			be.NilErr(t, err)
+			obj, err := root.NewObject(ctx, "object-1")
+			be.NilErr(t, err)
+			fsys.write, fsys.remove = write, remove
+			defer func() { fsys.write, fsys.remove = "", "" }()
+			func() {
+				defer func() { be.Equal(t, any(errCrash), recover()) }()
+				obj.Update(ctx, stage, "update", ocfl.User{Name: "Tester"})
+			}()
+			updates := pending(t, root)
+			be.Equal(t, 1, len(updates))
+			// the journal doesn't record the steps that ran
+			be.False(t, updates[0].Plan.Completed())
+			return updates[0], stage
+		}
+		objDir := filepath.Join(tmpDir, filepath.FromSlash(obj.Path()))
+		t.Run("revert", func(t *testing.T) {
+			update, stage := crash(t, "content c", "v2/inventory.json", "")
+			_, err := os.Stat(filepath.Join(objDir, "v2", "content", "c.txt"))
+			be.NilErr(t, err)
+			be.NilErr(t, update.Revert(ctx, stage))
+			_, err = os.Stat(filepath.Join(objDir, "v2"))
+			be.True(t, errors.Is(err, fs.ErrNotExist))
+			be.Equal(t, 0, len(pending(t, root)))
+			be.NilErr(t, root.ValidateObject(ctx, "object-1").Err())
+		})
+		t.Run("resume", func(t *testing.T) {
+			update, stage := crash(t, "content c", "v2/inventory.json", "")
+			obj, err := update.Resume(ctx, stage)
+			be.NilErr(t, err)
+			be.Equal(t, "v2", obj.Head().String())
+			be.Equal(t, 0, len(pending(t, root)))
+			be.NilErr(t, root.ValidateObject(ctx, "object-1").Err())
+		})
+		t.Run("completed", func(t *testing.T) {
+			// crash while removing the journal entry
+			update, stage := crash(t, "content d", "", ".json")
+			be.True(t, errors.Is(update.Revert(ctx, stage), ocfl.ErrRevertUpdate))
+			obj, err := update.Resume(ctx, stage)
+			be.NilErr(t, err)
+			be.Equal(t, "v3", obj.Head().String())
+			be.Equal(t, 0, len(pending(t, root)))
+			be.NilErr(t, root.ValidateObject(ctx, "object-1").Err())
+		})
+	})
+	t.Run("discard", func(t *testing.T) {
+		root, _, _ := setup(t)
+		updates := pending(t, root)
+		be.Equal(t, 1, len(updates))
+		be.NilErr(t, updates[0].Discard(ctx))
+		be.Equal(t, 0, len(pending(t, root)))
+	})
+}
+
+var errCrash = errors.New("crash")
+
+// crashFS is a local.FS that panics when writing a file with the suffix write
+// or removing a file with the suffix remove, simulating a crash.
+type crashFS struct {
+	*local.FS
+	write  string
+	remove string
+}
+
+func (fsys *crashFS) Write(ctx context.Context, name string, r io.Reader) (int64, error) {
+	if fsys.write != "" && strings.HasSuffix(name, fsys.write) {
+		panic(errCrash)
+	}
+	return fsys.FS.Write(ctx, name, r)
+}
+
+func (fsys *crashFS) Remove(ctx context.Context, name string) error {
+	if fsys.remove != "" && strings.HasSuffix(name, fsys.remove) {
+		panic(errCrash)
+	}
+	return fsys.FS.Remove(ctx, name)
+}
@@ -0,0 +1,95 @@
+package ocfl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+// MigrateDigestAlgorithm changes the object's primary digest algorithm to alg
+// (sha512 or sha256). All content in the object's manifest is digested using
+// alg and the object's existing digest algorithm: if the existing digest
+// doesn't match the manifest, an error is returned. A new version is created
+// with an unchanged state. In the new version's inventory, the manifest and all
+// version states use digests from alg and the previous digests are included in
+// the fixity block. Inventories in existing version directories are not
+// changed. After the update, the object is validated and any validation errors
+// are returned. The *UpdatePlan used to create the new version is returned even
+// if an error occurs while applying it.
+func (obj *Object) MigrateDigestAlgorithm(ctx context.Context, alg digest.Algorithm, msg string, user User, opts ...ObjectUpdateOption) (*UpdatePlan, error) {
+	if !obj.Exists() {
+		return nil, errors.New("object doesn't exist")
+	}
+	if alg.ID() != digest.SHA512.ID() && alg.ID() != digest.SHA256.ID() {
+		return nil, fmt.Errorf("digest algorithm must be sha512 or sha256, not %q", alg.ID())
+	}
+	oldAlg := obj.DigestAlgorithm()
+	if alg.ID() == oldAlg.ID() {
+		return nil, fmt.Errorf("object already uses %s", alg.ID())
+	}
+	ctx = obj.context(ctx)
+	updateOpts := newObjectUpdateOptions(opts...)
+	// digest all content (first content path only) with the new algorithm
+	existing := map[string]string{}
+	for dig, contentPaths := range obj.Manifest() {
+		existing[contentPaths[0]] = normalizeDigest(dig)
+	}
+	files := func(yield func(*ocflfs.FileRef) bool) {
+		for _, name := range slices.Sorted(maps.Keys(existing)) {
+			if !yield(&ocflfs.FileRef{FS: obj.fs, BaseDir: obj.path, Path: name}) {
+				return
+			}
+		}
+	}
+	newDigests := make(map[string]string, len(existing))
+	for ref, err := range digest.DigestFilesBatch(ctx, files, updateOpts.goLimit, alg, oldAlg) {
+		if err != nil {
+			return nil, fmt.Errorf("digesting object content: %w", err)
+		}
+		expected := existing[ref.Path]
+		if got := ref.Fixity[oldAlg.ID()]; !strings.EqualFold(got, expected) {
+			return nil, &digest.DigestError{
+				Path:     ref.FullPath(),
+				Alg:      oldAlg.ID(),
+				Got:      got,
+				Expected: expected,
+			}
+		}
+		newDigests[expected] = ref.Digests[alg.ID()]
+	}
+	headState := obj.version(0).State
+	newState := make(DigestMap, len(headState))
+	for dig, paths := range headState {
+		newState[newDigests[normalizeDigest(dig)]] = slices.Clone(paths)
+	}
+	newInv, err := obj.InventoryBuilder().
+		Rekey(alg, newDigests).
+		Spec(updateOpts.spec).
+		AddVersion(newState, alg, updateOpts.created, msg, &user).
+		Finalize()
+	if err != nil {
+		return nil, fmt.Errorf("building new inventory for digest algorithm change: %w", err)
+	}
+	plan, err := newUpdatePlan(newInv, obj.inventory, obj.extensionConfigs(newInv, updateOpts.algorithms))
+	if err != nil {
+		return nil, fmt.Errorf("in object update plan: %w", err)
+	}
+	plan.setGoLimit(updateOpts.goLimit)
+	plan.setLogger(updateOpts.logger)
+	plan.setTelemetry(obj.telemetry)
+	plan.setVerify(updateOpts.verify)
+	plan.setRetry(updateOpts.retry)
+	if err := obj.ApplyUpdatePlan(ctx, plan, obj); err != nil {
+		return plan, err
+	}
+	if err := ValidateObject(ctx, obj.fs, obj.path, ValidationAlgorithms(obj.Algorithms())).Err(); err != nil {
+		return plan, fmt.Errorf("object is not valid after changing digest algorithm: %w", err)
+	}
+	return plan, nil
+}
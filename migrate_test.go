@@ -0,0 +1,88 @@
+package ocfl_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/local"
+)
+
+func TestMigrateDigestAlgorithm(t *testing.T) {
+	ctx := context.Background()
+	fsys, err := local.NewFS(t.TempDir())
+	be.NilErr(t, err)
+	obj, err := ocfl.NewObject(ctx, fsys, "object", ocfl.ObjectWithID("object-01"))
+	be.NilErr(t, err)
+	user := ocfl.User{Name: "Tester"}
+	for i, content := range []map[string][]byte{
+		{"a.txt": []byte("content a"), "b.txt": []byte("content b")},
+		{"a.txt": []byte("content a"), "c.txt": []byte("content c")},
+	} {
+		stage, err := ocfl.StageBytes(content, digest.SHA256, digest.MD5)
+		be.NilErr(t, err)
+		_, err = obj.Update(ctx, stage, "update", user)
+		be.NilErr(t, err)
+		be.Equal(t, i+1, obj.Head().Num())
+	}
+	oldManifest := obj.Manifest()
+	_, err = obj.MigrateDigestAlgorithm(ctx, digest.SHA512, "change to sha512", user)
+	be.NilErr(t, err)
+	be.Equal(t, digest.SHA512.ID(), obj.DigestAlgorithm().ID())
+	be.Equal(t, 3, obj.Head().Num())
+	be.NilErr(t, ocfl.ValidateObject(ctx, fsys, "object").Err())
+	// old sidecar is removed
+	_, err = ocflfs.StatFile(ctx, fsys, "object/inventory.json.sha256")
+	be.Nonzero(t, err)
+	// previous digests are in fixity
+	for dig := range obj.Manifest() {
+		fixity := obj.GetFixity(dig)
+		be.Nonzero(t, fixity[digest.MD5.ID()])
+		be.Nonzero(t, oldManifest[fixity[digest.SHA256.ID()]])
+	}
+	// version states have the same paths
+	for v := 1; v <= 3; v++ {
+		be.Equal(t, 128, len(obj.Version(v).State().DigestFor("a.txt")))
+	}
+	be.DeepEqual(t, obj.Version(2).State(), obj.Version(3).State())
+
+	// objects can be updated with the new algorithm
+	stage, err := ocfl.StageBytes(map[string][]byte{"d.txt": []byte("content d")}, digest.SHA512)
+	be.NilErr(t, err)
+	_, err = obj.Update(ctx, stage, "update", user)
+	be.NilErr(t, err)
+	be.NilErr(t, ocfl.ValidateObject(ctx, fsys, "object").Err())
+
+	t.Run("same algorithm", func(t *testing.T) {
+		_, err := obj.MigrateDigestAlgorithm(ctx, digest.SHA512, "change", user)
+		be.Nonzero(t, err)
+	})
+	t.Run("uppercase digests", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		fixture := filepath.Join("testdata", "object-fixtures", "1.1", "good-objects", "minimal_uppercase_digests")
+		be.NilErr(t, os.CopyFS(tmpDir, os.DirFS(fixture)))
+		fsys, err := local.NewFS(tmpDir)
+		be.NilErr(t, err)
+		obj, err := ocfl.NewObject(ctx, fsys, ".")
+		be.NilErr(t, err)
+		// Rekey accepts digests as they appear in the manifest
+		newDigests := map[string]string{}
+		for dig := range obj.Manifest() {
+			newDigests[dig] = strings.Repeat("A", 64)
+		}
+		inv, err := obj.InventoryBuilder().Rekey(digest.SHA256, newDigests).Finalize()
+		be.NilErr(t, err)
+		be.Equal(t, strings.Repeat("a", 64), inv.Manifest.DigestFor("v1/content/a_file.txt"))
+		_, err = obj.MigrateDigestAlgorithm(ctx, digest.SHA256, "change to sha256", user)
+		be.NilErr(t, err)
+		be.Equal(t, digest.SHA256.ID(), obj.DigestAlgorithm().ID())
+		be.NilErr(t, ocfl.ValidateObject(ctx, fsys, ".").Err())
+		be.Equal(t, 64, len(obj.Version(1).State().DigestFor("a_file.txt")))
+	})
+}
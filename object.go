@@ -2,6 +2,7 @@ package ocfl
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -12,9 +13,11 @@ import (
 	"time"
 
 	"github.com/srerickson/ocfl-go/digest"
+	"github.com/srerickson/ocfl-go/extension"
 	ocflfs "github.com/srerickson/ocfl-go/fs"
 	"github.com/srerickson/ocfl-go/internal/logical-fs"
 	"github.com/srerickson/ocfl-go/logging"
+	"github.com/srerickson/ocfl-go/telemetry"
 )
 
 var ErrObjectReadOnly = errors.New("object is read-only")
@@ -37,6 +40,10 @@ type Object struct {
 	root *Root
 	// expected object ID
 	requiredID string
+	// telemetry provider used to instrument object operations
+	telemetry telemetry.Provider
+	// registry of digest algorithms used by the object
+	algRegistry digest.AlgorithmRegistry
 }
 
 // NewObject returns an *Object for managing the OCFL object at directory dir in
@@ -46,48 +53,59 @@ func NewObject(ctx context.Context, fsys ocflfs.FS, dir string, opts ...ObjectOp
 		return nil, fmt.Errorf("invalid object path: %q: %w", dir, fs.ErrInvalid)
 	}
 	obj, config := newObjectAndConfig(fsys, dir, opts...)
+	ctx, end := telemetry.StartSpan(obj.context(ctx), "ocfl.NewObject", slog.String("path", dir))
+	err := obj.open(ctx, config)
+	end(err)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// open initializes obj's state using the contents of the object root
+// directory.
+func (obj *Object) open(ctx context.Context, config *newObjectConfig) error {
 	if obj.inventory == nil {
 		if err := obj.sync(ctx); err != nil {
 			if config.mustExist || !errors.Is(err, fs.ErrNotExist) {
-				return nil, err
+				return err
 			}
 		}
 	}
 	if obj.inventory != nil {
 		inv := obj.inventory
 		if obj.requiredID != "" && inv.ID != obj.requiredID {
-			err := fmt.Errorf("object has unexpected ID: %q; expected: %q", inv.ID, obj.requiredID)
-			return nil, err
+			return fmt.Errorf("object has unexpected ID: %q; expected: %q", inv.ID, obj.requiredID)
 		}
 		if !config.skipRootSidecarValidation {
-			err := inv.ValidateSidecar(ctx, fsys, dir)
+			err := inv.ValidateSidecar(ctx, obj.fs, obj.path)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			obj.inventoryIsRoot = true
 		}
-		return obj, nil
+		return nil
 	}
 	if obj.requiredID == "" {
-		return nil, ErrNoObjectID
+		return ErrNoObjectID
 	}
 	// inventory doesn't exist: open as uninitialized object. The object
 	// root directory must not exist or be an empty directory. the object's
 	// inventory is nil.
-	entries, err := ocflfs.ReadDir(ctx, fsys, dir)
+	entries, err := ocflfs.ReadDir(ctx, obj.fs, obj.path)
 	if err != nil {
 		if !errors.Is(err, fs.ErrNotExist) {
-			return nil, fmt.Errorf("reading object root directory: %w", err)
+			return fmt.Errorf("reading object root directory: %w", err)
 		}
 	}
 	rootState := ParseObjectDir(entries)
 	switch {
 	case rootState.Empty():
-		return obj, nil
+		return nil
 	case rootState.HasNamaste():
-		return nil, fmt.Errorf("incomplete OCFL object: %s: %w", inventoryBase, fs.ErrNotExist)
+		return fmt.Errorf("incomplete OCFL object: %s: %w", inventoryBase, fs.ErrNotExist)
 	default:
-		return nil, errors.New("directory is not empty: non-conforming contents")
+		return errors.New("directory is not empty: non-conforming contents")
 	}
 }
 
@@ -108,13 +126,20 @@ func (obj *Object) ApplyUpdatePlan(ctx context.Context, update *UpdatePlan, src
 	if baseInvDigest != update.BaseInventoryDigest() {
 		return errors.New("update plan does not reflect object's current inventory state")
 	}
-	newInv, err := update.Apply(ctx, obj.fs, obj.path, src)
-	if err != nil {
-		return err
+	var newInv *StoredInventory
+	var err error
+	switch {
+	case obj.root != nil && obj.root.journal:
+		relPath := rootRelPath(obj.root, obj.path)
+		newInv, err = obj.root.applyJournaled(obj.context(ctx), relPath, update, src)
+	default:
+		newInv, err = update.Apply(obj.context(ctx), obj.fs, obj.path, src)
 	}
-	obj.inventory = newInv
-	obj.inventoryIsRoot = true
-	return nil
+	if newInv != nil {
+		obj.inventory = newInv
+		obj.inventoryIsRoot = true
+	}
+	return err
 }
 
 // ContentDirectory return "content" or the value set in the root inventory.
@@ -161,15 +186,27 @@ func (obj *Object) NewUpdatePlan(stage *Stage, msg string, user User, opts ...Ob
 			return nil, errors.New("update has unchanged version state")
 		}
 	}
-	plan, err := newUpdatePlan(newInv, currentInv)
+	plan, err := newUpdatePlan(newInv, currentInv, obj.extensionConfigs(newInv, updateOpts.algorithms))
 	if err != nil {
 		return nil, fmt.Errorf("in object update plan: %w", err)
 	}
 	plan.setGoLimit(updateOpts.goLimit)
 	plan.setLogger(updateOpts.logger)
+	plan.setTelemetry(obj.telemetry)
+	plan.setVerify(updateOpts.verify)
+	plan.setRetry(updateOpts.retry)
 	return plan, nil
 }
 
+// Algorithms returns the registry of digest algorithms available for the
+// object's fixity (see [ObjectWithAlgorithms]).
+func (obj Object) Algorithms() digest.AlgorithmRegistry {
+	if obj.algRegistry.Len() == 0 {
+		return digest.DefaultRegistry()
+	}
+	return obj.algRegistry
+}
+
 // DigestAlgorithm returns sha512 unless sha256 is set in the root inventory.
 func (obj Object) DigestAlgorithm() digest.Algorithm {
 	if obj.inventory != nil && obj.inventory.DigestAlgorithm == digest.SHA256.ID() {
@@ -365,7 +402,7 @@ func (obj *Object) VersionFS(ctx context.Context, v int) (fs.FS, error) {
 		logicalNames[name] = path.Join(obj.path, realNames[0])
 	}
 	fsys := logical.NewLogicalFS(
-		ctx,
+		obj.context(ctx),
 		obj.fs,
 		logicalNames,
 		ver.Created,
@@ -395,6 +432,45 @@ func (obj Object) version(v int) *InventoryVersion {
 	return obj.inventory.version(v)
 }
 
+// extensionConfigs returns marshaled configurations for extensions that
+// provide fixity algorithms used in newInv but not in the object's current
+// inventory. Algorithms are looked-up in reg or, if reg is empty, the object's
+// registry. Configurations are indexed by extension name.
+func (obj Object) extensionConfigs(newInv *Inventory, reg digest.AlgorithmRegistry) map[string][]byte {
+	if reg.Len() == 0 {
+		reg = obj.Algorithms()
+	}
+	var configs map[string][]byte
+	for algID := range newInv.Fixity {
+		if obj.inventory != nil && obj.inventory.Fixity[algID] != nil {
+			continue
+		}
+		alg, err := reg.Get(algID)
+		if err != nil {
+			continue
+		}
+		extAlg, ok := alg.(extension.Algorithm)
+		if !ok || extAlg.Extension() == nil {
+			continue
+		}
+		ext := extAlg.Extension()
+		b, err := json.Marshal(ext)
+		if err != nil {
+			continue
+		}
+		if configs == nil {
+			configs = map[string][]byte{}
+		}
+		configs[ext.Name()] = b
+	}
+	return configs
+}
+
+// context returns a new context with the object's telemetry provider, if set.
+func (obj Object) context(ctx context.Context) context.Context {
+	return telemetry.NewContext(ctx, obj.telemetry)
+}
+
 // sync re-reads the object's root inventory, updating obj's internal state
 func (obj *Object) sync(ctx context.Context) error {
 	inv, err := ReadInventory(ctx, obj.fs, obj.path)
@@ -409,6 +485,8 @@ func (obj *Object) sync(ctx context.Context) error {
 // ValidateObject fully validates the OCFL Object at dir in fsys
 func ValidateObject(ctx context.Context, fsys ocflfs.FS, dir string, opts ...ObjectValidationOption) *ObjectValidation {
 	v := newObjectValidation(fsys, dir, opts...)
+	ctx, end := telemetry.StartSpan(v.obj.context(ctx), "ocfl.ValidateObject", slog.String("path", dir))
+	defer func() { end(v.Err()) }()
 	if !fs.ValidPath(dir) {
 		err := fmt.Errorf("invalid object path: %q: %w", dir, fs.ErrInvalid)
 		v.AddFatal(err)
@@ -432,26 +510,48 @@ func ValidateObject(ctx context.Context, fsys ocflfs.FS, dir string, opts ...Obj
 		v.AddFatal(err)
 		return v
 	}
-	if err := impl.ValidateObjectRoot(ctx, v, state); err != nil {
+	rootCtx, endRoot := telemetry.StartSpan(ctx, "ocfl.ValidateObjectRoot")
+	err = impl.ValidateObjectRoot(rootCtx, v, state)
+	endRoot(err)
+	if err != nil {
+		return v
+	}
+	if err := v.selectVersions(state.VersionDirs.Head()); err != nil {
+		v.AddFatal(err)
 		return v
 	}
 	// validate versions using previous specs
 	versionOCFL := lowestOCFL()
 	var prevInv *StoredInventory
 	for _, vnum := range state.VersionDirs.Head().Lineage() {
+		if !v.versionSelected(vnum.Num()) {
+			prevInv = nil
+			if v.versionSelected(vnum.Num() + 1) {
+				// the next version is compared to this version's inventory
+				prevInv, _ = ReadInventory(ctx, fsys, path.Join(dir, vnum.String()))
+				if prevInv != nil {
+					versionOCFL = mustGetOCFL(prevInv.Type.Spec)
+				}
+			}
+			continue
+		}
+		verCtx, endVer := telemetry.StartSpan(ctx, "ocfl.ValidateObjectVersion", slog.String("version", vnum.String()))
 		versionDir := path.Join(dir, vnum.String())
-		versionInv, err := ReadInventory(ctx, fsys, versionDir)
+		versionInv, err := ReadInventory(verCtx, fsys, versionDir)
 		if err != nil && !errors.Is(err, fs.ErrNotExist) {
-			v.AddFatal(fmt.Errorf("reading %s/inventory.json: %w", vnum, err))
+			err = fmt.Errorf("reading %s/inventory.json: %w", vnum, err)
+			v.AddFatal(err)
+			endVer(err)
 			continue
 		}
 		if versionInv != nil {
 			versionOCFL = mustGetOCFL(versionInv.Type.Spec)
 		}
-		versionOCFL.ValidateObjectVersion(ctx, v, vnum, versionInv, prevInv)
+		endVer(versionOCFL.ValidateObjectVersion(verCtx, v, vnum, versionInv, prevInv))
 		prevInv = versionInv
 	}
-	impl.ValidateObjectContent(ctx, v)
+	contentCtx, endContent := telemetry.StartSpan(ctx, "ocfl.ValidateObjectContent")
+	endContent(impl.ValidateObjectContent(contentCtx, v))
 	return v
 }
 
@@ -494,6 +594,26 @@ func ObjectWithInventory(inv *StoredInventory) ObjectOption {
 	}
 }
 
+// ObjectWithAlgorithms sets the registry of digest algorithms that may be used
+// for the object's fixity. The registry is used when creating update plans and
+// validating the object. If the registry includes algorithms defined by an
+// extension (see [extension.Algorithm]), the extension's configuration is added
+// to the object's extensions directory when the algorithm is first used in the
+// object's fixity. The default is [digest.DefaultRegistry].
+func ObjectWithAlgorithms(reg digest.AlgorithmRegistry) ObjectOption {
+	return func(o *newObjectConfig) {
+		o.algRegistry = reg
+	}
+}
+
+// ObjectWithTelemetry sets a telemetry provider used to instrument the
+// object's operations, including reading inventories and applying updates.
+func ObjectWithTelemetry(p telemetry.Provider) ObjectOption {
+	return func(o *newObjectConfig) {
+		o.telemetry = p
+	}
+}
+
 // objectWithRoot is an ObjectOption that sets the object's storage root.
 // It's only meant to be used in Root methods.
 func objectWithRoot(root *Root) ObjectOption {
@@ -501,6 +621,12 @@ func objectWithRoot(root *Root) ObjectOption {
 		if o.root == nil {
 			o.root = root
 		}
+		if o.telemetry == nil {
+			o.telemetry = root.telemetry
+		}
+		if o.algRegistry.Len() == 0 {
+			o.algRegistry = root.algRegistry
+		}
 	}
 }
 
@@ -516,6 +642,10 @@ type newObjectConfig struct {
 	root *Root
 	// storedInventory is an explicit inventory to open the object with
 	inv *StoredInventory
+	// telemetry provider
+	telemetry telemetry.Provider
+	// digest algorithm registry
+	algRegistry digest.AlgorithmRegistry
 }
 
 // create a new *Object with required feilds and apply options
@@ -525,11 +655,13 @@ func newObjectAndConfig(fsys ocflfs.FS, dir string, opts ...ObjectOption) (*Obje
 		optFn(&config)
 	}
 	return &Object{
-		fs:         fsys,
-		path:       dir,
-		root:       config.root,
-		requiredID: config.requiredID,
-		inventory:  config.inv,
+		fs:          fsys,
+		path:        dir,
+		root:        config.root,
+		requiredID:  config.requiredID,
+		inventory:   config.inv,
+		telemetry:   config.telemetry,
+		algRegistry: config.algRegistry,
 	}, &config
 }
 
@@ -570,6 +702,9 @@ type objectUpdateOptions struct {
 	contentPathFunc func(oldPaths []string) (newPaths []string)
 	logger          *slog.Logger
 	goLimit         int
+	verify          bool
+	retry           *ocflfs.RetryPolicy
+	algorithms      digest.AlgorithmRegistry // for fixity extension configs
 }
 
 func newObjectUpdateOptions(opts ...ObjectUpdateOption) *objectUpdateOptions {
@@ -638,6 +773,28 @@ func UpdateWithLogger(logger *slog.Logger) ObjectUpdateOption {
 	}
 }
 
+// UpdateWithVerify is used to verify content copied to the object before the
+// object's inventory is written. Digests stored by the object's FS are used,
+// if available (see StoredDigestsFS in the fs package); otherwise, the copied
+// content is read from the object. Content that doesn't match the expected
+// digests is copied again.
+func UpdateWithVerify() ObjectUpdateOption {
+	return func(o *objectUpdateOptions) {
+		o.verify = true
+	}
+}
+
+// UpdateWithRetry sets a retry policy for steps in the UpdatePlan that fail
+// with retryable errors. Without a retry policy, the UpdatePlan stops at the
+// first error. If the policy doesn't have a Retryable function, errors are
+// classified with [ocflfs.IsTransientError], which includes S3 throttling
+// errors.
+func UpdateWithRetry(policy *ocflfs.RetryPolicy) ObjectUpdateOption {
+	return func(o *objectUpdateOptions) {
+		o.retry = policy
+	}
+}
+
 // UpdateWithGoLimit sets the number of goroutines used to run
 // concurrent steps when running the UpdatePlan.
 func UpdateWithGoLimit(gos int) ObjectUpdateOption {
@@ -645,3 +802,11 @@ func UpdateWithGoLimit(gos int) ObjectUpdateOption {
 		o.goLimit = gos
 	}
 }
+
+// updateWithAlgorithms sets the registry used to look-up fixity algorithms
+// that may require extension configurations in the object.
+func updateWithAlgorithms(reg digest.AlgorithmRegistry) ObjectUpdateOption {
+	return func(o *objectUpdateOptions) {
+		o.algorithms = reg
+	}
+}
@@ -4,14 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"maps"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"testing/fstest"
 
@@ -458,6 +462,138 @@ func TestValidateObject(t *testing.T) {
 	})
 }
 
+func TestValidateObject_trustStoredDigests(t *testing.T) {
+	ctx := context.Background()
+	localFS, err := local.NewFS(t.TempDir())
+	be.NilErr(t, err)
+	obj, err := ocfl.NewObject(ctx, localFS, "obj", ocfl.ObjectWithID("object-1"))
+	be.NilErr(t, err)
+	stage, err := ocfl.StageBytes(map[string][]byte{
+		"a.txt": []byte("content a"),
+		"b.txt": []byte("content b"),
+	}, digest.SHA512, digest.SHA256)
+	be.NilErr(t, err)
+	_, err = obj.Update(ctx, stage, "v1", ocfl.User{Name: "Tester"})
+	be.NilErr(t, err)
+	stored := map[string]map[string]string{}
+	for dig, paths := range obj.Manifest() {
+		fixity := obj.GetFixity(dig)
+		stored[path.Join("obj", paths[0])] = map[string]string{"sha256": fixity["sha256"]}
+	}
+	fsys := &storedDigestsFS{FS: localFS, stored: stored}
+
+	t.Run("stored digests match", func(t *testing.T) {
+		fsys.contentOpened.Store(0)
+		v := ocfl.ValidateObject(ctx, fsys, "obj", ocfl.ValidationTrustStoredDigests())
+		be.NilErr(t, v.Err())
+		be.Equal(t, int64(0), fsys.contentOpened.Load())
+	})
+	t.Run("without option", func(t *testing.T) {
+		fsys.contentOpened.Store(0)
+		v := ocfl.ValidateObject(ctx, fsys, "obj")
+		be.NilErr(t, v.Err())
+		be.Equal(t, int64(2), fsys.contentOpened.Load())
+	})
+	t.Run("stored digest mismatch", func(t *testing.T) {
+		fsys.contentOpened.Store(0)
+		key := path.Join("obj", "v1", "content", "a.txt")
+		stored[key] = map[string]string{"sha256": strings.Repeat("0", 64)}
+		v := ocfl.ValidateObject(ctx, fsys, "obj", ocfl.ValidationTrustStoredDigests())
+		var verr *ocfl.ValidationError
+		be.True(t, errors.As(v.Err(), &verr))
+		be.Equal(t, "E093", verr.Code)
+		be.Equal(t, int64(0), fsys.contentOpened.Load())
+	})
+}
+
+func TestValidateObject_versions(t *testing.T) {
+	ctx := context.Background()
+	fixture := filepath.Join(`testdata`, `object-fixtures`, `1.1`, `good-objects`, `spec-ex-full`)
+	tmpDir := TempDirFixtureCopy(t, fixture)
+	// damage v1 content
+	be.NilErr(t, os.WriteFile(filepath.Join(tmpDir, "v1", "content", "image.tiff"), []byte("damaged"), 0644))
+	be.NilErr(t, os.Remove(filepath.Join(tmpDir, "v1", "content", "empty.txt")))
+	fsys := ocflfs.DirFS(tmpDir)
+	t.Run("all versions", func(t *testing.T) {
+		v := ocfl.ValidateObject(ctx, fsys, ".")
+		be.Nonzero(t, v.Err())
+	})
+	t.Run("head only", func(t *testing.T) {
+		v := ocfl.ValidateObject(ctx, fsys, ".", ocfl.ValidationHeadOnly())
+		be.NilErr(t, v.Err())
+	})
+	t.Run("selected versions", func(t *testing.T) {
+		v := ocfl.ValidateObject(ctx, fsys, ".", ocfl.ValidationVersions(ocfl.V(2), ocfl.V(3)))
+		be.NilErr(t, v.Err())
+		v = ocfl.ValidateObject(ctx, fsys, ".", ocfl.ValidationVersions(ocfl.V(1)))
+		be.Nonzero(t, v.Err())
+	})
+	t.Run("version not in object", func(t *testing.T) {
+		v := ocfl.ValidateObject(ctx, fsys, ".", ocfl.ValidationVersions(ocfl.V(4)))
+		be.Nonzero(t, v.Err())
+	})
+	t.Run("root inventory", func(t *testing.T) {
+		// the root inventory is always validated
+		be.NilErr(t, os.WriteFile(filepath.Join(tmpDir, "inventory.json.sha512"), []byte("bad"), 0644))
+		v := ocfl.ValidateObject(ctx, fsys, ".", ocfl.ValidationHeadOnly())
+		be.Nonzero(t, v.Err())
+	})
+}
+
+func TestObject_Update_contentDigests(t *testing.T) {
+	// digests are passed to the FS when content is written
+	ctx := context.Background()
+	localFS, err := local.NewFS(t.TempDir())
+	be.NilErr(t, err)
+	fsys := &digestsWriteFS{FS: localFS, digests: map[string]map[string]string{}}
+	obj, err := ocfl.NewObject(ctx, fsys, "obj", ocfl.ObjectWithID("object-1"))
+	be.NilErr(t, err)
+	stage, err := ocfl.StageBytes(map[string][]byte{"a.txt": []byte("content a")}, digest.SHA512, digest.SHA256)
+	be.NilErr(t, err)
+	_, err = obj.Update(ctx, stage, "v1", ocfl.User{Name: "Tester"})
+	be.NilErr(t, err)
+	dig := obj.Version(1).State().DigestFor("a.txt")
+	got := fsys.digests[path.Join("obj", "v1", "content", "a.txt")]
+	be.Equal(t, dig, got[digest.SHA512.ID()])
+	be.Equal(t, obj.GetFixity(dig)[digest.SHA256.ID()], got[digest.SHA256.ID()])
+}
+
+// digestsWriteFS is a local.FS that records content digests from the context
+// used to write files.
+type digestsWriteFS struct {
+	*local.FS
+	mx      sync.Mutex
+	digests map[string]map[string]string
+}
+
+func (fsys *digestsWriteFS) Write(ctx context.Context, name string, r io.Reader) (int64, error) {
+	if digests := ocflfs.ContentDigestsFromContext(ctx); digests != nil {
+		fsys.mx.Lock()
+		fsys.digests[name] = digests
+		fsys.mx.Unlock()
+	}
+	return fsys.FS.Write(ctx, name, r)
+}
+
+// storedDigestsFS is a local.FS that implements ocflfs.StoredDigestsFS and
+// counts files opened in content directories.
+type storedDigestsFS struct {
+	*local.FS
+	stored        map[string]map[string]string
+	contentOpened atomic.Int64
+}
+
+func (fsys *storedDigestsFS) OpenFile(ctx context.Context, name string) (fs.File, error) {
+	if strings.Contains(name, "/content/") {
+		fsys.contentOpened.Add(1)
+	}
+	return fsys.FS.OpenFile(ctx, name)
+}
+
+func (fsys *storedDigestsFS) StoredDigests(_ context.Context, name string) (map[string]string, error) {
+	return fsys.stored[name], nil
+}
+
 func TestValidateObject_Fixtures(t *testing.T) {
 	ctx := context.Background()
 	for _, spec := range []string{`1.0`, `1.1`} {
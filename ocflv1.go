@@ -234,6 +234,17 @@ func (imp ocflV1) ValidateInventory(inv *Inventory) *Validation {
 }
 
 func (imp ocflV1) ValidateInventoryBytes(raw []byte) (*StoredInventory, *Validation) {
+	inv, v := imp.validateInventoryBytes(raw)
+	if v.Err() != nil {
+		return nil, v
+	}
+	return inv, v
+}
+
+// validateInventoryBytes is like ValidateInventoryBytes, except the inventory
+// is returned even if validation fails. The inventory is only nil if raw isn't
+// valid json.
+func (imp ocflV1) validateInventoryBytes(raw []byte) (*StoredInventory, *Validation) {
 	specStr := string(imp.v1Spec)
 	v := &Validation{}
 	invMap := map[string]any{}
@@ -426,9 +437,6 @@ func (imp ocflV1) ValidateInventoryBytes(raw []byte) (*StoredInventory, *Validat
 		v.AddFatal(err)
 	}
 	v.Add(inv.Validate())
-	if v.Err() != nil {
-		return nil, v
-	}
 	return inv, v
 }
 
@@ -442,11 +450,15 @@ func (imp ocflV1) ValidateObjectRoot(ctx context.Context, vldr *ObjectValidation
 		switch {
 		case errors.Is(err, fs.ErrNotExist):
 			err = fmt.Errorf("%s: %w", name, ErrObjectNamasteNotExist)
-			vldr.AddFatal(verr(err, code.E003(specStr)))
+			err = verr(err, code.E003(specStr))
 		default:
-			vldr.AddFatal(verr(err, code.E007(specStr)))
+			err = verr(err, code.E007(specStr))
+		}
+		vldr.AddFatal(err)
+		// validation continues if the policy ignores the error
+		if err := vldr.policyFatal(err); err != nil {
+			return err
 		}
-		return err
 	}
 	// validate root inventory
 	invBytes, err := ocflfs.ReadAll(ctx, vldr.fs(), path.Join(vldr.path(), inventoryBase))
@@ -459,9 +471,16 @@ func (imp ocflV1) ValidateObjectRoot(ctx context.Context, vldr *ObjectValidation
 		}
 		return err
 	}
-	inv, invValidation := imp.ValidateInventoryBytes(invBytes)
+	inv, invValidation := imp.validateInventoryBytes(invBytes)
 	vldr.PrefixAdd("root inventory.json", invValidation)
-	if err := invValidation.Err(); err != nil {
+	// validation continues if the policy ignores the inventory's errors
+	if err := vldr.policyFatal(invValidation.Errors()...); err != nil {
+		return err
+	}
+	if inv == nil {
+		// validation can't continue without the inventory
+		err := fmt.Errorf("root inventory.json: %w", invValidation.Err())
+		vldr.addFatal(err)
 		return err
 	}
 	if err := inv.ValidateSidecar(ctx, vldr.fs(), vldr.path()); err != nil {
@@ -594,7 +613,11 @@ func (imp ocflV1) ValidateObjectContent(ctx context.Context, v *ObjectValidation
 		digests := v.existingContentDigests(v.fs(), v.path())
 		numgos := v.DigestConcurrency()
 		registry := v.ValidationAlgorithms()
-		for err := range digest.ValidateFilesBatch(ctx, digests, registry, numgos) {
+		validateFiles := digest.ValidateFilesBatch
+		if v.TrustStoredDigests() {
+			validateFiles = digest.ValidateStoredFilesBatch
+		}
+		for err := range validateFiles(ctx, digests, registry, numgos) {
 			var digestErr *digest.DigestError
 			isDigestErr := errors.As(err, &digestErr)
 			switch {
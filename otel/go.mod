@@ -0,0 +1,26 @@
+module github.com/srerickson/ocfl-go/otel
+
+go 1.24.2
+
+require (
+	github.com/carlmjohnson/be v0.23.2
+	github.com/srerickson/ocfl-go v0.12.0
+	go.opentelemetry.io/otel v1.38.0
+	go.opentelemetry.io/otel/metric v1.38.0
+	go.opentelemetry.io/otel/sdk v1.38.0
+	go.opentelemetry.io/otel/sdk/metric v1.38.0
+	go.opentelemetry.io/otel/trace v1.38.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	golang.org/x/sys v0.40.0 // indirect
+)
+
+// v0.12.0 is the first ocfl-go version with the telemetry package. This
+// replace is for development only: remove it after v0.12.0 is tagged and
+// before tagging a release of this module.
+replace github.com/srerickson/ocfl-go => ../
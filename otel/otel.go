@@ -0,0 +1,130 @@
+// Package otel provides an OpenTelemetry implementation of the
+// [telemetry.Provider] interface used to instrument ocfl-go. It is a separate
+// module so that ocfl-go itself doesn't depend on OpenTelemetry.
+//
+//	p := otel.New()
+//	root, err := ocfl.NewRoot(ctx, fsys, dir, ocfl.RootWithTelemetry(p))
+package otel
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/srerickson/ocfl-go/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name used for the tracer and meter.
+const ScopeName = "github.com/srerickson/ocfl-go"
+
+// Provider implements [telemetry.Provider] using OpenTelemetry.
+type Provider struct {
+	tracer   trace.Tracer
+	meter    metric.Meter
+	counters sync.Map // counter name -> metric.Int64Counter
+}
+
+var _ telemetry.Provider = (*Provider)(nil)
+
+// New returns a new *Provider. By default, it uses the global tracer and meter
+// providers.
+func New(opts ...Option) *Provider {
+	conf := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, o := range opts {
+		o(&conf)
+	}
+	return &Provider{
+		tracer: conf.tracerProvider.Tracer(ScopeName),
+		meter:  conf.meterProvider.Meter(ScopeName),
+	}
+}
+
+// StartSpan implements [telemetry.Provider] for Provider.
+func (p *Provider) StartSpan(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, func(error)) {
+	ctx, span := p.tracer.Start(ctx, name, trace.WithAttributes(convertAttrs(attrs)...))
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// AddCount implements [telemetry.Provider] for Provider.
+func (p *Provider) AddCount(ctx context.Context, name string, n int64, attrs ...slog.Attr) {
+	counter, err := p.counter(name)
+	if err != nil {
+		otel.Handle(err)
+		return
+	}
+	counter.Add(ctx, n, metric.WithAttributes(convertAttrs(attrs)...))
+}
+
+func (p *Provider) counter(name string) (metric.Int64Counter, error) {
+	if c, ok := p.counters.Load(name); ok {
+		return c.(metric.Int64Counter), nil
+	}
+	c, err := p.meter.Int64Counter(name)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := p.counters.LoadOrStore(name, c)
+	return actual.(metric.Int64Counter), nil
+}
+
+// Option is used to configure a new Provider.
+type Option func(*config)
+
+// WithTracerProvider sets the trace.TracerProvider used to create spans.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider sets the metric.MeterProvider used to create counters.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = mp
+	}
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// convertAttrs converts slog attributes to OpenTelemetry attributes.
+func convertAttrs(attrs []slog.Attr) []attribute.KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		val := a.Value.Resolve()
+		switch val.Kind() {
+		case slog.KindString:
+			kvs = append(kvs, attribute.String(a.Key, val.String()))
+		case slog.KindInt64:
+			kvs = append(kvs, attribute.Int64(a.Key, val.Int64()))
+		case slog.KindUint64:
+			kvs = append(kvs, attribute.Int64(a.Key, int64(val.Uint64())))
+		case slog.KindFloat64:
+			kvs = append(kvs, attribute.Float64(a.Key, val.Float64()))
+		case slog.KindBool:
+			kvs = append(kvs, attribute.Bool(a.Key, val.Bool()))
+		default:
+			kvs = append(kvs, attribute.String(a.Key, val.String()))
+		}
+	}
+	return kvs
+}
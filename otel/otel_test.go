@@ -0,0 +1,50 @@
+package otel_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	ocflotel "github.com/srerickson/ocfl-go/otel"
+	"github.com/srerickson/ocfl-go/telemetry"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	p := ocflotel.New(
+		ocflotel.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		ocflotel.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+	)
+	ctx = telemetry.NewContext(ctx, p)
+	t.Run("span", func(t *testing.T) {
+		_, end := telemetry.StartSpan(ctx, "test", slog.String("key", "value"), slog.Int("n", 1))
+		end(errors.New("failed"))
+		ended := spans.Ended()
+		be.Equal(t, 1, len(ended))
+		be.Equal(t, "test", ended[0].Name())
+		be.Equal(t, codes.Error, ended[0].Status().Code)
+		be.Equal(t, 2, len(ended[0].Attributes()))
+	})
+	t.Run("counter", func(t *testing.T) {
+		telemetry.AddCount(ctx, telemetry.BytesRead, 10)
+		telemetry.AddCount(ctx, telemetry.BytesRead, 5)
+		var data metricdata.ResourceMetrics
+		be.NilErr(t, reader.Collect(ctx, &data))
+		be.Equal(t, 1, len(data.ScopeMetrics))
+		metrics := data.ScopeMetrics[0].Metrics
+		be.Equal(t, 1, len(metrics))
+		be.Equal(t, telemetry.BytesRead, metrics[0].Name)
+		sum, ok := metrics[0].Data.(metricdata.Sum[int64])
+		be.True(t, ok)
+		be.Equal(t, int64(15), sum.DataPoints[0].Value)
+	})
+}
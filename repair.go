@@ -0,0 +1,308 @@
+package ocfl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+// maximum number of times RepairObject re-validates an object and plans new
+// repairs. Repairs can reveal errors that weren't previously reported: e.g.,
+// validation stops if the object declaration is invalid.
+const maxRepairRounds = 3
+
+// validation codes for errors that RepairObject may be able to fix.
+var (
+	repairNamasteCodes   = []string{"E003", "E007"}
+	repairSidecarCodes   = []string{"E058", "E060", "E061"}
+	repairVersionInvCode = "W010"
+)
+
+// RepairAction is a change to a file in an object made by [RepairObject] to
+// fix a validation error.
+type RepairAction struct {
+	// Code is the validation code for the error fixed by the repair (e.g.,
+	// "E058").
+	Code string
+	// Path is the path of the file written by the repair, relative to the
+	// object's FS.
+	Path string
+	// Description describes the repair.
+	Description string
+
+	content []byte // new file contents
+}
+
+func (a RepairAction) String() string {
+	return a.Code + ": " + a.Description
+}
+
+// RepairReport is the result of [RepairObject].
+type RepairReport struct {
+	// DryRun is true if repairs were not applied.
+	DryRun bool
+	// Actions are the repairs that were applied or, if DryRun is true, the
+	// repairs that would be applied.
+	Actions []RepairAction
+	// Validation is the result of validating the object after repairs were
+	// applied. If DryRun is true, it is the result of validating the object
+	// without repairs.
+	Validation *ObjectValidation
+}
+
+// RepairOption is used to configure [RepairObject].
+type RepairOption func(*repairConfig)
+
+type repairConfig struct {
+	apply    bool
+	vldrOpts []ObjectValidationOption
+}
+
+// RepairApply is used to apply repairs. Without it, RepairObject runs in
+// dry-run mode and doesn't change the object.
+func RepairApply() RepairOption {
+	return func(conf *repairConfig) {
+		conf.apply = true
+	}
+}
+
+// RepairWithValidation sets options used to validate the object before and
+// after repairs.
+func RepairWithValidation(opts ...ObjectValidationOption) RepairOption {
+	return func(conf *repairConfig) {
+		conf.vldrOpts = append(conf.vldrOpts, opts...)
+	}
+}
+
+// RepairObject validates the object at dir in fsys and fixes errors that can
+// be repaired safely. Repairs are based on the validation codes for errors
+// and warnings:
+//
+//   - E003, E007: the object declaration is (re)written using the OCFL
+//     version from the root inventory.
+//   - E058, E060, E061: inventory sidecar files are regenerated for valid
+//     inventories in the object root and version directories.
+//   - W010: if the head version directory is missing an inventory, the root
+//     inventory and sidecar are copied into it.
+//
+// Inventories are not regenerated if the root inventory is invalid. Sidecars
+// for the root and head version inventories are only regenerated if the two
+// inventories are identical (or the head version inventory is missing). By
+// default, RepairObject runs in dry-run mode: the returned report lists the
+// repairs that would be made but fsys isn't changed. Use [RepairApply] to
+// apply the repairs, which requires fsys to be an [ocflfs.WriteFS]. After
+// repairs are applied, the object is validated again. RepairObject only
+// returns an error if planning or applying repairs fails: validation errors
+// that remain after repairs are available in the report's Validation.
+func RepairObject(ctx context.Context, fsys ocflfs.FS, dir string, opts ...RepairOption) (*RepairReport, error) {
+	var conf repairConfig
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	report := &RepairReport{DryRun: !conf.apply}
+	report.Validation = ValidateObject(ctx, fsys, dir, conf.vldrOpts...)
+	written := map[string]bool{}
+	for range maxRepairRounds {
+		codes := repairableCodes(report.Validation)
+		if len(codes) == 0 {
+			break
+		}
+		planned, err := planRepairs(ctx, fsys, dir, codes)
+		if err != nil {
+			return report, fmt.Errorf("planning object repairs: %w", err)
+		}
+		var actions []RepairAction
+		for _, act := range planned {
+			// don't repeat repairs that didn't fix the error
+			if !written[act.Path] {
+				actions = append(actions, act)
+			}
+		}
+		if len(actions) == 0 {
+			break
+		}
+		report.Actions = append(report.Actions, actions...)
+		if report.DryRun {
+			break
+		}
+		for _, act := range actions {
+			if _, err := ocflfs.Write(ctx, fsys, act.Path, bytes.NewReader(act.content)); err != nil {
+				return report, fmt.Errorf("repairing object: %w", err)
+			}
+			written[act.Path] = true
+		}
+		report.Validation = ValidateObject(ctx, fsys, dir, conf.vldrOpts...)
+	}
+	return report, nil
+}
+
+// repairableCodes returns the codes for validation errors and warnings in v
+// that RepairObject may be able to fix.
+func repairableCodes(v *ObjectValidation) map[string]bool {
+	codes := map[string]bool{}
+	isRepairable := func(c string) bool {
+		return c == repairVersionInvCode ||
+			slices.Contains(repairNamasteCodes, c) ||
+			slices.Contains(repairSidecarCodes, c)
+	}
+	errs := append(v.Errors(), v.WarnErrors()...)
+	for _, err := range errs {
+		var vErr *ValidationError
+		if errors.As(err, &vErr) && isRepairable(vErr.Code) {
+			codes[vErr.Code] = true
+		}
+	}
+	return codes
+}
+
+// planRepairs returns repairs for the object at dir based on validation codes.
+func planRepairs(ctx context.Context, fsys ocflfs.FS, dir string, codes map[string]bool) ([]RepairAction, error) {
+	entries, err := ocflfs.ReadDir(ctx, fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	state := ParseObjectDir(entries)
+	rootBytes, err := ocflfs.ReadAll(ctx, fsys, path.Join(dir, inventoryBase))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// nothing can be repaired without the root inventory
+			return nil, nil
+		}
+		return nil, err
+	}
+	rootInv, rootVldn := ValidateInventoryBytes(rootBytes)
+	if rootVldn.Err() != nil {
+		return nil, nil
+	}
+	headDir := path.Join(dir, rootInv.Head.String())
+	headBytes, err := ocflfs.ReadAll(ctx, fsys, path.Join(headDir, inventoryBase))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	headMissing := err != nil
+	// the root inventory is only trusted for repairs if it matches the head
+	// version inventory.
+	rootIsHead := headMissing || bytes.Equal(rootBytes, headBytes)
+	checkNamaste := anyCode(codes, repairNamasteCodes...)
+	// validation stops if the declaration is invalid, so other errors may not
+	// have been reported.
+	checkSidecars := checkNamaste || anyCode(codes, repairSidecarCodes...)
+	checkHeadInv := checkNamaste || codes[repairVersionInvCode]
+	var actions []RepairAction
+	if checkNamaste {
+		decl := Namaste{Type: NamasteTypeObject, Version: rootInv.Type.Spec}
+		// don't add a declaration if one exists for a different version.
+		sameDecl := !state.HasNamaste() || state.Namaste() == decl
+		declPath := path.Join(dir, decl.Name())
+		act := RepairAction{Path: declPath, content: []byte(decl.Body())}
+		err := ValidateNamaste(ctx, fsys, declPath)
+		switch {
+		case err == nil || !sameDecl:
+		case errors.Is(err, fs.ErrNotExist):
+			act.Code = "E003"
+			act.Description = "write missing object declaration"
+			actions = append(actions, act)
+		case errors.Is(err, ErrNamasteContents):
+			act.Code = "E007"
+			act.Description = "rewrite object declaration"
+			actions = append(actions, act)
+		default:
+			return nil, err
+		}
+	}
+	if checkSidecars {
+		if rootIsHead {
+			act, err := repairSidecar(ctx, fsys, dir, rootInv)
+			if err != nil {
+				return nil, err
+			}
+			if act != nil {
+				actions = append(actions, *act)
+			}
+		}
+		for _, vnum := range state.VersionDirs {
+			if vnum == rootInv.Head && !rootIsHead {
+				continue
+			}
+			verDir := path.Join(dir, vnum.String())
+			verBytes, err := ocflfs.ReadAll(ctx, fsys, path.Join(verDir, inventoryBase))
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			verInv, verVldn := ValidateInventoryBytes(verBytes)
+			if verVldn.Err() != nil {
+				continue
+			}
+			act, err := repairSidecar(ctx, fsys, verDir, verInv)
+			if err != nil {
+				return nil, err
+			}
+			if act != nil {
+				actions = append(actions, *act)
+			}
+		}
+	}
+	if checkHeadInv && headMissing && state.HasVersionDir(rootInv.Head) {
+		actions = append(actions,
+			RepairAction{
+				Code:        repairVersionInvCode,
+				Path:        path.Join(headDir, inventoryBase),
+				Description: "copy root inventory to head version directory",
+				content:     rootBytes,
+			},
+			RepairAction{
+				Code:        repairVersionInvCode,
+				Path:        path.Join(headDir, inventoryBase+"."+rootInv.DigestAlgorithm),
+				Description: "write sidecar for head version inventory",
+				content:     []byte(inventorySidecar(rootInv.Digest())),
+			},
+		)
+	}
+	return actions, nil
+}
+
+// repairSidecar returns a RepairAction to regenerate the sidecar for the
+// inventory inv in dir, if necessary.
+func repairSidecar(ctx context.Context, fsys ocflfs.FS, dir string, inv *StoredInventory) (*RepairAction, error) {
+	sidecar := path.Join(dir, inventoryBase+"."+inv.DigestAlgorithm)
+	act := &RepairAction{
+		Path:    sidecar,
+		content: []byte(inventorySidecar(inv.Digest())),
+	}
+	expSum, err := ReadInventorySidecar(ctx, fsys, dir, inv.DigestAlgorithm)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		act.Code = "E058"
+		act.Description = "write missing inventory sidecar"
+	case errors.Is(err, ErrInventorySidecarContents):
+		act.Code = "E061"
+		act.Description = "rewrite invalid inventory sidecar"
+	case err != nil:
+		return nil, err
+	case !strings.EqualFold(expSum, inv.Digest()):
+		act.Code = "E060"
+		act.Description = "rewrite inventory sidecar with incorrect digest"
+	default:
+		return nil, nil
+	}
+	return act, nil
+}
+
+func anyCode(codes map[string]bool, vals ...string) bool {
+	for _, v := range vals {
+		if codes[v] {
+			return true
+		}
+	}
+	return false
+}
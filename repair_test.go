@@ -0,0 +1,98 @@
+package ocfl_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/local"
+)
+
+func TestRepairObject(t *testing.T) {
+	ctx := context.Background()
+	fixturesDir := filepath.Join(`testdata`, `object-fixtures`, `1.1`)
+	t.Run("repairable fixtures", func(t *testing.T) {
+		for fixture, expCode := range map[string]string{
+			"E003_no_decl":                  "E003",
+			"E007_bad_declaration_contents": "E007",
+			"E058_no_sidecar":               "E058",
+			"E061_invalid_sidecar":          "E061",
+		} {
+			t.Run(fixture, func(t *testing.T) {
+				fsys, err := local.NewFS(TempDirFixtureCopy(t, filepath.Join(fixturesDir, "bad-objects", fixture)))
+				be.NilErr(t, err)
+				// dry-run
+				report, err := ocfl.RepairObject(ctx, fsys, ".")
+				be.NilErr(t, err)
+				be.True(t, report.DryRun)
+				be.Equal(t, 1, len(report.Actions))
+				be.Equal(t, expCode, report.Actions[0].Code)
+				be.Nonzero(t, report.Validation.Err())
+				be.Nonzero(t, ocfl.ValidateObject(ctx, fsys, ".").Err())
+				// apply
+				report, err = ocfl.RepairObject(ctx, fsys, ".", ocfl.RepairApply())
+				be.NilErr(t, err)
+				be.False(t, report.DryRun)
+				be.Equal(t, expCode, report.Actions[0].Code)
+				be.NilErr(t, report.Validation.Err())
+				be.NilErr(t, ocfl.ValidateObject(ctx, fsys, ".").Err())
+			})
+		}
+	})
+	t.Run("missing head version inventory", func(t *testing.T) {
+		tmpDir := TempDirFixtureCopy(t, filepath.Join(fixturesDir, "good-objects", "spec-ex-full"))
+		be.NilErr(t, os.Remove(filepath.Join(tmpDir, "v3", "inventory.json")))
+		be.NilErr(t, os.Remove(filepath.Join(tmpDir, "v3", "inventory.json.sha512")))
+		fsys, err := local.NewFS(tmpDir)
+		be.NilErr(t, err)
+		report, err := ocfl.RepairObject(ctx, fsys, ".", ocfl.RepairApply())
+		be.NilErr(t, err)
+		be.Equal(t, 2, len(report.Actions))
+		for _, act := range report.Actions {
+			be.Equal(t, "W010", act.Code)
+		}
+		be.NilErr(t, report.Validation.Err())
+		be.NilErr(t, report.Validation.WarnErr())
+		rootInv, err := os.ReadFile(filepath.Join(tmpDir, "inventory.json"))
+		be.NilErr(t, err)
+		headInv, err := os.ReadFile(filepath.Join(tmpDir, "v3", "inventory.json"))
+		be.NilErr(t, err)
+		be.Equal(t, string(rootInv), string(headInv))
+	})
+	t.Run("multiple errors", func(t *testing.T) {
+		// the sidecar error isn't reported until the declaration is fixed.
+		tmpDir := TempDirFixtureCopy(t, filepath.Join(fixturesDir, "bad-objects", "E007_bad_declaration_contents"))
+		be.NilErr(t, os.Remove(filepath.Join(tmpDir, "inventory.json.sha512")))
+		fsys, err := local.NewFS(tmpDir)
+		be.NilErr(t, err)
+		report, err := ocfl.RepairObject(ctx, fsys, ".")
+		be.NilErr(t, err)
+		be.Equal(t, 2, len(report.Actions))
+		report, err = ocfl.RepairObject(ctx, fsys, ".", ocfl.RepairApply())
+		be.NilErr(t, err)
+		be.Equal(t, 2, len(report.Actions))
+		be.NilErr(t, report.Validation.Err())
+	})
+	t.Run("root inventory differs from head", func(t *testing.T) {
+		fixture := filepath.Join(fixturesDir, "bad-objects", "E060_E064_root_inventory_digest_mismatch")
+		fsys, err := local.NewFS(TempDirFixtureCopy(t, fixture))
+		be.NilErr(t, err)
+		report, err := ocfl.RepairObject(ctx, fsys, ".", ocfl.RepairApply())
+		be.NilErr(t, err)
+		be.Equal(t, 0, len(report.Actions))
+		be.Nonzero(t, report.Validation.Err())
+	})
+	t.Run("read-only FS", func(t *testing.T) {
+		fsys := ocflfs.DirFS(filepath.Join(fixturesDir, "bad-objects", "E058_no_sidecar"))
+		report, err := ocfl.RepairObject(ctx, fsys, ".")
+		be.NilErr(t, err)
+		be.Equal(t, 1, len(report.Actions))
+		_, err = ocfl.RepairObject(ctx, fsys, ".", ocfl.RepairApply())
+		be.True(t, errors.Is(err, ocflfs.ErrOpUnsupported))
+	})
+}
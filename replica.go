@@ -0,0 +1,229 @@
+package ocfl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"path"
+	"slices"
+
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/internal/pipeline"
+)
+
+// ErrContentNotRecovered is returned for a damaged or missing content file if
+// none of the replicas has a copy that matches the manifest digest.
+var ErrContentNotRecovered = errors.New("content not recovered: no replica has a valid copy")
+
+// ContentRecovery describes a content file in an object that was missing or
+// had the wrong digest.
+type ContentRecovery struct {
+	// ObjectID is the ID of the object with the damaged content file.
+	ObjectID string
+	// Path is the content path, relative to the object root.
+	Path string
+	// Digest is the manifest digest for the content.
+	Digest string
+	// Problem is the error found for the content file: an error wrapping
+	// fs.ErrNotExist if the file is missing or a *digest.DigestError if it has
+	// the wrong digest.
+	Problem error
+	// Replica is the replica root with the valid copy of the content. It is nil
+	// if the content was not recovered.
+	Replica *Root
+	// ReplicaPath is the path of the valid copy, relative to the replica
+	// object's root.
+	ReplicaPath string
+	// Size is the number of bytes copied. It is 0 in dry-run mode.
+	Size int64
+	// DryRun is true if the content was not copied.
+	DryRun bool
+}
+
+// RecoverOption is used to configure [RecoverContent] and
+// [RecoverObjectContent].
+type RecoverOption func(*recoverConfig)
+
+type recoverConfig struct {
+	dryRun  bool
+	goLimit int
+}
+
+// RecoverDryRun is used to find damaged content and valid replica copies
+// without copying the content.
+func RecoverDryRun() RecoverOption {
+	return func(conf *recoverConfig) {
+		conf.dryRun = true
+	}
+}
+
+// RecoverWithGoLimit sets the number of goroutines used to digest content in
+// the primary root.
+func RecoverWithGoLimit(gos int) RecoverOption {
+	return func(conf *recoverConfig) {
+		conf.goLimit = gos
+	}
+}
+
+// RecoverContent checks the content of every object in primary and replaces
+// content files that are missing or have the wrong digest with copies from
+// the replica roots. Replicas are tried in order: the first replica object
+// with a content file that matches the primary object's manifest digest is
+// used, and the copy is verified before it is written to primary with
+// [ocflfs.Copy]. Content files are identified by the object ID and the
+// manifest digest, so replica objects may use different content paths.
+//
+// RecoverContent returns an iterator that yields a *ContentRecovery for each
+// damaged content file that is found. If the content couldn't be recovered,
+// the *ContentRecovery is yielded with an error: [ErrContentNotRecovered] if
+// no replica has a valid copy, or the error from writing the copy. Other
+// errors (e.g., from reading an object in primary) are yielded with a nil
+// *ContentRecovery.
+func RecoverContent(ctx context.Context, primary *Root, replicas []*Root, opts ...RecoverOption) iter.Seq2[*ContentRecovery, error] {
+	return func(yield func(*ContentRecovery, error) bool) {
+		for obj, err := range primary.Objects(ctx) {
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			for rec, err := range RecoverObjectContent(ctx, obj, replicas, opts...) {
+				if !yield(rec, err) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// RecoverObjectContent is like [RecoverContent] for a single object. The
+// replica objects are the objects in replicas with the same ID as obj.
+func RecoverObjectContent(ctx context.Context, obj *Object, replicas []*Root, opts ...RecoverOption) iter.Seq2[*ContentRecovery, error] {
+	var conf recoverConfig
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	return func(yield func(*ContentRecovery, error) bool) {
+		if !obj.Exists() {
+			yield(nil, fmt.Errorf("object %q doesn't exist", obj.ID()))
+			return
+		}
+		// replica objects, opened as needed
+		var replicaObjs []*Object
+		openReplicas := func() {
+			if replicaObjs != nil {
+				return
+			}
+			replicaObjs = make([]*Object, len(replicas))
+			for i, r := range replicas {
+				replicaObj, err := r.NewObject(ctx, obj.ID(), ObjectMustExist())
+				if err == nil {
+					replicaObjs[i] = replicaObj
+				}
+			}
+		}
+		for rec, err := range damagedContent(ctx, obj, conf.goLimit) {
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			openReplicas()
+			if !yield(recoverFile(ctx, obj, rec, replicaObjs, conf.dryRun)) {
+				return
+			}
+		}
+	}
+}
+
+// damagedContent digests the content files in obj's manifest, yielding a
+// *ContentRecovery for files that are missing or that have the wrong digest.
+func damagedContent(ctx context.Context, obj *Object, gos int) iter.Seq2[*ContentRecovery, error] {
+	alg := obj.DigestAlgorithm().ID()
+	files := func(yield func(*digest.FileRef) bool) {
+		for name, dig := range obj.Manifest().Paths() {
+			ref := &digest.FileRef{
+				FileRef: ocflfs.FileRef{FS: obj.FS(), BaseDir: obj.Path(), Path: name},
+				Digests: digest.Set{alg: dig},
+			}
+			if !yield(ref) {
+				return
+			}
+		}
+	}
+	validate := func(ref *digest.FileRef) (*digest.FileRef, error) {
+		return ref, ref.Validate(ctx, obj.Algorithms())
+	}
+	return func(yield func(*ContentRecovery, error) bool) {
+		for result := range pipeline.Results(files, validate, gos) {
+			if result.Err == nil {
+				continue
+			}
+			var digestErr *digest.DigestError
+			if !errors.As(result.Err, &digestErr) && !errors.Is(result.Err, fs.ErrNotExist) {
+				if !yield(nil, result.Err) {
+					return
+				}
+				continue
+			}
+			rec := &ContentRecovery{
+				ObjectID: obj.ID(),
+				Path:     result.In.Path,
+				Digest:   result.In.Digests[alg],
+				Problem:  result.Err,
+			}
+			if !yield(rec, nil) {
+				return
+			}
+		}
+	}
+}
+
+// recoverFile replaces the content file described by rec using the first
+// valid copy in the replica objects.
+func recoverFile(ctx context.Context, obj *Object, rec *ContentRecovery, replicaObjs []*Object, dryRun bool) (*ContentRecovery, error) {
+	rec.DryRun = dryRun
+	alg := obj.DigestAlgorithm().ID()
+	for _, replicaObj := range replicaObjs {
+		if replicaObj == nil {
+			continue
+		}
+		// try the same content path first, then other paths with the digest
+		// if the replica uses the same algorithm.
+		candidates := []string{rec.Path}
+		if replicaObj.DigestAlgorithm().ID() == alg {
+			for _, p := range replicaObj.inventory.Manifest[rec.Digest] {
+				if !slices.Contains(candidates, p) {
+					candidates = append(candidates, p)
+				}
+			}
+		}
+		for _, name := range candidates {
+			ref := &digest.FileRef{
+				FileRef: ocflfs.FileRef{FS: replicaObj.FS(), BaseDir: replicaObj.Path(), Path: name},
+				Digests: digest.Set{alg: rec.Digest},
+			}
+			if err := ref.Validate(ctx, obj.Algorithms()); err != nil {
+				continue
+			}
+			rec.Replica = replicaObj.Root()
+			rec.ReplicaPath = name
+			if dryRun {
+				return rec, nil
+			}
+			dst := path.Join(obj.Path(), rec.Path)
+			size, err := ocflfs.Copy(ctx, obj.FS(), dst, replicaObj.FS(), ref.FullPath())
+			rec.Size = size
+			if err != nil {
+				return rec, fmt.Errorf("recovering %q from replica: %w", dst, err)
+			}
+			return rec, nil
+		}
+	}
+	return rec, fmt.Errorf("%s: %w", path.Join(obj.Path(), rec.Path), ErrContentNotRecovered)
+}
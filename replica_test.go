@@ -0,0 +1,107 @@
+package ocfl_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/digest"
+	"github.com/srerickson/ocfl-go/extension"
+	"github.com/srerickson/ocfl-go/fs/local"
+)
+
+func TestRecoverContent(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	fsys, err := local.NewFS(tmpDir)
+	be.NilErr(t, err)
+	primary, err := ocfl.NewRoot(ctx, fsys, "primary", ocfl.InitRoot(ocfl.Spec1_1, "", extension.Ext0004()))
+	be.NilErr(t, err)
+	content := map[string]map[string][]byte{
+		"object-1": {
+			"a.txt": []byte("content a"),
+			"b.txt": []byte("content b"),
+			"c.txt": []byte("content c"),
+		},
+		"object-2": {"d.txt": []byte("content d")},
+	}
+	for id, files := range content {
+		obj, err := primary.NewObject(ctx, id)
+		be.NilErr(t, err)
+		stage, err := ocfl.StageBytes(files, digest.SHA512)
+		be.NilErr(t, err)
+		_, err = obj.Update(ctx, stage, "v1", ocfl.User{Name: "Tester"})
+		be.NilErr(t, err)
+	}
+	be.NilErr(t, os.CopyFS(filepath.Join(tmpDir, "replica"), os.DirFS(filepath.Join(tmpDir, "primary"))))
+	replica, err := ocfl.NewRoot(ctx, fsys, "replica")
+	be.NilErr(t, err)
+	// a replica without the objects
+	emptyReplica, err := ocfl.NewRoot(ctx, fsys, "empty", ocfl.InitRoot(ocfl.Spec1_1, "", extension.Ext0004()))
+	be.NilErr(t, err)
+	replicas := []*ocfl.Root{emptyReplica, replica}
+
+	// damage content in primary
+	objDir, err := primary.ResolveID("object-1")
+	be.NilErr(t, err)
+	contentPath := func(root *ocfl.Root, name string) string {
+		return filepath.Join(tmpDir, root.Path(), filepath.FromSlash(objDir), "v1", "content", name)
+	}
+	be.NilErr(t, os.WriteFile(contentPath(primary, "a.txt"), []byte("damaged"), 0644))
+	be.NilErr(t, os.Remove(contentPath(primary, "b.txt")))
+	be.NilErr(t, os.WriteFile(contentPath(primary, "c.txt"), []byte("damaged"), 0644))
+	be.NilErr(t, os.WriteFile(contentPath(replica, "c.txt"), []byte("damaged"), 0644))
+
+	recoverAll := func(opts ...ocfl.RecoverOption) map[string]*ocfl.ContentRecovery {
+		t.Helper()
+		results := map[string]*ocfl.ContentRecovery{}
+		for rec, err := range ocfl.RecoverContent(ctx, primary, replicas, opts...) {
+			be.True(t, rec != nil)
+			be.Equal(t, "object-1", rec.ObjectID)
+			if rec.Path == "v1/content/c.txt" {
+				be.True(t, errors.Is(err, ocfl.ErrContentNotRecovered))
+				be.True(t, rec.Replica == nil)
+			} else {
+				be.NilErr(t, err)
+				be.Equal(t, replica, rec.Replica)
+				be.Equal(t, rec.Path, rec.ReplicaPath)
+			}
+			results[rec.Path] = rec
+		}
+		return results
+	}
+	t.Run("dry run", func(t *testing.T) {
+		results := recoverAll(ocfl.RecoverDryRun())
+		be.Equal(t, 3, len(results))
+		be.True(t, results["v1/content/a.txt"].DryRun)
+		var digestErr *digest.DigestError
+		be.True(t, errors.As(results["v1/content/a.txt"].Problem, &digestErr))
+		be.True(t, errors.Is(results["v1/content/b.txt"].Problem, fs.ErrNotExist))
+		be.Equal(t, int64(0), results["v1/content/b.txt"].Size)
+		_, err := os.Stat(contentPath(primary, "b.txt"))
+		be.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+	t.Run("recover", func(t *testing.T) {
+		results := recoverAll(ocfl.RecoverWithGoLimit(2))
+		be.Equal(t, 3, len(results))
+		be.Equal(t, int64(len("content b")), results["v1/content/b.txt"].Size)
+		for name, expect := range content["object-1"] {
+			got, err := os.ReadFile(contentPath(primary, name))
+			be.NilErr(t, err)
+			if name == "c.txt" {
+				be.Equal(t, "damaged", string(got))
+				continue
+			}
+			be.Equal(t, string(expect), string(got))
+		}
+		// only the unrecoverable file remains
+		results = recoverAll()
+		be.Equal(t, 1, len(results))
+		be.Nonzero(t, results["v1/content/c.txt"])
+	})
+}
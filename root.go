@@ -10,10 +10,13 @@ import (
 	"io/fs"
 	"iter"
 	"path"
+	"slices"
 
+	"github.com/srerickson/ocfl-go/digest"
 	"github.com/srerickson/ocfl-go/extension"
 	ocflfs "github.com/srerickson/ocfl-go/fs"
 	"github.com/srerickson/ocfl-go/internal/pipeline"
+	"github.com/srerickson/ocfl-go/telemetry"
 )
 
 const (
@@ -27,11 +30,14 @@ var ErrLayoutUndefined = errors.New("storage root's layout is undefined")
 
 // Root represents an OCFL Storage Root.
 type Root struct {
-	fs           ocflfs.FS         // root's fs
-	dir          string            // root's director relative to FS
-	spec         Spec              // OCFL spec version in storage root declaration
-	layout       extension.Layout  // layout used to resolve object ids
-	layoutConfig map[string]string // contents of `ocfl_layout.json`
+	fs           ocflfs.FS                // root's fs
+	dir          string                   // root's director relative to FS
+	spec         Spec                     // OCFL spec version in storage root declaration
+	layout       extension.Layout         // layout used to resolve object ids
+	layoutConfig map[string]string        // contents of `ocfl_layout.json`
+	telemetry    telemetry.Provider       // telemetry provider passed to objects
+	algRegistry  digest.AlgorithmRegistry // digest algorithms passed to objects
+	journal      bool                     // journal object updates
 
 	// initArgs is used to initialize new root. Values
 	// are set by InitRoot option.
@@ -77,6 +83,15 @@ func NewRoot(ctx context.Context, fsys ocflfs.FS, dir string, opts ...RootOption
 	return r, nil
 }
 
+// Algorithms returns the registry of digest algorithms used by objects in the
+// root (see [RootWithAlgorithms]).
+func (r *Root) Algorithms() digest.AlgorithmRegistry {
+	if r.algRegistry.Len() == 0 {
+		return digest.DefaultRegistry()
+	}
+	return r.algRegistry
+}
+
 // Description returns the description string from the storage roots
 // `ocfl_layout.json` file, which may be empty.
 func (r *Root) Description() string {
@@ -212,6 +227,12 @@ func (r *Root) ValidateObject(ctx context.Context, id string, opts ...ObjectVali
 
 // ValidateObjectDir validates the object at a path relative to the root.
 func (r *Root) ValidateObjectDir(ctx context.Context, dir string, opts ...ObjectValidationOption) *ObjectValidation {
+	if r.telemetry != nil {
+		opts = append([]ObjectValidationOption{ValidationTelemetry(r.telemetry)}, opts...)
+	}
+	if r.algRegistry.Len() > 0 {
+		opts = append([]ObjectValidationOption{ValidationAlgorithms(r.algRegistry)}, opts...)
+	}
 	return ValidateObject(ctx, r.fs, path.Join(r.dir, dir), opts...)
 }
 
@@ -235,7 +256,22 @@ func (r *Root) init(ctx context.Context) error {
 	}
 	r.spec = r.initArgs.spec
 	var haveLayout bool
-	for _, e := range r.initArgs.extensions {
+	extensions := r.initArgs.extensions
+	// add configs for extensions that provide algorithms in the registry
+	for _, alg := range r.algRegistry.All() {
+		extAlg, ok := alg.(extension.Algorithm)
+		if !ok || extAlg.Extension() == nil {
+			continue
+		}
+		ext := extAlg.Extension()
+		hasExt := slices.ContainsFunc(extensions, func(e extension.Extension) bool {
+			return e.Name() == ext.Name()
+		})
+		if !hasExt {
+			extensions = append(extensions, ext)
+		}
+	}
+	for _, e := range extensions {
 		layout, isLayout := e.(extension.Layout)
 		if isLayout && !haveLayout {
 			if err := r.setLayout(ctx, layout, r.initArgs.layoutDesc); err != nil {
@@ -390,6 +426,26 @@ type initRootArgs struct {
 	extensions []extension.Extension
 }
 
+// RootWithAlgorithms sets the registry of digest algorithms available for
+// objects in the root. The registry is passed to objects accessed through the
+// root (see [ObjectWithAlgorithms]) and used when validating objects. If the
+// root is initialized with [InitRoot], the configurations for extensions that
+// define algorithms in the registry are added to the root's extensions
+// directory.
+func RootWithAlgorithms(reg digest.AlgorithmRegistry) RootOption {
+	return func(root *Root) {
+		root.algRegistry = reg
+	}
+}
+
+// RootWithTelemetry sets a telemetry provider that is used to instrument
+// operations on objects in the root.
+func RootWithTelemetry(p telemetry.Provider) RootOption {
+	return func(root *Root) {
+		root.telemetry = p
+	}
+}
+
 // InitRoot returns a RootOption for initializing a new storage root as part of
 // the call to NewRoot().
 func InitRoot(spec Spec, layoutDesc string, extensions ...extension.Extension) RootOption {
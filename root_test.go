@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"io/fs"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -171,6 +172,52 @@ func TestRoot_ValidateObject(t *testing.T) {
 	})
 }
 
+func TestRoot_Algorithms(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	fsys, err := local.NewFS(tmpDir)
+	be.NilErr(t, err)
+	ext0009 := extension.Ext0009().(extension.AlgorithmRegistry)
+	reg := digest.DefaultRegistry().Append(ext0009.Algorithms().All()...)
+	root, err := ocfl.NewRoot(ctx, fsys, "root",
+		ocfl.InitRoot(ocfl.Spec1_1, "", extension.Ext0004()),
+		ocfl.RootWithAlgorithms(reg),
+	)
+	be.NilErr(t, err)
+	be.Equal(t, reg.Len(), root.Algorithms().Len())
+	// the root has a config for the extension providing the algorithms
+	_, err = os.Stat(filepath.Join(tmpDir, "root", "extensions", "0009-digest-algorithms", "config.json"))
+	be.NilErr(t, err)
+	obj, err := root.NewObject(ctx, "object-1")
+	be.NilErr(t, err)
+	be.Equal(t, reg.Len(), obj.Algorithms().Len())
+	size, err := obj.Algorithms().Get(digest.SIZE.ID())
+	be.NilErr(t, err)
+	stage, err := ocfl.StageBytes(map[string][]byte{"a.txt": []byte("content")}, digest.SHA512, size)
+	be.NilErr(t, err)
+	_, err = obj.Update(ctx, stage, "v1", ocfl.User{Name: "Tester"})
+	be.NilErr(t, err)
+	be.Equal(t, "7", obj.GetFixity(obj.Version(1).State().DigestFor("a.txt"))[digest.SIZE.ID()])
+	// the object has a config for the extension providing the fixity algorithm
+	_, err = os.Stat(filepath.Join(tmpDir, obj.Path(), "extensions", "0009-digest-algorithms", "config.json"))
+	be.NilErr(t, err)
+	// fixity is validated using the root's algorithms, without W013 warnings for
+	// the object's extension.
+	valid := root.ValidateObject(ctx, obj.ID())
+	be.NilErr(t, valid.Err())
+	for _, err := range valid.WarnErrors() {
+		var verr *ocfl.ValidationError
+		if errors.As(err, &verr) {
+			be.Unequal(t, "W013", verr.Code)
+		}
+	}
+	be.Equal(t, reg.Len(), valid.ValidationAlgorithms().Len())
+	// without the registry, size fixity isn't validated
+	valid = ocfl.ValidateObject(ctx, fsys, obj.Path())
+	be.NilErr(t, valid.Err())
+	be.Equal(t, digest.DefaultRegistry().Len(), valid.ValidationAlgorithms().Len())
+}
+
 func TestNewRoot_InvalidConfig(t *testing.T) {
 	ctx := context.Background()
 
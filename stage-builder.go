@@ -0,0 +1,337 @@
+package ocfl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"testing/fstest"
+
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+// StageBuilder is used to build a [Stage] through a series of incremental
+// changes: adding files from readers, directories, or other FSs; removing
+// files; and renaming or copying paths. Typically, the builder is initialized
+// with an existing object version (see [Object.VersionStage]). Content added
+// to the builder is digested as it is added. Methods are chainable: if a
+// method fails, subsequent calls have no effect and the error is returned by
+// Finalize.
+type StageBuilder struct {
+	alg       digest.Algorithm
+	fixity    []digest.Algorithm
+	state     PathMap
+	base      *Stage
+	added     stageContent
+	spoolFS   ocflfs.FS
+	spoolDir  string
+	memFS     fstest.MapFS
+	memWrapFS ocflfs.FS
+	numSpools int
+	err       error
+}
+
+// NewStageBuilder returns a new StageBuilder with an initial state, content
+// source, and fixity source from base. Content added to the builder is
+// digested using base's digest algorithm and the given fixity algorithms. If
+// base is nil, the builder's initial state is empty and sha512 is used as the
+// digest algorithm.
+func NewStageBuilder(base *Stage, fixity ...digest.Algorithm) *StageBuilder {
+	b := &StageBuilder{
+		alg:    digest.SHA512,
+		fixity: fixity,
+		state:  PathMap{},
+		base:   base,
+		added:  stageContent{},
+	}
+	if base != nil {
+		if base.DigestAlgorithm != nil {
+			b.alg = base.DigestAlgorithm
+		}
+		if base.State != nil {
+			b.state = base.State.PathMap()
+		}
+	}
+	return b
+}
+
+// SpoolDir sets a directory in fsys where content added with AddReader is
+// written. By default, content from readers is held in memory. The caller is
+// responsible for removing the directory when it is no longer needed.
+func (b *StageBuilder) SpoolDir(fsys ocflfs.FS, dir string) *StageBuilder {
+	b.spoolFS = fsys
+	b.spoolDir = dir
+	return b
+}
+
+// AddReader adds a file with the given logical path to the stage using content
+// read from r. If the path already exists in the stage, it is replaced.
+func (b *StageBuilder) AddReader(ctx context.Context, name string, r io.Reader) *StageBuilder {
+	if b.err != nil {
+		return b
+	}
+	if !fs.ValidPath(name) || name == "." {
+		b.err = fmt.Errorf("invalid stage path: %q", name)
+		return b
+	}
+	digester := digest.NewMultiDigester(b.algs()...)
+	b.numSpools++
+	spoolName := strconv.Itoa(b.numSpools)
+	var contentFS ocflfs.FS
+	var contentPath string
+	switch {
+	case b.spoolFS != nil:
+		contentFS = b.spoolFS
+		contentPath = path.Join(b.spoolDir, spoolName)
+		if _, err := ocflfs.Write(ctx, contentFS, contentPath, io.TeeReader(r, digester)); err != nil {
+			b.err = fmt.Errorf("adding %q to stage: %w", name, err)
+			return b
+		}
+	default:
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, io.TeeReader(r, digester)); err != nil {
+			b.err = fmt.Errorf("adding %q to stage: %w", name, err)
+			return b
+		}
+		if b.memFS == nil {
+			b.memFS = fstest.MapFS{}
+			b.memWrapFS = ocflfs.NewWrapFS(b.memFS)
+		}
+		b.memFS[spoolName] = &fstest.MapFile{Data: buf.Bytes()}
+		contentFS = b.memWrapFS
+		contentPath = spoolName
+	}
+	b.addContent(name, contentFS, contentPath, digester.Sums())
+	return b
+}
+
+// AddFile adds the file src in fsys to the stage with the logical path name. If
+// the path already exists in the stage, it is replaced.
+func (b *StageBuilder) AddFile(ctx context.Context, fsys ocflfs.FS, src string, name string) *StageBuilder {
+	if b.err != nil {
+		return b
+	}
+	if !fs.ValidPath(name) || name == "." {
+		b.err = fmt.Errorf("invalid stage path: %q", name)
+		return b
+	}
+	files := ocflfs.Files(fsys, src)
+	for ref, err := range digest.DigestFiles(ctx, files, b.alg, b.fixity...) {
+		if err != nil {
+			b.err = fmt.Errorf("adding %q to stage: %w", name, err)
+			return b
+		}
+		b.addContent(name, ref.FS, ref.FullPath(), b.digestSet(ref))
+	}
+	return b
+}
+
+// AddDir adds all files in the directory dir in fsys to the stage. Logical
+// paths for the files are relative to stageDir, which may be ".". Hidden files
+// are ignored. Existing paths in the stage are replaced.
+func (b *StageBuilder) AddDir(ctx context.Context, fsys ocflfs.FS, dir string, stageDir string) *StageBuilder {
+	if b.err != nil {
+		return b
+	}
+	if !fs.ValidPath(stageDir) {
+		b.err = fmt.Errorf("invalid stage path: %q", stageDir)
+		return b
+	}
+	files, walkErr := ocflfs.UntilErr(ocflfs.WalkFiles(ctx, fsys, dir))
+	files = ocflfs.FilterFiles(files, ocflfs.IsNotHidden)
+	validFiles, typeErr := ocflfs.UntilErr(ocflfs.CheckFileTypes(ctx, files))
+	for ref, err := range digest.DigestFiles(ctx, validFiles, b.alg, b.fixity...) {
+		if err != nil {
+			b.err = fmt.Errorf("adding directory %q to stage: %w", dir, err)
+			return b
+		}
+		b.addContent(path.Join(stageDir, ref.Path), ref.FS, ref.FullPath(), b.digestSet(ref))
+	}
+	if err := errors.Join(walkErr(), typeErr()); err != nil {
+		b.err = fmt.Errorf("adding directory %q to stage: %w", dir, err)
+	}
+	return b
+}
+
+// Remove removes paths in the stage that match the pattern. The pattern syntax
+// is the same as [path.Match]. If the pattern matches a directory, all files in
+// the directory are removed. It is an error if the pattern doesn't match any
+// paths.
+func (b *StageBuilder) Remove(pattern string) *StageBuilder {
+	if b.err != nil {
+		return b
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		b.err = fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		return b
+	}
+	var removed int
+	for name := range b.state {
+		if matchPathOrParent(pattern, name) {
+			delete(b.state, name)
+			removed++
+		}
+	}
+	if removed == 0 {
+		b.err = fmt.Errorf("no stage paths match %q: %w", pattern, fs.ErrNotExist)
+	}
+	return b
+}
+
+// Rename renames the file or directory src in the stage to dst. Existing files
+// at dst are replaced.
+func (b *StageBuilder) Rename(src, dst string) *StageBuilder {
+	return b.move(src, dst, true)
+}
+
+// Copy copies the file or directory src in the stage to dst. Existing files at
+// dst are replaced.
+func (b *StageBuilder) Copy(src, dst string) *StageBuilder {
+	return b.move(src, dst, false)
+}
+
+// Finalize returns a new Stage with the builder's state, content, and fixity.
+// It returns an error if any of the builder's previous method calls failed or
+// if the resulting state is invalid.
+func (b *StageBuilder) Finalize() (*Stage, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	state := b.state.DigestMap()
+	if err := state.Valid(); err != nil {
+		return nil, fmt.Errorf("invalid stage state: %w", err)
+	}
+	stage := &Stage{
+		State:           state,
+		DigestAlgorithm: b.alg,
+		ContentSource:   b.added,
+		FixitySource:    b.added,
+	}
+	if b.base != nil {
+		stage.addContentSource(b.base.ContentSource)
+		stage.addFixitySource(b.base.FixitySource)
+	}
+	return stage, nil
+}
+
+func (b *StageBuilder) move(src, dst string, removeSrc bool) *StageBuilder {
+	if b.err != nil {
+		return b
+	}
+	if !fs.ValidPath(src) || !fs.ValidPath(dst) {
+		b.err = fmt.Errorf("invalid stage path: %q or %q", src, dst)
+		return b
+	}
+	if src == dst {
+		return b
+	}
+	moved := PathMap{}
+	for name, dig := range b.state {
+		newName, ok := replacePathPrefix(name, src, dst)
+		if !ok {
+			continue
+		}
+		moved[newName] = dig
+		if removeSrc {
+			delete(b.state, name)
+		}
+	}
+	if len(moved) == 0 {
+		b.err = &fs.PathError{Op: "rename", Path: src, Err: fs.ErrNotExist}
+		return b
+	}
+	for name, dig := range moved {
+		b.state[name] = dig
+	}
+	return b
+}
+
+func (b *StageBuilder) addContent(name string, fsys ocflfs.FS, contentPath string, digests digest.Set) {
+	dig := digests[b.alg.ID()]
+	entry := b.added[dig]
+	if entry.fs == nil {
+		entry.fs = fsys
+		entry.path = contentPath
+	}
+	for alg, val := range digests {
+		if alg == b.alg.ID() {
+			continue
+		}
+		if entry.fixity == nil {
+			entry.fixity = digest.Set{}
+		}
+		entry.fixity[alg] = val
+	}
+	b.added[dig] = entry
+	b.state[name] = dig
+}
+
+func (b *StageBuilder) algs() []digest.Algorithm {
+	return append([]digest.Algorithm{b.alg}, b.fixity...)
+}
+
+// digestSet returns all digests (primary and fixity) for ref.
+func (b *StageBuilder) digestSet(ref *digest.FileRef) digest.Set {
+	set := make(digest.Set, len(ref.Digests)+len(ref.Fixity))
+	for alg, val := range ref.Digests {
+		set[alg] = val
+	}
+	for alg, val := range ref.Fixity {
+		set[alg] = val
+	}
+	return set
+}
+
+// matchPathOrParent returns true if name or any of its parent directories match
+// pattern.
+func matchPathOrParent(pattern, name string) bool {
+	for p := name; p != "."; p = path.Dir(p) {
+		if match, _ := path.Match(pattern, p); match {
+			return true
+		}
+	}
+	return false
+}
+
+// replacePathPrefix returns name with src replaced by dst if name is src or
+// a file in the directory src.
+func replacePathPrefix(name, src, dst string) (string, bool) {
+	if src == "." {
+		return path.Join(dst, name), true
+	}
+	if name == src {
+		return dst, true
+	}
+	if rest, ok := strings.CutPrefix(name, src+"/"); ok {
+		return path.Join(dst, rest), true
+	}
+	return "", false
+}
+
+// stageContent is a ContentSource and FixitySource for content added to a
+// StageBuilder.
+type stageContent map[string]stageContentEntry
+
+type stageContentEntry struct {
+	fs     ocflfs.FS
+	path   string
+	fixity digest.Set
+}
+
+func (c stageContent) GetContent(dig string) (ocflfs.FS, string) {
+	entry, ok := c[dig]
+	if !ok {
+		return nil, ""
+	}
+	return entry.fs, entry.path
+}
+
+func (c stageContent) GetFixity(dig string) digest.Set {
+	return c[dig].fixity
+}
@@ -0,0 +1,103 @@
+package ocfl_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/local"
+)
+
+func TestStageBuilder(t *testing.T) {
+	ctx := context.Background()
+	fsys, err := local.NewFS(t.TempDir())
+	be.NilErr(t, err)
+	testdataFS := ocflfs.DirFS(`testdata`)
+
+	obj, err := ocfl.NewObject(ctx, fsys, "object", ocfl.ObjectWithID("object-01"))
+	be.NilErr(t, err)
+	v1, err := ocfl.StageBytes(map[string][]byte{
+		"a.txt":       []byte("a"),
+		"dir/b.txt":   []byte("b"),
+		"dir/c.txt":   []byte("c"),
+		"other/d.txt": []byte("d"),
+	}, digest.SHA512)
+	be.NilErr(t, err)
+	_, err = obj.Update(ctx, v1, "v1", ocfl.User{Name: "Tester"})
+	be.NilErr(t, err)
+
+	stage, err := ocfl.NewStageBuilder(obj.VersionStage(0), digest.MD5).
+		AddReader(ctx, "new/e.txt", strings.NewReader("e")).
+		AddFile(ctx, testdataFS, "content-fixture/hello.csv", "hello.csv").
+		AddDir(ctx, testdataFS, "content-fixture/folder1", "folder1").
+		Remove("dir/b.*").
+		Rename("other", "renamed").
+		Copy("a.txt", "copies/a.txt").
+		Finalize()
+	be.NilErr(t, err)
+	be.DeepEqual(t, []string{
+		"a.txt",
+		"copies/a.txt",
+		"dir/c.txt",
+		"folder1/file.txt",
+		"folder1/folder2/file2.txt",
+		"folder1/folder2/sculpture-stone-face-head-888027.jpg",
+		"hello.csv",
+		"new/e.txt",
+		"renamed/d.txt",
+	}, slices.Sorted(slices.Values(stage.State.AllPaths())))
+	be.Equal(t, digest.SHA512.ID(), stage.DigestAlgorithm.ID())
+	// new content has fixity
+	newDigest := stage.State.DigestFor("new/e.txt")
+	be.Nonzero(t, stage.GetFixity(newDigest)[digest.MD5.ID()])
+	for dig := range stage.State {
+		be.True(t, stage.HasContent(dig))
+	}
+	_, err = obj.Update(ctx, stage, "v2", ocfl.User{Name: "Tester"})
+	be.NilErr(t, err)
+	be.NilErr(t, ocfl.ValidateObject(ctx, fsys, "object").Err())
+
+	t.Run("remove directory", func(t *testing.T) {
+		stage, err := ocfl.NewStageBuilder(obj.VersionStage(0)).Remove("folder1").Finalize()
+		be.NilErr(t, err)
+		be.Equal(t, 6, stage.State.NumPaths())
+	})
+	t.Run("empty builder", func(t *testing.T) {
+		stage, err := ocfl.NewStageBuilder(nil).
+			AddReader(ctx, "file.txt", strings.NewReader("content")).
+			Finalize()
+		be.NilErr(t, err)
+		be.Equal(t, digest.SHA512.ID(), stage.DigestAlgorithm.ID())
+		be.Equal(t, 1, stage.State.NumPaths())
+	})
+	t.Run("spool dir", func(t *testing.T) {
+		stage, err := ocfl.NewStageBuilder(nil).
+			SpoolDir(fsys, "spool").
+			AddReader(ctx, "file.txt", strings.NewReader("content")).
+			Finalize()
+		be.NilErr(t, err)
+		cFS, cPath := stage.GetContent(stage.State.DigestFor("file.txt"))
+		be.Equal[ocflfs.FS](t, fsys, cFS)
+		be.Equal(t, "spool/1", cPath)
+	})
+	t.Run("errors", func(t *testing.T) {
+		_, err := ocfl.NewStageBuilder(obj.VersionStage(0)).Remove("missing*").Finalize()
+		be.True(t, errors.Is(err, fs.ErrNotExist))
+		_, err = ocfl.NewStageBuilder(obj.VersionStage(0)).Rename("missing", "other").Finalize()
+		be.True(t, errors.Is(err, fs.ErrNotExist))
+		_, err = ocfl.NewStageBuilder(obj.VersionStage(0)).AddFile(ctx, testdataFS, "missing", "file").Finalize()
+		be.True(t, errors.Is(err, fs.ErrNotExist))
+		// file and directory with the same name
+		_, err = ocfl.NewStageBuilder(obj.VersionStage(0)).Copy("a.txt", "dir").Finalize()
+		be.Nonzero(t, err)
+		_, err = ocfl.NewStageBuilder(nil).AddReader(ctx, "../file", strings.NewReader("")).Finalize()
+		be.Nonzero(t, err)
+	})
+}
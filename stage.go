@@ -41,7 +41,9 @@ func StageBytes(content map[string][]byte, alg digest.Algorithm, fixity ...diges
 // StageDir builds a stage based on the contents of the directory dir in FS.
 // Files in dir and its subdirectories are digested with the given digest
 // algorithms and added to the stage. Hidden files are ignored. The alg argument
-// must be sha512 or sha256.
+// must be sha512 or sha256. To avoid re-digesting unchanged files when staging
+// the same directory more than once (e.g., after an interruption), use a
+// context with a digest cache (see [digest.NewCacheContext]).
 func StageDir(ctx context.Context, fsys fs.FS, dir string, alg digest.Algorithm, fixity ...digest.Algorithm) (*Stage, error) {
 	files, walkErr := fs.UntilErr(fs.WalkFiles(ctx, fsys, dir))
 	files = fs.FilterFiles(files, fs.IsNotHidden)
@@ -62,7 +64,8 @@ func StageDir(ctx context.Context, fsys fs.FS, dir string, alg digest.Algorithm,
 
 // StageFiles buils a stage from entries in files. Files are digested with the
 // given digest algorithms and added to the stage. The alg argument must be
-// sha512 or sha256.
+// sha512 or sha256. If ctx carries a digest cache (see
+// [digest.NewCacheContext]), it is used to look-up and store file digests.
 func StageFiles(ctx context.Context, files iter.Seq[*fs.FileRef], alg digest.Algorithm, fixity ...digest.Algorithm) (*Stage, error) {
 	if alg.ID() != digest.SHA512.ID() && alg.ID() != digest.SHA256.ID() {
 		return nil, fmt.Errorf("at least one algorithm (sha512 or sha256) must be provided")
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"io/fs"
+	"path/filepath"
 	"testing"
 
 	"github.com/carlmjohnson/be"
@@ -56,3 +57,22 @@ func TestStageDir(t *testing.T) {
 	be.NilErr(t, err)
 	be.Nonzero(t, stage.FixitySource.GetFixity(expDigest)[`md5`])
 }
+
+func TestStageDir_cache(t *testing.T) {
+	ctx := context.Background()
+	testdataFS := ocflfs.DirFS(`testdata`)
+	cache, err := digest.OpenFileCache(filepath.Join(t.TempDir(), "cache.jsonl"))
+	be.NilErr(t, err)
+	defer cache.Close()
+	ctx = digest.NewCacheContext(ctx, cache)
+	stage1, err := ocfl.StageDir(ctx, testdataFS, "content-fixture", digest.SHA256, digest.MD5)
+	be.NilErr(t, err)
+	be.Equal(t, 4, cache.Len()) // non-hidden files
+	// second stage uses cached digests
+	stage2, err := ocfl.StageDir(ctx, testdataFS, "content-fixture", digest.SHA256, digest.MD5)
+	be.NilErr(t, err)
+	be.True(t, stage1.State.Eq(stage2.State))
+	for dig := range stage2.State {
+		be.Nonzero(t, stage2.FixitySource.GetFixity(dig)[`md5`])
+	}
+}
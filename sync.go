@@ -0,0 +1,510 @@
+package ocfl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/srerickson/ocfl-go/digest"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/internal/pipeline"
+)
+
+// ErrSyncConflict is returned when a target object can't be updated from its
+// source because the target object's root inventory doesn't match any
+// version inventory in the source object.
+var ErrSyncConflict = errors.New("target object is not a previous state of the source object")
+
+// SyncAction is a change made to a target object by [SyncObject] or
+// [SyncRoots].
+type SyncAction uint8
+
+const (
+	// SyncUnchanged indicates that the target object has the same root
+	// inventory as the source object.
+	SyncUnchanged SyncAction = iota
+	// SyncCreate indicates that the object doesn't exist in the target.
+	SyncCreate
+	// SyncUpdate indicates that the source object has new versions.
+	SyncUpdate
+	// SyncDelete indicates that the object is in the target but not the
+	// source.
+	SyncDelete
+)
+
+func (a SyncAction) String() string {
+	switch a {
+	case SyncUnchanged:
+		return "unchanged"
+	case SyncCreate:
+		return "create"
+	case SyncUpdate:
+		return "update"
+	case SyncDelete:
+		return "delete"
+	default:
+		return fmt.Sprintf("SyncAction(%d)", uint8(a))
+	}
+}
+
+// ObjectSync describes changes to an object in a target storage root made by
+// [SyncObject] or [SyncRoots]. In dry-run mode, it describes the changes that
+// would be made.
+type ObjectSync struct {
+	// ID is the object's ID.
+	ID string
+	// Path is the object's path in the target FS.
+	Path string
+	// Action is the change to the target object.
+	Action SyncAction
+	// SourceDigest and TargetDigest are the root inventory digests for the
+	// source and target objects before the sync. They are empty if the
+	// object doesn't exist.
+	SourceDigest string
+	TargetDigest string
+	// Versions are the version directories copied to the target.
+	Versions VNums
+	// Files are the files copied to the target, relative to the object root,
+	// in the order they are copied.
+	Files []string
+	// Removed are the files removed from the target, relative to the object
+	// root. If the object was deleted, it is "."
+	Removed []string
+	// Size is the total size of Files in bytes.
+	Size int64
+	// DryRun is true if the target was not changed.
+	DryRun bool
+}
+
+// SyncOption is used to configure [SyncObject] and [SyncRoots].
+type SyncOption func(*syncConfig)
+
+type syncConfig struct {
+	dryRun  bool
+	delete  bool
+	goLimit int
+}
+
+// SyncDryRun is used to report changes that would be made by a sync without
+// changing the target.
+func SyncDryRun() SyncOption {
+	return func(conf *syncConfig) {
+		conf.dryRun = true
+	}
+}
+
+// SyncWithDelete is used to delete objects from the target root that aren't
+// in the source root.
+func SyncWithDelete() SyncOption {
+	return func(conf *syncConfig) {
+		conf.delete = true
+	}
+}
+
+// SyncWithGoLimit sets the number of goroutines used to read objects in the
+// source root and to copy files.
+func SyncWithGoLimit(gos int) SyncOption {
+	return func(conf *syncConfig) {
+		conf.goLimit = gos
+	}
+}
+
+// SyncRoots copies objects from the src root to the dst root, so dst
+// becomes a replica of src. Objects in dst are stored at the same path
+// (relative to the root) as in src. Objects are compared using their root
+// inventory digests: only objects that are new or that have new versions are
+// copied (see [SyncObject]). If [SyncWithDelete] is used, objects in dst that
+// aren't in src are deleted. SyncRoots doesn't change the storage root
+// declaration, layout, or extensions in dst.
+//
+// SyncRoots returns an iterator that yields an *ObjectSync for each object
+// that is created, updated, or deleted. Errors for individual objects are
+// yielded with the object's *ObjectSync, if possible, and do not stop the
+// sync. A sync that is interrupted can be resumed by running it again:
+// objects that were synced are unchanged, and files that were copied for
+// incomplete objects are not copied again.
+func SyncRoots(ctx context.Context, src, dst *Root, opts ...SyncOption) iter.Seq2[*ObjectSync, error] {
+	var conf syncConfig
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	return func(yield func(*ObjectSync, error) bool) {
+		for srcObj, err := range src.ObjectsBatch(ctx, conf.goLimit) {
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			dstDir := path.Join(dst.dir, rootRelPath(src, srcObj.Path()))
+			result, err := SyncObject(ctx, srcObj, dst.fs, dstDir, opts...)
+			if result != nil && result.Action == SyncUnchanged && err == nil {
+				continue
+			}
+			if !yield(result, err) {
+				return
+			}
+		}
+		if !conf.delete {
+			return
+		}
+		var deletes []*Object
+		for dstObj, err := range dst.Objects(ctx) {
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				continue
+			}
+			srcDir := path.Join(src.dir, rootRelPath(dst, dstObj.Path()))
+			entries, err := ocflfs.ReadDir(ctx, src.fs, srcDir)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				if !yield(nil, fmt.Errorf("reading source object %q: %w", dstObj.ID(), err)) {
+					return
+				}
+				continue
+			}
+			if !ParseObjectDir(entries).HasNamaste() {
+				deletes = append(deletes, dstObj)
+			}
+		}
+		// objects are deleted after the target root is scanned.
+		for _, dstObj := range deletes {
+			if !yield(deleteSyncObject(ctx, dstObj, conf.dryRun)) {
+				return
+			}
+		}
+	}
+}
+
+// SyncObject copies new versions from the src object to the object at dstDir
+// in dstFS, which may not exist. If the target object exists, its root
+// inventory must be identical to the inventory for one of src's versions.
+// Files are copied in an order that keeps the target valid: for each new
+// version, content files are copied before the version inventory and sidecar.
+// The root inventory and sidecar are copied after all new versions, followed
+// by the object declaration if the target is a new object. Files in the
+// version directories of new versions that already exist in the target with
+// the expected size and digest are not copied again, so a sync that was
+// interrupted can be resumed. Other files in src's object root (e.g., object
+// extensions) are always copied, and files in the target object root that
+// aren't in src (e.g., a previous declaration or sidecar) are removed. If the
+// target's root inventory matches src's but the other files in the object
+// root don't (e.g., because a sync was interrupted after the root inventory
+// was copied), the sync is resumed with no new versions and the action is
+// SyncUpdate.
+func SyncObject(ctx context.Context, src *Object, dstFS ocflfs.FS, dstDir string, opts ...SyncOption) (*ObjectSync, error) {
+	var conf syncConfig
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	if !src.Exists() {
+		return nil, fmt.Errorf("source object %q doesn't exist", src.ID())
+	}
+	result := &ObjectSync{
+		ID:           src.ID(),
+		Path:         dstDir,
+		SourceDigest: src.InventoryDigest(),
+		DryRun:       conf.dryRun,
+	}
+	entries, err := ocflfs.ReadDir(ctx, dstFS, dstDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return result, fmt.Errorf("%s: reading target object: %w", src.ID(), err)
+	}
+	dstState := ParseObjectDir(entries)
+	firstNew := 1
+	// without a declaration, the target is new or was partially copied.
+	if dstState.HasNamaste() {
+		dstInv, err := ReadInventory(ctx, dstFS, dstDir)
+		if err != nil {
+			return result, fmt.Errorf("%s: reading target inventory: %w", src.ID(), err)
+		}
+		result.TargetDigest = dstInv.Digest()
+		if dstInv.ID != src.ID() {
+			return result, fmt.Errorf("target object has a different ID (%q): %w", dstInv.ID, ErrSyncConflict)
+		}
+		dstHead := dstInv.Head
+		if result.TargetDigest == result.SourceDigest {
+			synced, err := syncedObjectRoot(ctx, src, dstFS, dstDir, entries)
+			if err != nil {
+				return result, fmt.Errorf("%s: checking target object root: %w", src.ID(), err)
+			}
+			if synced {
+				return result, nil
+			}
+			// a previous sync was interrupted: the target's head is the
+			// same as src's.
+		}
+		if result.TargetDigest != result.SourceDigest && dstHead.Num() >= src.Head().Num() {
+			return result, fmt.Errorf("%s: target head is %s: %w", src.ID(), dstHead, ErrSyncConflict)
+		}
+		srcVerInv, err := ReadInventory(ctx, src.FS(), path.Join(src.Path(), dstHead.String()))
+		if err != nil {
+			return result, fmt.Errorf("%s: reading source %s inventory: %w", src.ID(), dstHead, err)
+		}
+		if srcVerInv.Digest() != result.TargetDigest {
+			return result, fmt.Errorf("%s: target inventory doesn't match source %s inventory: %w", src.ID(), dstHead, ErrSyncConflict)
+		}
+		result.Action = SyncUpdate
+		firstNew = dstHead.Num() + 1
+	} else {
+		result.Action = SyncCreate
+	}
+	for _, v := range src.Head().Lineage() {
+		if v.Num() >= firstNew {
+			result.Versions = append(result.Versions, v)
+		}
+	}
+	steps, removed, err := planObjectSync(ctx, src, dstFS, dstDir, result.Versions, result.Action == SyncCreate, conf.goLimit)
+	if err != nil {
+		return result, err
+	}
+	for _, step := range steps {
+		for _, f := range step {
+			result.Files = append(result.Files, f.Path)
+			if f.Info != nil {
+				result.Size += f.Info.Size()
+			}
+		}
+	}
+	result.Removed = removed
+	if conf.dryRun {
+		return result, nil
+	}
+	copyFile := func(f *ocflfs.FileRef) (*ocflfs.FileRef, error) {
+		_, err := ocflfs.Copy(ctx, dstFS, path.Join(dstDir, f.Path), src.FS(), f.FullPath())
+		return f, err
+	}
+	for _, step := range steps {
+		for r := range pipeline.Results(slices.Values(step), copyFile, conf.goLimit) {
+			if r.Err != nil {
+				return result, fmt.Errorf("%s: copying %s: %w", src.ID(), r.In.Path, r.Err)
+			}
+		}
+	}
+	for _, name := range removed {
+		if err := ocflfs.Remove(ctx, dstFS, path.Join(dstDir, name)); err != nil {
+			return result, fmt.Errorf("%s: removing %s: %w", src.ID(), name, err)
+		}
+	}
+	return result, nil
+}
+
+// planObjectSync returns groups of files in src that should be copied to
+// dstDir, in order. Files in a group can be copied concurrently. It also
+// returns the names of files in the target object root that should be
+// removed.
+func planObjectSync(ctx context.Context, src *Object, dstFS ocflfs.FS, dstDir string, versions VNums, create bool, goLimit int) ([][]*ocflfs.FileRef, []string, error) {
+	existing := map[string]int64{} // existing files in dst
+	for f, err := range ocflfs.WalkFiles(ctx, dstFS, dstDir) {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				break
+			}
+			return nil, nil, fmt.Errorf("%s: listing target files: %w", src.ID(), err)
+		}
+		size := int64(-1)
+		if f.Info != nil {
+			size = f.Info.Size()
+		}
+		existing[f.Path] = size
+	}
+	newVersion := map[string]int{}
+	for i, v := range versions {
+		newVersion[v.String()] = i
+	}
+	versionContent := make([][]*ocflfs.FileRef, len(versions))
+	versionInvs := make([][]*ocflfs.FileRef, len(versions))
+	var others, rootInv, rootSidecars, decls []*ocflfs.FileRef
+	var copied []*ocflfs.FileRef // files that may have been copied previously
+	srcRootFiles := map[string]bool{}
+	for f, err := range ocflfs.WalkFiles(ctx, src.FS(), src.Path()) {
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: listing source files: %w", src.ID(), err)
+		}
+		first, rest, isDir := strings.Cut(f.Path, "/")
+		if !isDir {
+			srcRootFiles[f.Path] = true
+			switch {
+			case f.Path == inventoryBase:
+				rootInv = append(rootInv, f)
+			case strings.HasPrefix(f.Path, inventoryBase+"."):
+				rootSidecars = append(rootSidecars, f)
+			case strings.HasPrefix(f.Path, "0="):
+				if _, exists := existing[f.Path]; create || !exists {
+					decls = append(decls, f)
+				}
+			default:
+				others = append(others, f)
+			}
+			continue
+		}
+		var vnum VNum
+		if err := ParseVNum(first, &vnum); err != nil {
+			// extensions, logs, etc.
+			others = append(others, f)
+			continue
+		}
+		i, isNew := newVersion[first]
+		if !isNew {
+			continue
+		}
+		if size, exists := existing[f.Path]; exists && f.Info != nil && size == f.Info.Size() {
+			// copied by a previous sync? (checked below)
+			copied = append(copied, f)
+			continue
+		}
+		if rest == inventoryBase || strings.HasPrefix(rest, inventoryBase+".") {
+			versionInvs[i] = append(versionInvs[i], f)
+			continue
+		}
+		versionContent[i] = append(versionContent[i], f)
+	}
+	checkCopied := func(f *ocflfs.FileRef) (bool, error) {
+		return syncedFile(ctx, src, dstFS, dstDir, f.Path)
+	}
+	for r := range pipeline.Results(slices.Values(copied), checkCopied, goLimit) {
+		if r.Err != nil {
+			return nil, nil, fmt.Errorf("%s: checking target file %s: %w", src.ID(), r.In.Path, r.Err)
+		}
+		if r.Out {
+			continue
+		}
+		first, rest, _ := strings.Cut(r.In.Path, "/")
+		i := newVersion[first]
+		if rest == inventoryBase || strings.HasPrefix(rest, inventoryBase+".") {
+			versionInvs[i] = append(versionInvs[i], r.In)
+			continue
+		}
+		versionContent[i] = append(versionContent[i], r.In)
+	}
+	var steps [][]*ocflfs.FileRef
+	for i := range versions {
+		steps = append(steps, versionContent[i], versionInvs[i])
+	}
+	steps = append(steps, others, rootInv, rootSidecars, decls)
+	steps = slices.DeleteFunc(steps, func(step []*ocflfs.FileRef) bool { return len(step) == 0 })
+	var removed []string
+	if !create {
+		for name := range existing {
+			if !strings.Contains(name, "/") && !srcRootFiles[name] {
+				removed = append(removed, name)
+			}
+		}
+	}
+	return steps, removed, nil
+}
+
+// syncedFile returns true if the file name (relative to the object root)
+// exists in the target with the same content as in src. Content files are
+// compared using the digests in src's manifest; digests stored by dstFS are
+// used if possible. Other files are read and compared byte-by-byte.
+func syncedFile(ctx context.Context, src *Object, dstFS ocflfs.FS, dstDir string, name string) (bool, error) {
+	dig := src.inventory.Manifest.DigestFor(name)
+	if dig == "" {
+		return sameFile(ctx, src.FS(), path.Join(src.Path(), name), dstFS, path.Join(dstDir, name))
+	}
+	ref := &digest.FileRef{
+		FileRef: ocflfs.FileRef{FS: dstFS, BaseDir: dstDir, Path: name},
+		Digests: digest.Set{src.DigestAlgorithm().ID(): dig},
+	}
+	compared, err := ref.ValidateStored(ctx)
+	if err == nil && !compared {
+		err = ref.Validate(ctx, src.Algorithms())
+	}
+	var digestErr *digest.DigestError
+	switch {
+	case errors.As(err, &digestErr):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return true, nil
+}
+
+// syncedObjectRoot returns true if the files in the target object root (given
+// by entries) are the same as the files in src's object root.
+func syncedObjectRoot(ctx context.Context, src *Object, dstFS ocflfs.FS, dstDir string, entries []fs.DirEntry) (bool, error) {
+	srcEntries, err := ocflfs.ReadDir(ctx, src.FS(), src.Path())
+	if err != nil {
+		return false, err
+	}
+	rootFiles := func(entries []fs.DirEntry) []string {
+		var names []string
+		for _, e := range entries {
+			if e.Type().IsRegular() {
+				names = append(names, e.Name())
+			}
+		}
+		slices.Sort(names)
+		return names
+	}
+	srcFiles := rootFiles(srcEntries)
+	if !slices.Equal(srcFiles, rootFiles(entries)) {
+		return false, nil
+	}
+	for _, name := range srcFiles {
+		if name == inventoryBase {
+			// compared by digest
+			continue
+		}
+		same, err := sameFile(ctx, src.FS(), path.Join(src.Path(), name), dstFS, path.Join(dstDir, name))
+		if err != nil || !same {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// sameFile returns true if the files have the same contents. It should only be
+// used for small files (e.g., sidecars and declarations).
+func sameFile(ctx context.Context, fsysA ocflfs.FS, nameA string, fsysB ocflfs.FS, nameB string) (bool, error) {
+	a, err := ocflfs.ReadAll(ctx, fsysA, nameA)
+	if err != nil {
+		return false, err
+	}
+	b, err := ocflfs.ReadAll(ctx, fsysB, nameB)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(a, b), nil
+}
+
+// deleteSyncObject deletes obj from the target root. The object declaration
+// is removed first, so the partially deleted object isn't treated as an
+// object.
+func deleteSyncObject(ctx context.Context, obj *Object, dryRun bool) (*ObjectSync, error) {
+	result := &ObjectSync{
+		ID:           obj.ID(),
+		Path:         obj.Path(),
+		Action:       SyncDelete,
+		TargetDigest: obj.InventoryDigest(),
+		Removed:      []string{"."},
+		DryRun:       dryRun,
+	}
+	if dryRun {
+		return result, nil
+	}
+	decl := Namaste{Type: NamasteTypeObject, Version: obj.Spec()}
+	if err := ocflfs.Remove(ctx, obj.FS(), path.Join(obj.Path(), decl.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return result, fmt.Errorf("%s: removing object declaration: %w", obj.ID(), err)
+	}
+	if err := ocflfs.RemoveAll(ctx, obj.FS(), obj.Path()); err != nil {
+		return result, fmt.Errorf("%s: removing object: %w", obj.ID(), err)
+	}
+	return result, nil
+}
+
+// rootRelPath returns the path of name relative to the root's directory.
+func rootRelPath(r *Root, name string) string {
+	if r.dir == "." || r.dir == "" {
+		return name
+	}
+	return strings.TrimPrefix(name, r.dir+"/")
+}
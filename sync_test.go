@@ -0,0 +1,232 @@
+package ocfl_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	"github.com/srerickson/ocfl-go/digest"
+	"github.com/srerickson/ocfl-go/extension"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/fs/local"
+)
+
+func TestSyncRoots(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	fsys, err := local.NewFS(tmpDir)
+	be.NilErr(t, err)
+	src, err := ocfl.NewRoot(ctx, fsys, "src", ocfl.InitRoot(ocfl.Spec1_1, "", extension.Ext0004()))
+	be.NilErr(t, err)
+	dst, err := ocfl.NewRoot(ctx, fsys, "dst", ocfl.InitRoot(ocfl.Spec1_1, "", extension.Ext0004()))
+	be.NilErr(t, err)
+	update := func(root *ocfl.Root, id string, files map[string]string) {
+		t.Helper()
+		obj, err := root.NewObject(ctx, id)
+		be.NilErr(t, err)
+		content := map[string][]byte{}
+		for name, body := range files {
+			content[name] = []byte(body)
+		}
+		stage, err := ocfl.StageBytes(content, digest.SHA512)
+		be.NilErr(t, err)
+		_, err = obj.Update(ctx, stage, "update", ocfl.User{Name: "Tester"})
+		be.NilErr(t, err)
+	}
+	sync := func(opts ...ocfl.SyncOption) map[string]*ocfl.ObjectSync {
+		t.Helper()
+		results := map[string]*ocfl.ObjectSync{}
+		for result, err := range ocfl.SyncRoots(ctx, src, dst, opts...) {
+			be.NilErr(t, err)
+			results[result.ID] = result
+		}
+		return results
+	}
+	validateDst := func(id string) {
+		t.Helper()
+		be.NilErr(t, dst.ValidateObject(ctx, id).Err())
+		srcObj, err := src.NewObject(ctx, id)
+		be.NilErr(t, err)
+		dstObj, err := dst.NewObject(ctx, id)
+		be.NilErr(t, err)
+		be.Equal(t, srcObj.InventoryDigest(), dstObj.InventoryDigest())
+	}
+	objDir1 := func(t *testing.T) string {
+		t.Helper()
+		objDir, err := src.ResolveID("object-1")
+		be.NilErr(t, err)
+		return objDir
+	}
+	update(src, "object-1", map[string]string{"a.txt": "content a", "b.txt": "content b"})
+	update(src, "object-2", map[string]string{"c.txt": "content c"})
+
+	t.Run("dry run", func(t *testing.T) {
+		results := sync(ocfl.SyncDryRun())
+		be.Equal(t, 2, len(results))
+		result := results["object-1"]
+		be.True(t, result.DryRun)
+		be.Equal(t, ocfl.SyncCreate, result.Action)
+		be.Equal(t, "v1", result.Versions.Head().String())
+		be.Equal(t, 7, len(result.Files))
+		be.Equal(t, int64(len("content a")+len("content b")), result.Size-sizeOfNonContent(t, tmpDir, src, "object-1"))
+		for obj, err := range dst.Objects(ctx) {
+			t.Fatal("unexpected object in target", obj, err)
+		}
+	})
+	t.Run("create", func(t *testing.T) {
+		results := sync(ocfl.SyncWithGoLimit(2))
+		be.Equal(t, 2, len(results))
+		files := results["object-1"].Files
+		// content is copied before inventories; the declaration is last.
+		invIdx := slices.Index(files, "v1/inventory.json")
+		be.True(t, slices.Index(files, "v1/content/a.txt") < invIdx)
+		be.True(t, invIdx < slices.Index(files, "inventory.json"))
+		be.True(t, slices.Index(files, "inventory.json") < slices.Index(files, "inventory.json.sha512"))
+		be.Equal(t, "0=ocfl_object_1.1", files[len(files)-1])
+		validateDst("object-1")
+		validateDst("object-2")
+		// nothing to sync
+		be.Equal(t, 0, len(sync()))
+	})
+	t.Run("update", func(t *testing.T) {
+		update(src, "object-1", map[string]string{"a.txt": "content a", "d.txt": "content d"})
+		results := sync()
+		be.Equal(t, 1, len(results))
+		result := results["object-1"]
+		be.Equal(t, ocfl.SyncUpdate, result.Action)
+		be.AllEqual(t, ocfl.VNums{ocfl.V(2)}, result.Versions)
+		be.Nonzero(t, result.TargetDigest)
+		for _, name := range result.Files {
+			be.False(t, path.Dir(name) == "v1" || path.Dir(name) == "v1/content")
+		}
+		be.Equal(t, 0, len(result.Removed))
+		validateDst("object-1")
+	})
+	t.Run("resume", func(t *testing.T) {
+		update(src, "object-1", map[string]string{"e.txt": "content e", "f.txt": "content f"})
+		objDir, err := src.ResolveID("object-1")
+		be.NilErr(t, err)
+		// simulate an interrupted sync: e.txt was copied.
+		srcFile := filepath.Join(tmpDir, "src", filepath.FromSlash(objDir), "v3", "content", "e.txt")
+		dstFile := filepath.Join(tmpDir, "dst", filepath.FromSlash(objDir), "v3", "content", "e.txt")
+		be.NilErr(t, os.MkdirAll(filepath.Dir(dstFile), 0755))
+		body, err := os.ReadFile(srcFile)
+		be.NilErr(t, err)
+		be.NilErr(t, os.WriteFile(dstFile, body, 0644))
+		result := sync()["object-1"]
+		be.False(t, slices.Contains(result.Files, "v3/content/e.txt"))
+		be.True(t, slices.Contains(result.Files, "v3/content/f.txt"))
+		validateDst("object-1")
+
+		// interrupted before the declaration was copied
+		update(src, "object-4", map[string]string{"h.txt": "content h"})
+		objDir, err = src.ResolveID("object-4")
+		be.NilErr(t, err)
+		dstObjDir := filepath.Join(tmpDir, "dst", filepath.FromSlash(objDir))
+		be.NilErr(t, os.CopyFS(dstObjDir, os.DirFS(filepath.Join(tmpDir, "src", filepath.FromSlash(objDir)))))
+		be.NilErr(t, os.Remove(filepath.Join(dstObjDir, "0=ocfl_object_1.1")))
+		result = sync()["object-4"]
+		be.Equal(t, ocfl.SyncCreate, result.Action)
+		be.AllEqual(t, []string{"inventory.json", "inventory.json.sha512", "0=ocfl_object_1.1"}, result.Files)
+		validateDst("object-4")
+
+		// a file with the expected size but different content is copied
+		update(src, "object-1", map[string]string{"g.txt": "content g"})
+		dstFile = filepath.Join(tmpDir, "dst", filepath.FromSlash(objDir1(t)), "v4", "content", "g.txt")
+		be.NilErr(t, os.MkdirAll(filepath.Dir(dstFile), 0755))
+		be.NilErr(t, os.WriteFile(dstFile, []byte("content X"), 0644))
+		result = sync()["object-1"]
+		be.True(t, slices.Contains(result.Files, "v4/content/g.txt"))
+		validateDst("object-1")
+
+		// interrupted after the root inventory was copied, before the sidecar
+		update(src, "object-1", map[string]string{"i.txt": "content i"})
+		srcObj, err := src.NewObject(ctx, "object-1")
+		be.NilErr(t, err)
+		dstDir := path.Join("dst", objDir1(t))
+		plan, err := ocfl.SyncObject(ctx, srcObj, fsys, dstDir, ocfl.SyncDryRun())
+		be.NilErr(t, err)
+		for _, name := range plan.Files {
+			if name == "inventory.json.sha512" {
+				break
+			}
+			_, err := ocflfs.Copy(ctx, fsys, path.Join(dstDir, name), fsys, path.Join(srcObj.Path(), name))
+			be.NilErr(t, err)
+		}
+		result, err = ocfl.SyncObject(ctx, srcObj, fsys, dstDir)
+		be.NilErr(t, err)
+		be.Equal(t, ocfl.SyncUpdate, result.Action)
+		be.Equal(t, result.SourceDigest, result.TargetDigest)
+		be.Equal(t, 0, len(result.Versions))
+		be.True(t, slices.Contains(result.Files, "inventory.json.sha512"))
+		validateDst("object-1")
+		result, err = ocfl.SyncObject(ctx, srcObj, fsys, dstDir)
+		be.NilErr(t, err)
+		be.Equal(t, ocfl.SyncUnchanged, result.Action)
+	})
+	t.Run("conflict", func(t *testing.T) {
+		update(dst, "object-2", map[string]string{"c.txt": "changed in target"})
+		update(src, "object-2", map[string]string{"c.txt": "changed in source"})
+		update(src, "object-2", map[string]string{"c.txt": "changed again"})
+		var errs []error
+		for _, err := range ocfl.SyncRoots(ctx, src, dst) {
+			errs = append(errs, err)
+		}
+		be.Equal(t, 1, len(errs))
+		be.True(t, errors.Is(errs[0], ocfl.ErrSyncConflict))
+	})
+	t.Run("delete", func(t *testing.T) {
+		update(dst, "object-3", map[string]string{"g.txt": "only in target"})
+		objDir, err := dst.ResolveID("object-3")
+		be.NilErr(t, err)
+		for result, err := range ocfl.SyncRoots(ctx, src, dst, ocfl.SyncWithDelete(), ocfl.SyncDryRun()) {
+			if result.ID != "object-3" {
+				continue
+			}
+			be.NilErr(t, err)
+			be.Equal(t, ocfl.SyncDelete, result.Action)
+		}
+		_, err = os.Stat(filepath.Join(tmpDir, "dst", filepath.FromSlash(objDir)))
+		be.NilErr(t, err)
+		var deleted bool
+		for result, err := range ocfl.SyncRoots(ctx, src, dst, ocfl.SyncWithDelete()) {
+			if result.ID == "object-3" {
+				be.NilErr(t, err)
+				deleted = true
+			}
+		}
+		be.True(t, deleted)
+		_, err = os.Stat(filepath.Join(tmpDir, "dst", filepath.FromSlash(objDir)))
+		be.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+}
+
+// sizeOfNonContent returns the total size of files in the object that aren't
+// in a version content directory.
+func sizeOfNonContent(t *testing.T, tmpDir string, root *ocfl.Root, id string) int64 {
+	t.Helper()
+	objDir, err := root.ResolveID(id)
+	be.NilErr(t, err)
+	objPath := filepath.Join(tmpDir, root.Path(), filepath.FromSlash(objDir))
+	var size int64
+	err = filepath.WalkDir(objPath, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Base(filepath.Dir(name)) == "content" {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	be.NilErr(t, err)
+	return size
+}
@@ -0,0 +1,75 @@
+// Package telemetry defines hooks for instrumenting ocfl-go operations with
+// tracing spans and metrics counters. The package has no dependencies beyond
+// the standard library: a [Provider] implementation for a specific telemetry
+// system (e.g., OpenTelemetry) can be found in a separate module
+// (github.com/srerickson/ocfl-go/otel).
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Counter names used by ocfl-go.
+const (
+	// BytesRead is the number of bytes read from storage backends for
+	// digesting or copying content.
+	BytesRead = "ocfl.bytes_read"
+	// BytesWritten is the number of bytes written to storage backends.
+	BytesWritten = "ocfl.bytes_written"
+	// DigestsComputed is the number of files digested.
+	DigestsComputed = "ocfl.digests_computed"
+	// Requests is the number of requests sent to a storage backend's API
+	// (e.g., S3 API calls).
+	Requests = "ocfl.requests"
+)
+
+// Provider is implemented by types that create tracing spans and update metrics
+// counters.
+type Provider interface {
+	// StartSpan starts a new span with the given name and attributes. It
+	// returns a context that includes the span and a function that ends the
+	// span. If the error passed to the end function is not nil, it should be
+	// recorded as the span's error status.
+	StartSpan(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, func(err error))
+	// AddCount increments the named counter by n.
+	AddCount(ctx context.Context, name string, n int64, attrs ...slog.Attr)
+}
+
+type ctxKey struct{}
+
+// NewContext returns a new context that carries the Provider p.
+func NewContext(ctx context.Context, p Provider) context.Context {
+	if p == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext returns the Provider carried by ctx. If ctx doesn't have a
+// Provider, a no-op Provider is returned.
+func FromContext(ctx context.Context) Provider {
+	if p, ok := ctx.Value(ctxKey{}).(Provider); ok {
+		return p
+	}
+	return noop{}
+}
+
+// StartSpan is shorthand for FromContext(ctx).StartSpan(ctx, name, attrs...).
+func StartSpan(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, func(err error)) {
+	return FromContext(ctx).StartSpan(ctx, name, attrs...)
+}
+
+// AddCount is shorthand for FromContext(ctx).AddCount(ctx, name, n, attrs...).
+func AddCount(ctx context.Context, name string, n int64, attrs ...slog.Attr) {
+	FromContext(ctx).AddCount(ctx, name, n, attrs...)
+}
+
+// noop is a Provider that does nothing.
+type noop struct{}
+
+func (noop) StartSpan(ctx context.Context, _ string, _ ...slog.Attr) (context.Context, func(error)) {
+	return ctx, func(error) {}
+}
+
+func (noop) AddCount(context.Context, string, int64, ...slog.Attr) {}
@@ -0,0 +1,49 @@
+package telemetry_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go/telemetry"
+)
+
+type recorder struct {
+	spans  []string
+	counts map[string]int64
+}
+
+func (r *recorder) StartSpan(ctx context.Context, name string, _ ...slog.Attr) (context.Context, func(error)) {
+	r.spans = append(r.spans, name)
+	return ctx, func(error) {}
+}
+
+func (r *recorder) AddCount(_ context.Context, name string, n int64, _ ...slog.Attr) {
+	if r.counts == nil {
+		r.counts = map[string]int64{}
+	}
+	r.counts[name] += n
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	t.Run("noop", func(t *testing.T) {
+		// no provider in context: helpers are no-ops
+		newCtx, end := telemetry.StartSpan(ctx, "test")
+		end(nil)
+		be.Equal(t, ctx, newCtx)
+		telemetry.AddCount(ctx, telemetry.BytesRead, 1)
+		be.Equal(t, ctx, telemetry.NewContext(ctx, nil))
+	})
+	t.Run("provider", func(t *testing.T) {
+		rec := &recorder{}
+		ctx := telemetry.NewContext(ctx, rec)
+		_, end := telemetry.StartSpan(ctx, "test")
+		end(nil)
+		telemetry.AddCount(ctx, telemetry.BytesRead, 2)
+		telemetry.AddCount(ctx, telemetry.BytesRead, 3)
+		be.DeepEqual(t, []string{"test"}, rec.spans)
+		be.Equal(t, 5, rec.counts[telemetry.BytesRead])
+	})
+}
@@ -0,0 +1,186 @@
+package ocfl
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// UpdatePlanSchemaVersion is the schema version used in the JSON encoding of
+// an [UpdatePlan]. It is incremented for changes to the encoding that aren't
+// backwards compatible.
+const UpdatePlanSchemaVersion = 1
+
+// updatePlanJSON is the JSON encoding of an UpdatePlan (schema version 1).
+type updatePlanJSON struct {
+	SchemaVersion       int               `json:"schema_version"`
+	ObjectID            string            `json:"object_id"`
+	Head                string            `json:"head"`
+	DigestAlgorithm     string            `json:"digest_algorithm"`
+	BaseInventoryDigest string            `json:"base_inventory_digest,omitempty"`
+	NextInventoryDigest string            `json:"next_inventory_digest"`
+	Steps               []planStepJSON    `json:"steps"`
+	NewInventory        string            `json:"new_inventory"`
+	OldInventory        string            `json:"old_inventory,omitempty"`
+	ExtensionConfigs    map[string]string `json:"extension_configs,omitempty"`
+}
+
+// planStepJSON is the JSON encoding of a PlanStep's state.
+type planStepJSON struct {
+	Name          string `json:"name"`
+	ContentDigest string `json:"content_digest,omitempty"`
+	Completed     bool   `json:"completed"`
+	Size          int64  `json:"size,omitempty"`
+	Attempts      int    `json:"attempts,omitempty"`
+	Err           string `json:"error,omitempty"`
+	RevertErr     string `json:"revert_error,omitempty"`
+}
+
+// MarshalJSON returns the JSON encoding of u. The encoding is an object with
+// the following fields:
+//
+//   - schema_version: the encoding's schema version ([UpdatePlanSchemaVersion])
+//   - object_id: the ID of the object being updated
+//   - head: the object version created by the update (e.g., "v2")
+//   - digest_algorithm: the digest algorithm for the new inventory
+//   - base_inventory_digest: the digest of the object's existing inventory
+//     (omitted if the update creates a new object)
+//   - next_inventory_digest: the digest of the new inventory
+//   - steps: an array of the plan's steps, in order, with the fields name,
+//     content_digest, completed, size, attempts, error, and revert_error.
+//   - new_inventory: the contents of the new inventory.json as a string
+//   - old_inventory: the contents of the existing inventory.json as a string
+//     (omitted if the update creates a new object)
+//   - extension_configs: the contents of extension config files added by the
+//     update, indexed by extension name
+//
+// Inventories are encoded as strings so that their exact contents (and
+// digests) are preserved.
+func (u UpdatePlan) MarshalJSON() ([]byte, error) {
+	if u.newInv == nil {
+		return nil, errors.New("update plan has no inventory")
+	}
+	enc := updatePlanJSON{
+		SchemaVersion:       UpdatePlanSchemaVersion,
+		ObjectID:            u.newInv.ID,
+		Head:                u.newInv.Head.String(),
+		DigestAlgorithm:     u.newInv.DigestAlgorithm,
+		BaseInventoryDigest: u.BaseInventoryDigest(),
+		NextInventoryDigest: u.newInv.digest,
+		Steps:               make([]planStepJSON, len(u.steps)),
+		NewInventory:        string(u.newInv.bytes),
+	}
+	if u.oldInv != nil {
+		enc.OldInventory = string(u.oldInv.bytes)
+	}
+	if len(u.extConfigs) > 0 {
+		enc.ExtensionConfigs = make(map[string]string, len(u.extConfigs))
+		for name, config := range u.extConfigs {
+			enc.ExtensionConfigs[name] = string(config)
+		}
+	}
+	for i, step := range u.steps {
+		enc.Steps[i] = planStepJSON{
+			Name:          step.state.Name,
+			ContentDigest: step.state.ContentDigest,
+			Completed:     step.state.Completed,
+			Size:          step.state.Size,
+			Attempts:      step.state.Attempts,
+			Err:           step.state.Err,
+			RevertErr:     step.state.RevertErr,
+		}
+	}
+	return json.MarshalIndent(enc, "", "  ")
+}
+
+// UnmarshalJSON decodes the JSON encoding of an UpdatePlan (see
+// [UpdatePlan.MarshalJSON]) and sets u to match. It returns an error if the
+// encoding's schema version isn't supported or if the encoded inventories
+// don't match their digests.
+func (u *UpdatePlan) UnmarshalJSON(b []byte) error {
+	var enc updatePlanJSON
+	if err := json.Unmarshal(b, &enc); err != nil {
+		return err
+	}
+	switch {
+	case enc.SchemaVersion < 1:
+		return errors.New("update plan is missing a schema version")
+	case enc.SchemaVersion > UpdatePlanSchemaVersion:
+		return fmt.Errorf("unsupported update plan schema version: %d", enc.SchemaVersion)
+	}
+	newInv, err := newStoredInventory([]byte(enc.NewInventory))
+	if err != nil {
+		return fmt.Errorf("decoding update plan's new inventory: %w", err)
+	}
+	if enc.NextInventoryDigest != "" && newInv.digest != enc.NextInventoryDigest {
+		return errors.New("update plan's new inventory doesn't match its digest")
+	}
+	var oldInv *StoredInventory
+	if enc.OldInventory != "" {
+		oldInv, err = newStoredInventory([]byte(enc.OldInventory))
+		if err != nil {
+			return fmt.Errorf("decoding update plan's existing inventory: %w", err)
+		}
+		if enc.BaseInventoryDigest != "" && oldInv.digest != enc.BaseInventoryDigest {
+			return errors.New("update plan's existing inventory doesn't match its digest")
+		}
+	}
+	var extConfigs map[string][]byte
+	if len(enc.ExtensionConfigs) > 0 {
+		extConfigs = make(map[string][]byte, len(enc.ExtensionConfigs))
+		for name, config := range enc.ExtensionConfigs {
+			extConfigs[name] = []byte(config)
+		}
+	}
+	steps := make(PlanSteps, len(enc.Steps))
+	for i, step := range enc.Steps {
+		steps[i].state = planStepState{
+			Name:          step.Name,
+			ContentDigest: step.ContentDigest,
+			Completed:     step.Completed,
+			Size:          step.Size,
+			Attempts:      step.Attempts,
+			Err:           step.Err,
+			RevertErr:     step.RevertErr,
+		}
+	}
+	u.newInv = newInv
+	u.oldInv = oldInv
+	u.extConfigs = extConfigs
+	u.steps = steps
+	return u.prepareSteps()
+}
+
+// unmarshalGob decodes the gob encoding of an UpdatePlan returned by
+// MarshalBinary.
+func (u *UpdatePlan) unmarshalGob(b []byte) error {
+	var decoded updatePlanState
+	var newInv, oldInv *StoredInventory
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&decoded)
+	if err != nil {
+		return err
+	}
+	newInv, err = newStoredInventory(decoded.NewInventoryBytes)
+	if err != nil {
+		return err
+	}
+	if len(decoded.OldInventoryBytes) > 0 {
+		oldInv, err = newStoredInventory(decoded.OldInventoryBytes)
+		if err != nil {
+			return err
+		}
+	}
+	u.newInv = newInv
+	u.oldInv = oldInv
+	u.extConfigs = decoded.ExtensionConfigs
+	u.steps = decoded.Steps
+	return u.prepareSteps()
+}
+
+// isJSONObject returns true if b looks like a JSON object.
+func isJSONObject(b []byte) bool {
+	b = bytes.TrimSpace(b)
+	return len(b) > 0 && b[0] == '{'
+}
@@ -0,0 +1,221 @@
+package ocfl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+// Operations performed by steps in an UpdatePlan (see [UpdateStepSummary]).
+const (
+	StepCopy   = "copy"   // copy content to the object
+	StepWrite  = "write"  // write an inventory, sidecar, declaration, or config file
+	StepRemove = "remove" // remove a file from the object
+)
+
+// UpdateSummary describes the changes an [UpdatePlan] makes to an object,
+// with estimates for the amount of data written. Totals only include steps
+// that haven't completed. It can be rendered as text with
+// [UpdateSummary.WriteText] or as JSON with [UpdateSummary.WriteJSON].
+type UpdateSummary struct {
+	ObjectID  string              `json:"object_id"`
+	Head      string              `json:"head"`       // version created by the update
+	NewObject bool                `json:"new_object"` // the update creates the object
+	Steps     []UpdateStepSummary `json:"steps"`
+
+	// CopyFiles and CopyBytes are the number and total size of content
+	// files copied to the object.
+	CopyFiles int   `json:"copy_files"`
+	CopyBytes int64 `json:"copy_bytes"`
+	// UnknownSizes is the number of content files with unknown sizes, which
+	// aren't included in CopyBytes.
+	UnknownSizes int `json:"unknown_sizes,omitempty"`
+	// WriteFiles and WriteBytes are the number and total size of other files
+	// written to the object (inventories, sidecars, etc.).
+	WriteFiles int   `json:"write_files"`
+	WriteBytes int64 `json:"write_bytes"`
+	// RemoveFiles is the number of files removed from the object.
+	RemoveFiles int `json:"remove_files"`
+	// DedupFiles and DedupBytes are the number and total size of files in
+	// the new version that aren't copied because their content is already
+	// in the object or is the same as another file's in the new version.
+	// DedupBytes only includes files with known sizes.
+	DedupFiles int   `json:"dedup_files"`
+	DedupBytes int64 `json:"dedup_bytes"`
+	// InventorySize is the size of the new inventory.json and
+	// InventorySizeDelta is the change in size from the existing inventory.
+	InventorySize      int64 `json:"inventory_size"`
+	InventorySizeDelta int64 `json:"inventory_size_delta"`
+}
+
+// UpdateStepSummary describes a step in an UpdatePlan.
+type UpdateStepSummary struct {
+	Name      string `json:"name"`
+	Op        string `json:"op,omitempty"`     // StepCopy, StepWrite, StepRemove, or empty
+	Path      string `json:"path,omitempty"`   // path relative to the object root
+	Digest    string `json:"digest,omitempty"` // digest of copied content
+	Size      int64  `json:"size"`             // -1 if unknown
+	Completed bool   `json:"completed"`
+	Err       string `json:"error,omitempty"`
+}
+
+// Summary returns an *UpdateSummary describing the changes u makes to the
+// object. It doesn't modify the object. Sizes of content files are read from
+// src, which may be nil; sizes for completed steps are the number of bytes
+// copied when the steps ran.
+func (u *UpdatePlan) Summary(ctx context.Context, src ContentSource) (*UpdateSummary, error) {
+	if u.newInv == nil {
+		return nil, errors.New("update plan has no inventory")
+	}
+	summary := &UpdateSummary{
+		ObjectID:      u.newInv.ID,
+		Head:          u.newInv.Head.String(),
+		NewObject:     u.oldInv == nil,
+		Steps:         make([]UpdateStepSummary, 0, len(u.steps)),
+		InventorySize: int64(len(u.newInv.bytes)),
+	}
+	summary.InventorySizeDelta = summary.InventorySize
+	if u.oldInv != nil {
+		summary.InventorySizeDelta -= int64(len(u.oldInv.bytes))
+	}
+	for i := range u.steps {
+		step := &u.steps[i]
+		op, name, _ := strings.Cut(step.state.Name, " ")
+		stepSum := UpdateStepSummary{
+			Name:      step.state.Name,
+			Size:      -1,
+			Completed: step.state.Completed,
+			Err:       step.state.Err,
+		}
+		switch op {
+		case StepCopy, StepWrite, StepRemove:
+			stepSum.Op = op
+			stepSum.Path = name
+		}
+		switch {
+		case step.state.Completed:
+			stepSum.Size = step.state.Size
+		case stepSum.Op == StepCopy:
+			size, err := contentSize(ctx, src, step.state.ContentDigest)
+			if err != nil {
+				return nil, err
+			}
+			stepSum.Size = size
+		case stepSum.Op == StepWrite:
+			stepSum.Size = step.writeSize
+		case stepSum.Op == StepRemove:
+			stepSum.Size = 0
+		}
+		if stepSum.Op == StepCopy {
+			stepSum.Digest = step.state.ContentDigest
+		}
+		summary.Steps = append(summary.Steps, stepSum)
+		if step.state.Completed {
+			continue
+		}
+		switch stepSum.Op {
+		case StepCopy:
+			summary.CopyFiles++
+			if stepSum.Size < 0 {
+				summary.UnknownSizes++
+				break
+			}
+			summary.CopyBytes += stepSum.Size
+		case StepWrite:
+			summary.WriteFiles++
+			summary.WriteBytes += max(stepSum.Size, 0)
+		case StepRemove:
+			summary.RemoveFiles++
+		}
+	}
+	// files in the new version with content from previous versions
+	// or with the same content as other files in the new version.
+	newContent := u.newInv.versionContent(u.newInv.Head)
+	copies := make(map[string]int, len(newContent))
+	for _, dig := range newContent {
+		copies[dig]++
+	}
+	if ver := u.newInv.Versions[u.newInv.Head]; ver != nil {
+		for dig, paths := range ver.State {
+			dedups := len(paths) - copies[dig]
+			if dedups < 1 {
+				continue
+			}
+			summary.DedupFiles += dedups
+			size, err := contentSize(ctx, src, dig)
+			if err != nil {
+				return nil, err
+			}
+			if size > 0 {
+				summary.DedupBytes += size * int64(dedups)
+			}
+		}
+	}
+	return summary, nil
+}
+
+// WriteText writes a human-readable description of the update summary to w.
+func (s *UpdateSummary) WriteText(w io.Writer) error {
+	var b strings.Builder
+	action := "update"
+	if s.NewObject {
+		action = "create"
+	}
+	fmt.Fprintf(&b, "%s object %q (%s)\n", action, s.ObjectID, s.Head)
+	for _, step := range s.Steps {
+		if step.Op == "" {
+			continue
+		}
+		status := "pending"
+		switch {
+		case step.Completed:
+			status = "done"
+		case step.Err != "":
+			status = "failed"
+		}
+		size := "?"
+		if step.Size >= 0 {
+			size = fmt.Sprintf("%d", step.Size)
+		}
+		fmt.Fprintf(&b, "  [%s] %-6s %s (%s bytes)\n", status, step.Op, step.Path, size)
+	}
+	fmt.Fprintf(&b, "copy: %d files, %d bytes", s.CopyFiles, s.CopyBytes)
+	if s.UnknownSizes > 0 {
+		fmt.Fprintf(&b, " (%d files with unknown size)", s.UnknownSizes)
+	}
+	fmt.Fprintf(&b, "\nwrite: %d files, %d bytes\n", s.WriteFiles, s.WriteBytes)
+	fmt.Fprintf(&b, "remove: %d files\n", s.RemoveFiles)
+	fmt.Fprintf(&b, "deduplicated: %d files, %d bytes\n", s.DedupFiles, s.DedupBytes)
+	fmt.Fprintf(&b, "inventory: %d bytes (%+d)\n", s.InventorySize, s.InventorySizeDelta)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes the JSON encoding of the update summary to w.
+func (s *UpdateSummary) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// contentSize returns the size of the content with the given digest in src or
+// -1 if the size can't be determined.
+func contentSize(ctx context.Context, src ContentSource, dig string) (int64, error) {
+	if src == nil {
+		return -1, nil
+	}
+	srcFS, srcPath := src.GetContent(dig)
+	if srcFS == nil {
+		return -1, nil
+	}
+	info, err := ocflfs.StatFile(ctx, srcFS, srcPath)
+	if err != nil {
+		return 0, fmt.Errorf("getting size of content %q: %w", srcPath, err)
+	}
+	return info.Size(), nil
+}
@@ -10,17 +10,25 @@ import (
 	"io/fs"
 	"iter"
 	"log/slog"
+	"maps"
 	"path"
 	"runtime"
 	"slices"
 
+	"github.com/srerickson/ocfl-go/digest"
 	ocflfs "github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/telemetry"
 	"golang.org/x/sync/errgroup"
 )
 
 // ErrRevertUpdate: can't revert an update because the update ran to completion
 var ErrRevertUpdate = errors.New("the update has completed and cannot be reverted")
 
+// maxVerifyAttempts is the number of times a step that copies content is run
+// if the content can't be verified (see [UpdateWithVerify]) and the plan
+// doesn't have a retry policy.
+const maxVerifyAttempts = 3
+
 // UpdatePlan is a sequence of steps ([PlanStep]) for updating an OCFL object.
 // It allows updates to be interrupted, resumed, retried or reverted. To update
 // an object, each [PlanStep] in the UpdatePlan must run to completion. The
@@ -32,23 +40,31 @@ type UpdatePlan struct {
 	steps  PlanSteps
 	newInv *StoredInventory
 	oldInv *StoredInventory
+	// extension configs to add to the object, indexed by extension name
+	extConfigs map[string][]byte
 
 	// options
-	goLimit int
-	logger  *slog.Logger
+	goLimit   int
+	logger    *slog.Logger
+	telemetry telemetry.Provider
+	verify    bool
+	retry     *ocflfs.RetryPolicy
 }
 
 // newUpdatePlan builds an *UpdatePlan that be used to update the object at
 // objDir in objFS, transitioning from oldInv to newInv, with new content
-// available in src.
-func newUpdatePlan(newInv *Inventory, oldInv *StoredInventory) (*UpdatePlan, error) {
+// available in src. If extConfigs is not empty, the plan includes steps for
+// writing the extension configuration files to the object's extensions
+// directory.
+func newUpdatePlan(newInv *Inventory, oldInv *StoredInventory, extConfigs map[string][]byte) (*UpdatePlan, error) {
 	newInvBytes, invDigest, err := newInv.marshal()
 	if err != nil {
 		return nil, fmt.Errorf("building new inventory: %w", err)
 	}
 	u := &UpdatePlan{
-		newInv: &StoredInventory{Inventory: *newInv, digest: invDigest, bytes: newInvBytes},
-		oldInv: oldInv,
+		newInv:     &StoredInventory{Inventory: *newInv, digest: invDigest, bytes: newInvBytes},
+		oldInv:     oldInv,
+		extConfigs: extConfigs,
 	}
 	if err := u.prepareSteps(); err != nil {
 		return nil, err
@@ -60,9 +76,18 @@ func newUpdatePlan(newInv *Inventory, oldInv *StoredInventory) (*UpdatePlan, err
 // *StoredInventory if the updated succeeded. If any step in the UpdatePlan
 // results in an error, execution stops and the error is returned. Some steps in
 // the plan may run concurrently. Use SetGoLimit to set number of goroutines
-// used to run concurrent steps.
+// used to run concurrent steps. If the plan was created with
+// [UpdateWithVerify], content copied to the object is verified before the
+// object's inventory is written. If the plan was created with
+// [UpdateWithRetry], steps that fail with retryable errors are run again
+// before execution stops.
 func (u *UpdatePlan) Apply(ctx context.Context, objFS ocflfs.FS, objDir string, src ContentSource) (*StoredInventory, error) {
-	err := runSteps(ctx, u.IncompleteSteps(), objFS, objDir, src, u.goLimit, u.logger, false)
+	ctx, end := telemetry.StartSpan(telemetry.NewContext(ctx, u.telemetry), "ocfl.UpdatePlan.Apply",
+		slog.String("object_id", u.ObjectID()),
+		slog.String("head", u.NextHead().String()),
+	)
+	err := runSteps(ctx, u.IncompleteSteps(), objFS, objDir, src, u.goLimit, u.logger, u.verify, u.retry, false)
+	end(err)
 	if err != nil {
 		return nil, err
 	}
@@ -144,9 +169,13 @@ func (u *UpdatePlan) IncompleteSteps() iter.Seq[*PlanStep] {
 	}
 }
 
-// MarshalBinary returns a binary representation of u
+// MarshalBinary returns a binary (gob) representation of u. Use
+// [UpdatePlan.MarshalJSON] for the JSON encoding.
 func (u UpdatePlan) MarshalBinary() ([]byte, error) {
-	toEncode := updatePlanState{Steps: u.steps}
+	toEncode := updatePlanState{
+		Steps:            u.steps,
+		ExtensionConfigs: u.extConfigs,
+	}
 	if u.newInv != nil {
 		toEncode.NewInventoryBytes = u.newInv.bytes
 	}
@@ -185,7 +214,30 @@ func (u *UpdatePlan) Revert(ctx context.Context, objFS ocflfs.FS, objDir string,
 	if u.Completed() {
 		return ErrRevertUpdate
 	}
-	return runSteps(ctx, u.CompletedSteps(), objFS, objDir, src, u.goLimit, u.logger, true)
+	ctx, end := telemetry.StartSpan(telemetry.NewContext(ctx, u.telemetry), "ocfl.UpdatePlan.Revert",
+		slog.String("object_id", u.ObjectID()),
+		slog.String("head", u.NextHead().String()),
+	)
+	err := runSteps(ctx, u.CompletedSteps(), objFS, objDir, src, u.goLimit, u.logger, false, nil, true)
+	end(err)
+	return err
+}
+
+// revertAll reverts all of u's steps, including incomplete steps that may have
+// run before the process applying the update was interrupted. (Reverting a
+// step that didn't run has no effect.) Steps are marked as completed before
+// they are reverted.
+func (u *UpdatePlan) revertAll(ctx context.Context, objFS ocflfs.FS, objDir string, src ContentSource) error {
+	for i := range u.steps {
+		u.steps[i].state.Completed = true
+	}
+	ctx, end := telemetry.StartSpan(telemetry.NewContext(ctx, u.telemetry), "ocfl.UpdatePlan.Revert",
+		slog.String("object_id", u.ObjectID()),
+		slog.String("head", u.NextHead().String()),
+	)
+	err := runSteps(ctx, u.CompletedSteps(), objFS, objDir, src, u.goLimit, u.logger, false, nil, true)
+	end(err)
+	return err
 }
 
 // setGoLimit sets the number of goroutines used for processing Steps with Async
@@ -195,6 +247,17 @@ func (u *UpdatePlan) setGoLimit(gos int) { u.goLimit = gos }
 // setLogger sets a logger that will be used when running steps in u.
 func (u *UpdatePlan) setLogger(logger *slog.Logger) { u.logger = logger }
 
+// setTelemetry sets the telemetry provider used to instrument steps in u.
+func (u *UpdatePlan) setTelemetry(p telemetry.Provider) { u.telemetry = p }
+
+// setVerify sets whether content copied to the object is verified after each
+// step.
+func (u *UpdatePlan) setVerify(verify bool) { u.verify = verify }
+
+// setRetry sets the retry policy used for steps that fail with retryable
+// errors.
+func (u *UpdatePlan) setRetry(policy *ocflfs.RetryPolicy) { u.retry = policy }
+
 // Steps iterates over all steps in the update plan
 func (u UpdatePlan) Steps() iter.Seq[*PlanStep] {
 	return func(yield func(*PlanStep) bool) {
@@ -208,39 +271,22 @@ func (u UpdatePlan) Steps() iter.Seq[*PlanStep] {
 }
 
 // UnmarshalBinary decodes b as a binary representation of an UpdatePlan
-// and sets u to match.
+// and sets u to match. It decodes the gob encoding returned by
+// [UpdatePlan.MarshalBinary] and the JSON encoding returned by
+// [UpdatePlan.MarshalJSON].
 func (u *UpdatePlan) UnmarshalBinary(b []byte) error {
-	var decoded updatePlanState
-	var newInv, oldInv *StoredInventory
-	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&decoded)
-	if err != nil {
-		return err
-	}
-	newInv, err = newStoredInventory(decoded.NewInventoryBytes)
-	if err != nil {
-		return err
-	}
-	if len(decoded.OldInventoryBytes) > 0 {
-		oldInv, err = newStoredInventory(decoded.OldInventoryBytes)
-		if err != nil {
-			return err
-		}
-	}
-	u.newInv = newInv
-	u.oldInv = oldInv
-	u.steps = decoded.Steps
-	if err := u.prepareSteps(); err != nil {
-		return err
+	if isJSONObject(b) {
+		return u.UnmarshalJSON(b)
 	}
-	return nil
+	return u.unmarshalGob(b)
 }
 
 // prepareSteps is used to regenerate u's Step functions. This is only needed
-// if u was created by unmarshaling from a binary representation.
+// if u was created by unmarshaling from a binary or JSON representation.
 func (u *UpdatePlan) prepareSteps() error {
 	// the unmarshaled newSteps have Done and Err state, but their run functions
 	// nil: rebuild the newSteps to run and import the previous run state.
-	newSteps, err := newPlanSteps(u.newInv, u.oldInv)
+	newSteps, err := newPlanSteps(u.newInv, u.oldInv, u.extConfigs)
 	if err != nil {
 		return err
 	}
@@ -254,11 +300,14 @@ func (u *UpdatePlan) prepareSteps() error {
 	for i := range newSteps {
 		u.steps[i].run = newSteps[i].run
 		u.steps[i].revert = newSteps[i].revert
+		u.steps[i].verify = newSteps[i].verify
+		u.steps[i].writeSize = newSteps[i].writeSize
+		u.steps[i].revertWritten = newSteps[i].revertWritten
 	}
 	return nil
 }
 
-func newPlanSteps(newInv, oldInv *StoredInventory) (PlanSteps, error) {
+func newPlanSteps(newInv, oldInv *StoredInventory, extConfigs map[string][]byte) (PlanSteps, error) {
 	newFiles := newInv.versionContent(newInv.Head)
 	newHead := newInv.Head.String()
 	newSpec := newInv.Type.Spec
@@ -322,7 +371,11 @@ func newPlanSteps(newInv, oldInv *StoredInventory) (PlanSteps, error) {
 	)
 	plan = append(plan,
 		// steps to copy contents into the version directory
-		updateVersionContentsSteps(newFiles)...,
+		updateVersionContentsSteps(newFiles, contentDigests(&newInv.Inventory, newFiles))...,
+	)
+	plan = append(plan,
+		// steps to add extension configs
+		updateExtensionConfigSteps(extConfigs)...,
 	)
 	plan = append(plan,
 		// steps to update inventories and sidecars in version directory and root
@@ -369,6 +422,13 @@ type PlanStep struct {
 	run func(ctx context.Context, objFS ocflfs.FS, objDir string, src ContentSource) (int64, error)
 	// revert undoes the run step.
 	revert func(ctx context.Context, objFS ocflfs.FS, objDir string, src ContentSource) error
+	// if revertWritten is true, revert is only called if the run step wrote
+	// content (i.e., its size is not zero).
+	revertWritten bool
+	// verify checks the content written by the run step (optional).
+	verify func(ctx context.Context, objFS ocflfs.FS, objDir string) error
+	// size of the file written by the run step, if known in advance.
+	writeSize int64
 }
 
 func (step PlanStep) MarshalBinary() ([]byte, error) {
@@ -379,6 +439,10 @@ func (step PlanStep) MarshalBinary() ([]byte, error) {
 	return buff.Bytes(), nil
 }
 
+// Attempts returns the number of times the step has run, including
+// retries and runs from previous calls to [UpdatePlan.Apply].
+func (step PlanStep) Attempts() int { return step.state.Attempts }
+
 // ErrMsg returns any error message from the step's last Run.
 func (step PlanStep) ErrMsg() string { return step.state.Err }
 
@@ -403,7 +467,10 @@ func (step *PlanStep) Run(ctx context.Context, objFS ocflfs.FS, objDir string, s
 	if step.state.Completed {
 		return nil
 	}
+	ctx, end := telemetry.StartSpan(ctx, "ocfl.PlanStep.Run", slog.String("step", step.state.Name))
+	step.state.Attempts++
 	size, err := step.run(ctx, objFS, objDir, src)
+	end(err)
 	if err != nil {
 		msg := err.Error()
 		if msg == "" {
@@ -429,7 +496,13 @@ func (step *PlanStep) Revert(ctx context.Context, objFS ocflfs.FS, objDir string
 	if !step.state.Completed {
 		return nil
 	}
-	err := step.revert(ctx, objFS, objDir, src)
+	var err error
+	if !step.revertWritten || step.state.Size > 0 {
+		var end func(error)
+		ctx, end = telemetry.StartSpan(ctx, "ocfl.PlanStep.Revert", slog.String("step", step.state.Name))
+		err = step.revert(ctx, objFS, objDir, src)
+		end(err)
+	}
 	if err != nil {
 		msg := err.Error()
 		if msg == "" {
@@ -466,7 +539,8 @@ func updateDeclarationSteps(newSpec, oldSpec Spec) []PlanStep {
 	}
 	newDecl := Namaste{Type: NamasteTypeObject, Version: newSpec}
 	steps = append(steps, PlanStep{
-		state: planStepState{Name: "write " + newDecl.Name()},
+		state:     planStepState{Name: "write " + newDecl.Name()},
+		writeSize: int64(len(newDecl.Body())),
 		run: func(ctx context.Context, objFS ocflfs.FS, objDir string, _ ContentSource) (int64, error) {
 			return 0, WriteDeclaration(ctx, objFS, objDir, newDecl)
 		},
@@ -499,8 +573,10 @@ func updateDeclarationSteps(newSpec, oldSpec Spec) []PlanStep {
 	return steps
 }
 
-// steps for copying files into the object's version directory
-func updateVersionContentsSteps(newContent PathMap) []PlanStep {
+// steps for copying files into the object's version directory. Digests for
+// the content are passed to the destination FS through the context (see
+// [ocflfs.NewContentDigestsContext]).
+func updateVersionContentsSteps(newContent PathMap, digests map[string]digest.Set) []PlanStep {
 	var steps []PlanStep
 	for dstName, dig := range newContent.SortedPaths() {
 		steps = append(steps, PlanStep{
@@ -515,6 +591,13 @@ func updateVersionContentsSteps(newContent PathMap) []PlanStep {
 				if srcFS == nil {
 					return 0, fmt.Errorf("content source doesn't provide %q", dig)
 				}
+				if srcFS == objFS && srcPath == dstPath {
+					// content was written in place (see Object.NewIngest)
+					return 0, nil
+				}
+				if sums := digests[dig]; len(sums) > 0 {
+					ctx = ocflfs.NewContentDigestsContext(ctx, sums)
+				}
 				return ocflfs.Copy(ctx, objFS, dstPath, srcFS, srcPath)
 			},
 			revert: func(ctx context.Context, objFS ocflfs.FS, objDir string, _ ContentSource) error {
@@ -525,6 +608,74 @@ func updateVersionContentsSteps(newContent PathMap) []PlanStep {
 				}
 				return err
 			},
+			verify: func(ctx context.Context, objFS ocflfs.FS, objDir string) error {
+				ref := &digest.FileRef{
+					FileRef: ocflfs.FileRef{FS: objFS, BaseDir: objDir, Path: dstName},
+					Digests: digests[dig],
+				}
+				// use digests stored by the backend, if available
+				compared, err := ref.ValidateStored(ctx)
+				if err == nil && !compared {
+					err = ref.Validate(ctx, digest.DefaultRegistry())
+				}
+				if err != nil {
+					return fmt.Errorf("verifying %s: %w", dstName, err)
+				}
+				return nil
+			},
+		})
+	}
+	return steps
+}
+
+// contentDigests returns digests, including fixity, for content paths in
+// content, indexed by the content's primary digest.
+func contentDigests(inv *Inventory, content PathMap) map[string]digest.Set {
+	sets := make(map[string]digest.Set, len(content))
+	for _, dig := range content {
+		sets[dig] = digest.Set{inv.DigestAlgorithm: dig}
+	}
+	for alg, fixMap := range inv.Fixity {
+		for p, fixDigest := range fixMap.Paths() {
+			if dig, ok := content[p]; ok {
+				sets[dig][alg] = fixDigest
+			}
+		}
+	}
+	return sets
+}
+
+// steps for writing extension config files in the object's extensions
+// directory. Existing config files are not modified.
+func updateExtensionConfigSteps(configs map[string][]byte) []PlanStep {
+	var steps []PlanStep
+	for _, name := range slices.Sorted(maps.Keys(configs)) {
+		config := configs[name]
+		confName := path.Join(extensionsDir, name, extensionConfigFile)
+		steps = append(steps, PlanStep{
+			state:     planStepState{Name: "write " + confName},
+			writeSize: int64(len(config)),
+			run: func(ctx context.Context, objFS ocflfs.FS, objDir string, _ ContentSource) (int64, error) {
+				objConfName := path.Join(objDir, confName)
+				_, err := ocflfs.StatFile(ctx, objFS, objConfName)
+				if err == nil {
+					return 0, nil // already exists
+				}
+				if !errors.Is(err, fs.ErrNotExist) {
+					return 0, err
+				}
+				return ocflfs.Write(ctx, objFS, objConfName, bytes.NewReader(config))
+			},
+			// the config may have existed before the update: it is only
+			// removed if the step created it.
+			revertWritten: true,
+			revert: func(ctx context.Context, objFS ocflfs.FS, objDir string, _ ContentSource) error {
+				err := ocflfs.Remove(ctx, objFS, path.Join(objDir, confName))
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			},
 		})
 	}
 	return steps
@@ -543,7 +694,8 @@ func updateInventorySteps(
 	verDirInvSidecar := verDirInv + "." + newAlg
 	// write version directory inventory.json
 	steps = append(steps, PlanStep{
-		state: planStepState{Name: "write " + verDirInv},
+		state:     planStepState{Name: "write " + verDirInv},
+		writeSize: int64(len(newInvBytes)),
 		run: func(ctx context.Context, objFS ocflfs.FS, objDir string, _ ContentSource) (int64, error) {
 			objVerDirInv := path.Join(objDir, verDirInv)
 			return ocflfs.Write(ctx, objFS, objVerDirInv, bytes.NewReader(newInvBytes))
@@ -559,7 +711,8 @@ func updateInventorySteps(
 	})
 	// write version directory inventory sidecar
 	steps = append(steps, PlanStep{
-		state: planStepState{Name: "write " + verDirInvSidecar},
+		state:     planStepState{Name: "write " + verDirInvSidecar},
+		writeSize: int64(len(inventorySidecar(newInvDigest))),
 		run: func(ctx context.Context, objFS ocflfs.FS, objDir string, _ ContentSource) (int64, error) {
 			objVerDir := path.Join(objDir, verDir)
 			return 0, writeInventorySidecar(ctx, objFS, objVerDir, newInvDigest, newAlg)
@@ -575,7 +728,8 @@ func updateInventorySteps(
 	})
 	// write root inventory.json
 	steps = append(steps, PlanStep{
-		state: planStepState{Name: "write " + inventoryBase},
+		state:     planStepState{Name: "write " + inventoryBase},
+		writeSize: int64(len(newInvBytes)),
 		run: func(ctx context.Context, objFS ocflfs.FS, objDir string, _ ContentSource) (int64, error) {
 			objInv := path.Join(objDir, inventoryBase)
 			return ocflfs.Write(ctx, objFS, objInv, bytes.NewReader(newInvBytes))
@@ -595,7 +749,8 @@ func updateInventorySteps(
 	})
 	// write root inventory sidecar
 	steps = append(steps, PlanStep{
-		state: planStepState{Name: "write " + invSidecar},
+		state:     planStepState{Name: "write " + invSidecar},
+		writeSize: int64(len(inventorySidecar(newInvDigest))),
 		run: func(ctx context.Context, objFS ocflfs.FS, objDir string, _ ContentSource) (int64, error) {
 			err := writeInventorySidecar(ctx, objFS, objDir, newInvDigest, newAlg)
 			if err != nil {
@@ -649,6 +804,8 @@ func runSteps(
 	src ContentSource,
 	gos int,
 	logger *slog.Logger,
+	verify bool,
+	retry *ocflfs.RetryPolicy,
 	backward bool,
 ) error {
 	if gos < 1 {
@@ -680,7 +837,7 @@ func runSteps(
 					}
 				default:
 					logger.Info(step.state.Name)
-					err = step.Run(groupCtx, objFS, objDir, src)
+					err = runStep(groupCtx, step, objFS, objDir, src, verify, retry, logger)
 					if err != nil {
 						logger.Error(err.Error())
 					}
@@ -707,7 +864,7 @@ func runSteps(
 			}
 		default:
 			logger.Info(step.state.Name)
-			if err := step.Run(ctx, objFS, objDir, src); err != nil {
+			if err := runStep(ctx, step, objFS, objDir, src, verify, retry, logger); err != nil {
 				logger.Error(err.Error())
 				return err
 			}
@@ -719,10 +876,56 @@ func runSteps(
 	return nil
 }
 
+// runStep runs step. If verify is true, content written by the step is
+// verified after the step runs; if the content's digests don't match, the
+// step is marked as incomplete and run again. Steps that fail with errors
+// that are retryable according to the retry policy are also run again.
+func runStep(
+	ctx context.Context,
+	step *PlanStep,
+	objFS ocflfs.FS,
+	objDir string,
+	src ContentSource,
+	verify bool,
+	retry *ocflfs.RetryPolicy,
+	logger *slog.Logger,
+) error {
+	for attempt := 1; ; attempt++ {
+		err := step.Run(ctx, objFS, objDir, src)
+		if err == nil && verify && step.verify != nil {
+			if err = step.verify(ctx, objFS, objDir); err != nil {
+				step.state.Completed = false
+				step.state.Err = err.Error()
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		var digestErr *digest.DigestError
+		isMismatch := verify && errors.As(err, &digestErr)
+		switch {
+		case retry == nil:
+			if !isMismatch || attempt >= maxVerifyAttempts {
+				return err
+			}
+		case attempt >= retry.MaxAttempts:
+			return err
+		case !isMismatch && !retry.IsRetryable(err):
+			return err
+		}
+		logger.Warn("retrying step", "step", step.state.Name, "attempt", attempt, "err", err)
+		if err := retry.Wait(ctx, attempt); err != nil {
+			return err
+		}
+	}
+}
+
+// updatePlanState is the gob encoding of an UpdatePlan used by MarshalBinary.
 type updatePlanState struct {
 	NewInventoryBytes []byte
 	OldInventoryBytes []byte
 	Steps             []PlanStep
+	ExtensionConfigs  map[string][]byte
 }
 
 type planStepState struct {
@@ -735,4 +938,5 @@ type planStepState struct {
 	RevertErr string
 	Completed bool
 	Size      int64
+	Attempts  int
 }
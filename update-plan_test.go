@@ -1,16 +1,26 @@
 package ocfl_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"io/fs"
+	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/carlmjohnson/be"
 	"github.com/srerickson/ocfl-go"
 	"github.com/srerickson/ocfl-go/digest"
+	"github.com/srerickson/ocfl-go/extension"
 	ocflfs "github.com/srerickson/ocfl-go/fs"
 	"github.com/srerickson/ocfl-go/fs/local"
 )
@@ -39,6 +49,178 @@ func TestUpdatePlan_Marshal(t *testing.T) {
 	be.Equal(t, update.BaseInventoryDigest(), sameUpdate.BaseInventoryDigest())
 }
 
+func TestUpdatePlan_MarshalJSON(t *testing.T) {
+	ctx := context.Background()
+	fixture := filepath.Join(`testdata`, `object-fixtures`, `1.0`, `good-objects`)
+	fsys, err := local.NewFS(fixture)
+	be.NilErr(t, err)
+	obj, err := ocfl.NewObject(ctx, fsys, "spec-ex-full")
+	be.NilErr(t, err)
+	stage, err := ocfl.StageBytes(map[string][]byte{"a.txt": []byte("content a")}, digest.SHA512)
+	be.NilErr(t, err)
+	update, err := obj.NewUpdatePlan(stage, "new version", ocfl.User{Name: "Me"})
+	be.NilErr(t, err)
+	planJSON, err := json.Marshal(update)
+	be.NilErr(t, err)
+
+	// the encoding is readable without the library
+	var decoded struct {
+		SchemaVersion       int    `json:"schema_version"`
+		ObjectID            string `json:"object_id"`
+		Head                string `json:"head"`
+		BaseInventoryDigest string `json:"base_inventory_digest"`
+		NextInventoryDigest string `json:"next_inventory_digest"`
+		NewInventory        string `json:"new_inventory"`
+		Steps               []struct {
+			Name      string `json:"name"`
+			Completed bool   `json:"completed"`
+		} `json:"steps"`
+	}
+	be.NilErr(t, json.Unmarshal(planJSON, &decoded))
+	be.Equal(t, ocfl.UpdatePlanSchemaVersion, decoded.SchemaVersion)
+	be.Equal(t, "ark:/12345/bcd987", decoded.ObjectID)
+	be.Equal(t, "v4", decoded.Head)
+	be.Equal(t, obj.InventoryDigest(), decoded.BaseInventoryDigest)
+	be.Equal(t, update.NextInventoryDigest(), decoded.NextInventoryDigest)
+	be.Equal(t, len(slices.Collect(update.Steps())), len(decoded.Steps))
+	var stepNames []string
+	for _, step := range decoded.Steps {
+		stepNames = append(stepNames, step.Name)
+		be.False(t, step.Completed)
+	}
+	be.True(t, slices.Contains(stepNames, "copy v4/content/a.txt"))
+	newInv, vldn := ocfl.ValidateInventoryBytes([]byte(decoded.NewInventory))
+	be.NilErr(t, vldn.Err())
+	be.Equal(t, update.NextInventoryDigest(), newInv.Digest())
+
+	t.Run("round trip", func(t *testing.T) {
+		var sameUpdate ocfl.UpdatePlan
+		be.NilErr(t, json.Unmarshal(planJSON, &sameUpdate))
+		be.True(t, update.Eq(&sameUpdate))
+		be.Equal(t, update.BaseInventoryDigest(), sameUpdate.BaseInventoryDigest())
+		// MarshalBinary uses the gob encoding
+		planBytes, err := update.MarshalBinary()
+		be.NilErr(t, err)
+		be.False(t, json.Valid(planBytes))
+		sameUpdate = ocfl.UpdatePlan{}
+		be.NilErr(t, sameUpdate.UnmarshalBinary(planBytes))
+		be.True(t, update.Eq(&sameUpdate))
+	})
+	t.Run("unsupported schema version", func(t *testing.T) {
+		for _, version := range []string{`0`, `99`} {
+			invalid := regexp.MustCompile(`"schema_version":\s*1`).ReplaceAll(planJSON, []byte(`"schema_version":`+version))
+			var plan ocfl.UpdatePlan
+			be.Nonzero(t, json.Unmarshal(invalid, &plan))
+		}
+	})
+	t.Run("wrong inventory digest", func(t *testing.T) {
+		invalid := bytes.Replace(planJSON, []byte(update.NextInventoryDigest()), []byte(strings.Repeat("0", 128)), 1)
+		var plan ocfl.UpdatePlan
+		be.Nonzero(t, json.Unmarshal(invalid, &plan))
+	})
+	t.Run("legacy gob encoding", func(t *testing.T) {
+		legacy, err := os.ReadFile(filepath.Join(`testdata`, `update-plans`, `legacy-gob-plan.bin`))
+		be.NilErr(t, err)
+		var plan ocfl.UpdatePlan
+		be.NilErr(t, plan.UnmarshalBinary(legacy))
+		be.Equal(t, "object-1", plan.ObjectID())
+		be.Equal(t, "v1", plan.NextHead().String())
+		steps := slices.Collect(plan.Steps())
+		be.Equal(t, 8, len(steps))
+		be.True(t, steps[0].Completed())
+		be.False(t, steps[1].Completed())
+		// re-encoded as JSON
+		planJSON, err := json.Marshal(&plan)
+		be.NilErr(t, err)
+		var samePlan ocfl.UpdatePlan
+		be.NilErr(t, json.Unmarshal(planJSON, &samePlan))
+		be.True(t, plan.Eq(&samePlan))
+	})
+}
+
+func TestUpdatePlan_Summary(t *testing.T) {
+	ctx := context.Background()
+	content := map[string][]byte{
+		"a.txt":     []byte("content a"),
+		"b.txt":     []byte("content b!"),
+		"dup/a.txt": []byte("content a"),
+	}
+	fsys, err := local.NewFS(t.TempDir())
+	be.NilErr(t, err)
+	obj, err := ocfl.NewObject(ctx, fsys, "obj", ocfl.ObjectWithID("object-1"))
+	be.NilErr(t, err)
+	stage, err := ocfl.StageBytes(content, digest.SHA512)
+	be.NilErr(t, err)
+	plan, err := obj.NewUpdatePlan(stage, "v1", ocfl.User{Name: "Tester"})
+	be.NilErr(t, err)
+	summary, err := plan.Summary(ctx, stage)
+	be.NilErr(t, err)
+	be.Equal(t, "object-1", summary.ObjectID)
+	be.Equal(t, "v1", summary.Head)
+	be.True(t, summary.NewObject)
+	be.Equal(t, 3, summary.CopyFiles)
+	be.Equal(t, 28, summary.CopyBytes)
+	be.Equal(t, 0, summary.UnknownSizes)
+	be.Equal(t, 0, summary.DedupFiles)
+	be.Equal(t, 0, summary.RemoveFiles)
+	// declaration, inventories, and sidecars
+	be.Equal(t, 5, summary.WriteFiles)
+	be.True(t, summary.WriteBytes > 2*summary.InventorySize)
+	be.True(t, summary.InventorySize > 0)
+	be.Equal(t, summary.InventorySize, summary.InventorySizeDelta)
+	var text strings.Builder
+	be.NilErr(t, summary.WriteText(&text))
+	be.In(t, `create object "object-1" (v1)`, text.String())
+	be.In(t, "copy: 3 files, 28 bytes", text.String())
+	be.In(t, "[pending] copy   v1/content/dup/a.txt (9 bytes)", text.String())
+	var summaryJSON bytes.Buffer
+	be.NilErr(t, summary.WriteJSON(&summaryJSON))
+	var decoded ocfl.UpdateSummary
+	be.NilErr(t, json.Unmarshal(summaryJSON.Bytes(), &decoded))
+	be.DeepEqual(t, *summary, decoded)
+
+	t.Run("without content source", func(t *testing.T) {
+		summary, err := plan.Summary(ctx, nil)
+		be.NilErr(t, err)
+		be.Equal(t, 3, summary.CopyFiles)
+		be.Equal(t, 0, summary.CopyBytes)
+		be.Equal(t, 3, summary.UnknownSizes)
+	})
+	t.Run("completed steps", func(t *testing.T) {
+		be.NilErr(t, obj.ApplyUpdatePlan(ctx, plan, stage))
+		summary, err := plan.Summary(ctx, stage)
+		be.NilErr(t, err)
+		be.Equal(t, 0, summary.CopyFiles)
+		be.Equal(t, 0, summary.WriteFiles)
+		for _, step := range summary.Steps {
+			be.True(t, step.Completed)
+			if step.Op == ocfl.StepCopy {
+				be.True(t, step.Size > 0)
+			}
+		}
+	})
+	t.Run("existing object", func(t *testing.T) {
+		// new version only has content that is already in the object
+		content["c.txt"] = []byte("content a")
+		delete(content, "b.txt")
+		stage, err := ocfl.StageBytes(content, digest.SHA512)
+		be.NilErr(t, err)
+		plan, err := obj.NewUpdatePlan(stage, "v2", ocfl.User{Name: "Tester"})
+		be.NilErr(t, err)
+		summary, err := plan.Summary(ctx, stage)
+		be.NilErr(t, err)
+		be.False(t, summary.NewObject)
+		be.Equal(t, "v2", summary.Head)
+		be.Equal(t, 0, summary.CopyFiles)
+		be.Equal(t, 3, summary.DedupFiles)
+		be.Equal(t, 27, summary.DedupBytes)
+		be.Equal(t, 4, summary.WriteFiles)
+		oldInv, err := ocflfs.ReadAll(ctx, fsys, "obj/inventory.json")
+		be.NilErr(t, err)
+		be.Equal(t, summary.InventorySize-int64(len(oldInv)), summary.InventorySizeDelta)
+	})
+}
+
 func TestUpdatePlan_RecoverUpdatePlan(t *testing.T) {
 	ctx := context.Background()
 	fixture := filepath.Join(`testdata`, `object-fixtures`, `1.0`, `good-objects`, `spec-ex-full`)
@@ -204,3 +386,220 @@ func TestUpdatePlan_RecoverUpdatePlan(t *testing.T) {
 	})
 
 }
+
+func TestUpdateWithVerify(t *testing.T) {
+	ctx := context.Background()
+	content := map[string][]byte{
+		"a.txt": []byte("content a"),
+		"b.txt": []byte("content b"),
+	}
+	newFS := func(t *testing.T, corrupt int) *flakyFS {
+		t.Helper()
+		localFS, err := local.NewFS(t.TempDir())
+		be.NilErr(t, err)
+		return &flakyFS{FS: localFS, name: "a.txt", corrupt: corrupt}
+	}
+	update := func(t *testing.T, fsys ocflfs.FS, opts ...ocfl.ObjectUpdateOption) (*ocfl.Object, error) {
+		t.Helper()
+		obj, err := ocfl.NewObject(ctx, fsys, "obj", ocfl.ObjectWithID("object-1"))
+		be.NilErr(t, err)
+		stage, err := ocfl.StageBytes(content, digest.SHA512)
+		be.NilErr(t, err)
+		_, err = obj.Update(ctx, stage, "v1", ocfl.User{Name: "Tester"}, opts...)
+		return obj, err
+	}
+	t.Run("without option", func(t *testing.T) {
+		fsys := newFS(t, 1)
+		_, err := update(t, fsys)
+		be.NilErr(t, err)
+		be.Nonzero(t, ocfl.ValidateObject(ctx, fsys, "obj").Err())
+	})
+	t.Run("retry after corrupt copy", func(t *testing.T) {
+		fsys := newFS(t, 2)
+		_, err := update(t, fsys, ocfl.UpdateWithVerify())
+		be.NilErr(t, err)
+		be.Equal(t, 0, fsys.corrupt)
+		be.NilErr(t, ocfl.ValidateObject(ctx, fsys, "obj").Err())
+	})
+	t.Run("too many corrupt copies", func(t *testing.T) {
+		fsys := newFS(t, 10)
+		obj, err := update(t, fsys, ocfl.UpdateWithVerify())
+		var digestErr *digest.DigestError
+		be.True(t, errors.As(err, &digestErr))
+		be.False(t, obj.Exists())
+		_, err = fs.Stat(ocflfs.DirFS(fsys.Root()), "obj/inventory.json")
+		be.True(t, errors.Is(err, fs.ErrNotExist))
+	})
+}
+
+func TestUpdateWithRetry(t *testing.T) {
+	ctx := context.Background()
+	content := map[string][]byte{
+		"a.txt": []byte("content a"),
+		"b.txt": []byte("content b"),
+	}
+	policy := &ocflfs.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	newPlan := func(t *testing.T, fail int, opts ...ocfl.ObjectUpdateOption) (*ocfl.Object, *ocfl.UpdatePlan, *ocfl.Stage) {
+		t.Helper()
+		localFS, err := local.NewFS(t.TempDir())
+		be.NilErr(t, err)
+		fsys := &flakyFS{FS: localFS, name: "a.txt", fail: fail}
+		obj, err := ocfl.NewObject(ctx, fsys, "obj", ocfl.ObjectWithID("object-1"))
+		be.NilErr(t, err)
+		stage, err := ocfl.StageBytes(content, digest.SHA512)
+		be.NilErr(t, err)
+		plan, err := obj.NewUpdatePlan(stage, "v1", ocfl.User{Name: "Tester"}, opts...)
+		be.NilErr(t, err)
+		return obj, plan, stage
+	}
+	copyStep := func(plan *ocfl.UpdatePlan) *ocfl.PlanStep {
+		for step := range plan.Steps() {
+			if step.Name() == "copy v1/content/a.txt" {
+				return step
+			}
+		}
+		t.Fatal("missing step")
+		return nil
+	}
+	t.Run("without retry", func(t *testing.T) {
+		obj, plan, stage := newPlan(t, 1)
+		err := obj.ApplyUpdatePlan(ctx, plan, stage)
+		be.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+		step := copyStep(plan)
+		be.Equal(t, 1, step.Attempts())
+		be.Nonzero(t, step.ErrMsg())
+		// attempts are persisted with the plan
+		planBytes, err := plan.MarshalBinary()
+		be.NilErr(t, err)
+		var samePlan ocfl.UpdatePlan
+		be.NilErr(t, samePlan.UnmarshalBinary(planBytes))
+		be.Equal(t, 1, copyStep(&samePlan).Attempts())
+	})
+	t.Run("with retry", func(t *testing.T) {
+		obj, plan, stage := newPlan(t, 2, ocfl.UpdateWithRetry(policy))
+		be.NilErr(t, obj.ApplyUpdatePlan(ctx, plan, stage))
+		step := copyStep(plan)
+		be.Equal(t, 3, step.Attempts())
+		be.Equal(t, "", step.ErrMsg())
+		be.NilErr(t, ocfl.ValidateObject(ctx, obj.FS(), obj.Path()).Err())
+	})
+	t.Run("too many failures", func(t *testing.T) {
+		obj, plan, stage := newPlan(t, 3, ocfl.UpdateWithRetry(policy))
+		err := obj.ApplyUpdatePlan(ctx, plan, stage)
+		be.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+		be.Equal(t, 3, copyStep(plan).Attempts())
+	})
+}
+
+// flakyFS is a local.FS that fails writes to files with the given base name,
+// up to fail times, and then corrupts content written to the files, up to
+// corrupt times.
+type flakyFS struct {
+	*local.FS
+	name    string
+	mu      sync.Mutex
+	fail    int
+	corrupt int
+}
+
+func (fsys *flakyFS) Write(ctx context.Context, name string, r io.Reader) (int64, error) {
+	fsys.mu.Lock()
+	var fail, corrupt bool
+	if path.Base(name) == fsys.name {
+		switch {
+		case fsys.fail > 0:
+			fsys.fail--
+			fail = true
+		case fsys.corrupt > 0:
+			fsys.corrupt--
+			corrupt = true
+		}
+	}
+	fsys.mu.Unlock()
+	if fail {
+		return 0, &fs.PathError{Op: "write", Path: name, Err: io.ErrUnexpectedEOF}
+	}
+	if corrupt {
+		r = io.MultiReader(r, strings.NewReader("corrupted"))
+	}
+	return fsys.FS.Write(ctx, name, r)
+}
+
+func TestUpdatePlan_RevertExtensionConfig(t *testing.T) {
+	ctx := context.Background()
+	ext0009 := extension.Ext0009().(extension.AlgorithmRegistry)
+	reg := digest.DefaultRegistry().Append(ext0009.Algorithms().All()...)
+	size, err := reg.Get(digest.SIZE.ID())
+	be.NilErr(t, err)
+	confName := path.Join("extensions", ext0009.Name(), "config.json")
+	// newObject creates an object with one version without fixity.
+	newObject := func(t *testing.T) *local.FS {
+		t.Helper()
+		fsys, err := local.NewFS(t.TempDir())
+		be.NilErr(t, err)
+		obj, err := ocfl.NewObject(ctx, fsys, ".", ocfl.ObjectWithID("object"))
+		be.NilErr(t, err)
+		stage, err := ocfl.StageBytes(map[string][]byte{"a.txt": []byte("a")}, digest.SHA512)
+		be.NilErr(t, err)
+		_, err = obj.Update(ctx, stage, "v1", ocfl.User{Name: "Me"})
+		be.NilErr(t, err)
+		return fsys
+	}
+	// partialUpdate runs the steps of an update that adds the size fixity
+	// algorithm (from an extension) to the object, stopping after the
+	// extension config step.
+	partialUpdate := func(t *testing.T, fsys *local.FS) *ocfl.UpdatePlan {
+		t.Helper()
+		obj, err := ocfl.NewObject(ctx, fsys, ".", ocfl.ObjectWithAlgorithms(reg))
+		be.NilErr(t, err)
+		stage, err := ocfl.StageBytes(map[string][]byte{"b.txt": []byte("b")}, digest.SHA512, size)
+		be.NilErr(t, err)
+		update, err := obj.NewUpdatePlan(stage, "v2", ocfl.User{Name: "Me"})
+		be.NilErr(t, err)
+		var ranConfigStep bool
+		for step := range update.Steps() {
+			be.NilErr(t, step.Run(ctx, fsys, ".", stage))
+			if step.Name() == "write "+confName {
+				ranConfigStep = true
+				break
+			}
+		}
+		be.True(t, ranConfigStep)
+		return update
+	}
+	t.Run("config created by update", func(t *testing.T) {
+		fsys := newObject(t)
+		update := partialUpdate(t, fsys)
+		_, err := ocflfs.StatFile(ctx, fsys, confName)
+		be.NilErr(t, err)
+		be.NilErr(t, update.Revert(ctx, fsys, ".", nil))
+		_, err = ocflfs.StatFile(ctx, fsys, confName)
+		be.True(t, errors.Is(err, fs.ErrNotExist))
+		be.NilErr(t, ocfl.ValidateObject(ctx, fsys, ".").Err())
+	})
+	t.Run("existing config", func(t *testing.T) {
+		fsys := newObject(t)
+		config := []byte(`{"extensionName":"existing"}`)
+		_, err := ocflfs.Write(ctx, fsys, confName, bytes.NewReader(config))
+		be.NilErr(t, err)
+		update := partialUpdate(t, fsys)
+		be.NilErr(t, update.Revert(ctx, fsys, ".", nil))
+		got, err := ocflfs.ReadAll(ctx, fsys, confName)
+		be.NilErr(t, err)
+		be.Equal(t, string(config), string(got))
+	})
+	t.Run("existing config with decoded plan", func(t *testing.T) {
+		fsys := newObject(t)
+		config := []byte(`{"extensionName":"existing"}`)
+		_, err := ocflfs.Write(ctx, fsys, confName, bytes.NewReader(config))
+		be.NilErr(t, err)
+		planBytes, err := json.Marshal(partialUpdate(t, fsys))
+		be.NilErr(t, err)
+		var decoded ocfl.UpdatePlan
+		be.NilErr(t, json.Unmarshal(planBytes, &decoded))
+		be.NilErr(t, decoded.Revert(ctx, fsys, ".", nil))
+		got, err := ocflfs.ReadAll(ctx, fsys, confName)
+		be.NilErr(t, err)
+		be.Equal(t, string(config), string(got))
+	})
+}
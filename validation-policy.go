@@ -0,0 +1,141 @@
+package ocfl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/srerickson/ocfl-go/validation/code"
+	"gopkg.in/yaml.v3"
+)
+
+// Names of validation policy presets (see [ValidationPolicyPreset]).
+const (
+	// PolicySpecDefault uses the severity of errors and warnings defined in
+	// the OCFL specification.
+	PolicySpecDefault = "spec-default"
+	// PolicyStrict treats all warnings as fatal errors.
+	PolicyStrict = "strict"
+	// PolicyLenient ignores all warnings.
+	PolicyLenient = "lenient"
+)
+
+// ValidationSeverity determines how an object validation error or warning
+// with a specific validation code is handled.
+type ValidationSeverity string
+
+const (
+	// ValidationIgnore indicates that errors with the code are ignored.
+	ValidationIgnore ValidationSeverity = "ignore"
+	// ValidationWarn indicates that errors with the code are warnings.
+	ValidationWarn ValidationSeverity = "warn"
+	// ValidationFatal indicates that errors with the code are fatal errors.
+	ValidationFatal ValidationSeverity = "fatal"
+)
+
+// severity for warnings in each preset
+var policyPresets = map[string]ValidationSeverity{
+	PolicySpecDefault: "",
+	PolicyStrict:      ValidationFatal,
+	PolicyLenient:     ValidationIgnore,
+}
+
+func (s ValidationSeverity) valid() bool {
+	switch s {
+	case ValidationIgnore, ValidationWarn, ValidationFatal:
+		return true
+	}
+	return false
+}
+
+// ValidationPolicy changes the severity of object validation errors and
+// warnings based on their validation code (see the validation/code package).
+// Errors without a validation code are not affected. Use
+// [ValidationWithPolicy] to validate objects with a policy.
+type ValidationPolicy struct {
+	// Preset is the name of a preset policy (see [ValidationPolicyPreset])
+	// used for codes that aren't in Codes. If Preset and Warnings are both
+	// set, Warnings is used.
+	Preset string `json:"preset,omitempty" yaml:"preset,omitempty"`
+	// Warnings is the severity for warning codes (W001, W002, etc.) that
+	// aren't in Codes. If it is empty, warnings are reported as warnings.
+	Warnings ValidationSeverity `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+	// Codes maps validation codes (e.g., "W004") to a severity.
+	Codes map[string]ValidationSeverity `json:"codes,omitempty" yaml:"codes,omitempty"`
+}
+
+// ValidationPolicyPreset returns a new *ValidationPolicy for a named preset:
+// [PolicySpecDefault], [PolicyStrict], or [PolicyLenient].
+func ValidationPolicyPreset(name string) (*ValidationPolicy, error) {
+	if _, ok := policyPresets[name]; !ok {
+		return nil, fmt.Errorf("unknown validation policy preset: %q", name)
+	}
+	return &ValidationPolicy{Preset: name}, nil
+}
+
+// ReadValidationPolicy reads a validation policy in JSON or YAML format from
+// r. An error is returned if the policy includes an unknown preset, validation
+// code, or severity.
+//
+// An example policy in YAML:
+//
+//	preset: spec-default
+//	codes:
+//	  W002: fatal
+//	  W004: ignore
+//	  W005: ignore
+func ReadValidationPolicy(r io.Reader) (*ValidationPolicy, error) {
+	// YAML is a superset of JSON, so the YAML decoder handles both
+	var policy ValidationPolicy
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("decoding validation policy: %w", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Validate returns an error if the policy includes an unknown preset,
+// validation code, or severity.
+func (p *ValidationPolicy) Validate() error {
+	if _, ok := policyPresets[p.Preset]; p.Preset != "" && !ok {
+		return fmt.Errorf("unknown validation policy preset: %q", p.Preset)
+	}
+	if p.Warnings != "" && !p.Warnings.valid() {
+		return fmt.Errorf("invalid severity for warnings: %q", p.Warnings)
+	}
+	for name, sev := range p.Codes {
+		if code.Lookup(name, string(Spec1_0)) == nil && code.Lookup(name, string(Spec1_1)) == nil {
+			return fmt.Errorf("unknown validation code: %q", name)
+		}
+		if !sev.valid() {
+			return fmt.Errorf("invalid severity for %s: %q", name, sev)
+		}
+	}
+	return nil
+}
+
+// Severity returns the severity for err, which is reported with the given
+// default severity. If err doesn't have a validation code, or if the policy
+// doesn't change the severity for its code, def is returned.
+func (p *ValidationPolicy) Severity(err error, def ValidationSeverity) ValidationSeverity {
+	var vErr *ValidationError
+	if p == nil || !errors.As(err, &vErr) {
+		return def
+	}
+	if sev := p.Codes[vErr.Code]; sev.valid() {
+		return sev
+	}
+	warnings := p.Warnings
+	if warnings == "" {
+		warnings = policyPresets[p.Preset]
+	}
+	if warnings != "" && strings.HasPrefix(vErr.Code, "W") {
+		return warnings
+	}
+	return def
+}
@@ -0,0 +1,131 @@
+package ocfl_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/carlmjohnson/be"
+	"github.com/srerickson/ocfl-go"
+	ocflfs "github.com/srerickson/ocfl-go/fs"
+)
+
+func TestValidationWithPolicy(t *testing.T) {
+	ctx := context.Background()
+	fixture := filepath.Join(`testdata`, `object-fixtures`, `1.1`, `warn-objects`, `W001_W004_W005_zero_padded_versions`)
+	fsys := ocflfs.DirFS(fixture)
+	codes := func(errs []error) []string {
+		var result []string
+		for _, err := range errs {
+			var vErr *ocfl.ValidationError
+			if errors.As(err, &vErr) && !slices.Contains(result, vErr.Code) {
+				result = append(result, vErr.Code)
+			}
+		}
+		slices.Sort(result)
+		return result
+	}
+	validate := func(policy *ocfl.ValidationPolicy) *ocfl.ObjectValidation {
+		return ocfl.ValidateObject(ctx, fsys, ".", ocfl.ValidationWithPolicy(policy))
+	}
+	t.Run("spec-default", func(t *testing.T) {
+		policy, err := ocfl.ValidationPolicyPreset(ocfl.PolicySpecDefault)
+		be.NilErr(t, err)
+		v := validate(policy)
+		be.NilErr(t, v.Err())
+		be.AllEqual(t, []string{"W001", "W004", "W005"}, codes(v.WarnErrors()))
+	})
+	t.Run("strict", func(t *testing.T) {
+		policy, err := ocfl.ValidationPolicyPreset(ocfl.PolicyStrict)
+		be.NilErr(t, err)
+		v := validate(policy)
+		be.AllEqual(t, []string{"W001", "W004", "W005"}, codes(v.Errors()))
+		be.Equal(t, 0, len(v.WarnErrors()))
+	})
+	t.Run("lenient", func(t *testing.T) {
+		policy, err := ocfl.ValidationPolicyPreset(ocfl.PolicyLenient)
+		be.NilErr(t, err)
+		v := validate(policy)
+		be.NilErr(t, v.Err())
+		be.NilErr(t, v.WarnErr())
+	})
+	t.Run("codes", func(t *testing.T) {
+		policy := &ocfl.ValidationPolicy{
+			Codes: map[string]ocfl.ValidationSeverity{
+				"W001": ocfl.ValidationFatal,
+				"W004": ocfl.ValidationIgnore,
+			},
+		}
+		v := validate(policy)
+		be.AllEqual(t, []string{"W001"}, codes(v.Errors()))
+		be.AllEqual(t, []string{"W005"}, codes(v.WarnErrors()))
+		// codes override the preset
+		policy.Preset = ocfl.PolicyLenient
+		policy.Codes["W004"] = ocfl.ValidationWarn
+		v = validate(policy)
+		be.AllEqual(t, []string{"W001"}, codes(v.Errors()))
+		be.AllEqual(t, []string{"W004"}, codes(v.WarnErrors()))
+	})
+	t.Run("fatal errors", func(t *testing.T) {
+		fixture := filepath.Join(`testdata`, `object-fixtures`, `1.1`, `bad-objects`, `E058_no_sidecar`)
+		policy := &ocfl.ValidationPolicy{
+			Codes: map[string]ocfl.ValidationSeverity{"E058": ocfl.ValidationWarn},
+		}
+		v := ocfl.ValidateObject(ctx, ocflfs.DirFS(fixture), ".", ocfl.ValidationWithPolicy(policy))
+		be.False(t, slices.Contains(codes(v.Errors()), "E058"))
+		be.True(t, slices.Contains(codes(v.WarnErrors()), "E058"))
+	})
+	t.Run("ignored errors don't stop validation", func(t *testing.T) {
+		fixture := filepath.Join(`testdata`, `object-fixtures`, `1.1`, `good-objects`, `minimal_one_version_one_file`)
+		objDir := t.TempDir()
+		be.NilErr(t, os.CopyFS(objDir, os.DirFS(fixture)))
+		// no declaration and corrupt content
+		be.NilErr(t, os.Remove(filepath.Join(objDir, "0=ocfl_object_1.1")))
+		be.NilErr(t, os.WriteFile(filepath.Join(objDir, "v1", "content", "a_file.txt"), []byte("changed"), 0644))
+		policy := &ocfl.ValidationPolicy{
+			Codes: map[string]ocfl.ValidationSeverity{"E003": ocfl.ValidationIgnore},
+		}
+		v := ocfl.ValidateObject(ctx, ocflfs.DirFS(objDir), ".", ocfl.ValidationWithPolicy(policy))
+		be.Nonzero(t, v.Err())
+		be.False(t, slices.Contains(codes(v.Errors()), "E003"))
+		be.True(t, slices.Contains(codes(v.Errors()), "E092"))
+	})
+}
+
+func TestReadValidationPolicy(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		policy, err := ocfl.ReadValidationPolicy(strings.NewReader("preset: lenient\ncodes:\n  W002: fatal\n  W004: ignore\n"))
+		be.NilErr(t, err)
+		be.Equal(t, ocfl.PolicyLenient, policy.Preset)
+		be.Equal(t, ocfl.ValidationFatal, policy.Codes["W002"])
+		be.Equal(t, ocfl.ValidationIgnore, policy.Codes["W004"])
+	})
+	t.Run("json", func(t *testing.T) {
+		policy, err := ocfl.ReadValidationPolicy(strings.NewReader(`{"warnings": "fatal", "codes": {"W005": "ignore"}}`))
+		be.NilErr(t, err)
+		be.Equal(t, ocfl.ValidationFatal, policy.Warnings)
+		be.Equal(t, ocfl.ValidationIgnore, policy.Codes["W005"])
+	})
+	t.Run("empty", func(t *testing.T) {
+		policy, err := ocfl.ReadValidationPolicy(strings.NewReader(""))
+		be.NilErr(t, err)
+		be.Equal(t, 0, len(policy.Codes))
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, config := range []string{
+			`{"preset": "unknown"}`,
+			`{"warnings": "maybe"}`,
+			`{"codes": {"W999": "fatal"}}`,
+			`{"codes": {"W004": "sometimes"}}`,
+			`{"unknown": "field"}`,
+			`codes: [W004]`,
+		} {
+			_, err := ocfl.ReadValidationPolicy(strings.NewReader(config))
+			be.Nonzero(t, err)
+		}
+	})
+}
@@ -6,10 +6,12 @@ import (
 	"iter"
 	"log/slog"
 	"runtime"
+	"strings"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/srerickson/ocfl-go/digest"
 	"github.com/srerickson/ocfl-go/fs"
+	"github.com/srerickson/ocfl-go/telemetry"
 	"github.com/srerickson/ocfl-go/validation"
 )
 
@@ -81,21 +83,29 @@ type ObjectValidation struct {
 	objOptions  []ObjectOption
 	logger      *slog.Logger
 	skipDigests bool
+	trustStored bool
 	concurrency int
 	files       map[string]*validationFileInfo
 	algRegistry digest.AlgorithmRegistry
+	policy      *ValidationPolicy
+	versions    map[int]bool // selected versions (all if empty)
+	headOnly    bool
 }
 
 // newObjectValidation constructs a new *Validation with the given
 // options
 func newObjectValidation(fsys fs.FS, dir string, opts ...ObjectValidationOption) *ObjectValidation {
-	v := &ObjectValidation{
-		algRegistry: digest.DefaultRegistry(),
-	}
+	v := &ObjectValidation{}
 	for _, opt := range opts {
 		opt(v)
 	}
 	v.obj, _ = newObjectAndConfig(fsys, dir, v.objOptions...)
+	if v.algRegistry.Len() == 0 {
+		v.algRegistry = digest.DefaultRegistry()
+		if v.obj != nil {
+			v.algRegistry = v.obj.Algorithms()
+		}
+	}
 	return v
 }
 
@@ -122,8 +132,53 @@ func (v *ObjectValidation) PrefixAdd(prefix string, v2 *Validation) {
 	}
 }
 
-// AddFatal adds fatal errors to the validation
+// AddFatal adds fatal errors to the validation. If the validation has a
+// policy, errors may be added as warnings or ignored.
 func (v *ObjectValidation) AddFatal(errs ...error) {
+	v.addWithPolicy(ValidationFatal, errs)
+}
+
+// AddWarn adds warning errors to the object validation and logs the errors
+// using the object validations logger, if set. If the validation has a
+// policy, errors may be added as fatal errors or ignored.
+func (v *ObjectValidation) AddWarn(errs ...error) {
+	v.addWithPolicy(ValidationWarn, errs)
+}
+
+// addWithPolicy adds errs with the given default severity, which may be
+// changed by the validation's policy.
+func (v *ObjectValidation) addWithPolicy(def ValidationSeverity, errs []error) {
+	var fatal, warn []error
+	for _, err := range errs {
+		switch v.policy.Severity(err, def) {
+		case ValidationFatal:
+			fatal = append(fatal, err)
+		case ValidationWarn:
+			warn = append(warn, err)
+		}
+	}
+	if len(fatal) > 0 {
+		v.addFatal(fatal...)
+	}
+	if len(warn) > 0 {
+		v.addWarn(warn...)
+	}
+}
+
+// policyFatal returns an error joining errs that are fatal according to the
+// validation's policy, or nil if there are none. It is used to decide whether
+// validation can continue after fatal errors.
+func (v *ObjectValidation) policyFatal(errs ...error) error {
+	var fatal []error
+	for _, err := range errs {
+		if v.policy.Severity(err, ValidationFatal) == ValidationFatal {
+			fatal = append(fatal, err)
+		}
+	}
+	return errors.Join(fatal...)
+}
+
+func (v *ObjectValidation) addFatal(errs ...error) {
 	v.Validation.AddFatal(errs...)
 	if v.logger == nil {
 		return
@@ -139,9 +194,7 @@ func (v *ObjectValidation) AddFatal(errs ...error) {
 	}
 }
 
-// AddWarn adds warning errors to the object validation and logs the errors
-// using the object validations logger, if set.
-func (v *ObjectValidation) AddWarn(errs ...error) {
+func (v *ObjectValidation) addWarn(errs ...error) {
 	v.Validation.AddWarn(errs...)
 	if v.logger == nil {
 		return
@@ -168,6 +221,12 @@ func (v *ObjectValidation) SkipDigests() bool {
 	return v.skipDigests
 }
 
+// TrustStoredDigests returns true if the validation uses digests stored by
+// the storage backend instead of reading content.
+func (v *ObjectValidation) TrustStoredDigests() bool {
+	return v.trustStored
+}
+
 // DigestConcurrency returns the configured number of go routines used to read
 // and digest contents during validation. The default value is runtime.NumCPU().
 func (v *ObjectValidation) DigestConcurrency() int {
@@ -179,6 +238,7 @@ func (v *ObjectValidation) DigestConcurrency() int {
 
 // ValidationAlgorithms returns the registry of digest algorithms
 // the object validation is configured to use. The default value is
+// the object's registry (see [ObjectWithAlgorithms]) or
 // digest.DefaultRegistry
 func (v *ObjectValidation) ValidationAlgorithms() digest.AlgorithmRegistry {
 	return v.algRegistry
@@ -275,11 +335,12 @@ func (v *ObjectValidation) path() string { return v.obj.path }
 
 // missingContent returns an iterator the yields the names of files that appear
 // in an inventory added to the validation but were not marked as existing.
+// Files in version directories that aren't being validated are skipped.
 func (v *ObjectValidation) missingContent() iter.Seq[string] {
 	return func(yield func(string) bool) {
 		for name, entry := range v.files {
 			seenInManifest := len(entry.manifestDigests) > 0
-			if seenInManifest && !entry.fileExists {
+			if seenInManifest && !entry.fileExists && v.contentSelected(name) {
 				if !yield(name) {
 					return
 				}
@@ -311,6 +372,19 @@ func ValidationSkipDigest() ObjectValidationOption {
 	}
 }
 
+// ValidationTrustStoredDigests configures validation to use digests stored by
+// the storage backend, if available, instead of reading and digesting object
+// content (see StoredDigestsFS in the fs package). Content is only read if the backend
+// doesn't store digests for any of the content's algorithms. This allows fast
+// audits that trust the backend's integrity checks (e.g., S3 checksums).
+// Backends may only store digests for some files: the S3 backend only stores
+// sha256 checksums for files written in a single part, for example.
+func ValidationTrustStoredDigests() ObjectValidationOption {
+	return func(opts *ObjectValidation) {
+		opts.trustStored = true
+	}
+}
+
 // ValidationLogger sets the *slog.Logger that should be used for logging
 // validation errors and warnings.
 func ValidationLogger(logger *slog.Logger) ObjectValidationOption {
@@ -319,6 +393,14 @@ func ValidationLogger(logger *slog.Logger) ObjectValidationOption {
 	}
 }
 
+// ValidationTelemetry sets a telemetry provider used to instrument each phase
+// of the validation process.
+func ValidationTelemetry(p telemetry.Provider) ObjectValidationOption {
+	return func(v *ObjectValidation) {
+		v.objOptions = append(v.objOptions, ObjectWithTelemetry(p))
+	}
+}
+
 // ValidationDigestConcurrency is used to set the number of go routines used to
 // read and digest contents during validation.
 func ValidationDigestConcurrency(num int) ObjectValidationOption {
@@ -327,6 +409,74 @@ func ValidationDigestConcurrency(num int) ObjectValidationOption {
 	}
 }
 
+// ValidationVersions limits validation to the given versions. The object
+// root, root inventory, and version directory names are always validated, but
+// only the inventories and content files in the given version directories are
+// checked. A version's inventory is compared to the previous version's
+// inventory, even if the previous version isn't validated.
+func ValidationVersions(vnums ...VNum) ObjectValidationOption {
+	return func(v *ObjectValidation) {
+		if v.versions == nil {
+			v.versions = map[int]bool{}
+		}
+		for _, vnum := range vnums {
+			v.versions[vnum.Num()] = true
+		}
+	}
+}
+
+// ValidationHeadOnly is like [ValidationVersions] for the object's most
+// recent version. It can be used to verify an object after an update without
+// validating content from previous versions.
+func ValidationHeadOnly() ObjectValidationOption {
+	return func(v *ObjectValidation) {
+		v.headOnly = true
+	}
+}
+
+// versionSelected returns true if the version with the given number should be
+// validated.
+func (v *ObjectValidation) versionSelected(num int) bool {
+	return len(v.versions) == 0 || v.versions[num]
+}
+
+// selectVersions sets the versions to validate if the validation is limited
+// to the head version. It returns an error if any selected versions aren't
+// in the object with the given head.
+func (v *ObjectValidation) selectVersions(head VNum) error {
+	if v.headOnly {
+		v.versions = map[int]bool{head.Num(): true}
+	}
+	for num := range v.versions {
+		if num < 1 || num > head.Num() {
+			return fmt.Errorf("can't validate version %d: object head is %s", num, head)
+		}
+	}
+	return nil
+}
+
+// contentSelected returns true if the content file with the given path,
+// relative to the object root, is in a version directory being validated.
+func (v *ObjectValidation) contentSelected(name string) bool {
+	if len(v.versions) == 0 {
+		return true
+	}
+	var vnum VNum
+	dir, _, _ := strings.Cut(name, "/")
+	if err := ParseVNum(dir, &vnum); err != nil {
+		return true
+	}
+	return v.versions[vnum.Num()]
+}
+
+// ValidationWithPolicy sets a policy that changes the severity of validation
+// errors and warnings based on their validation codes.
+func ValidationWithPolicy(policy *ValidationPolicy) ObjectValidationOption {
+	return func(v *ObjectValidation) {
+		v.policy = policy
+	}
+}
+
 // ValidationAlgorithms sets registry of available digest algorithms for
 // fixity validation.
 func ValidationAlgorithms(reg digest.AlgorithmRegistry) ObjectValidationOption {
@@ -2669,3 +2669,262 @@ func W016(spec string) *validation.ValidationCode {
 		return nil
 	}
 }
+
+// Lookup returns the validation code with the given name (e.g., "E001") for
+// the OCFL spec. It returns nil if the code isn't defined for the spec.
+func Lookup(name string, spec string) *validation.ValidationCode {
+	switch name {
+	case "E001":
+		return E001(spec)
+	case "E002":
+		return E002(spec)
+	case "E003":
+		return E003(spec)
+	case "E004":
+		return E004(spec)
+	case "E005":
+		return E005(spec)
+	case "E006":
+		return E006(spec)
+	case "E007":
+		return E007(spec)
+	case "E008":
+		return E008(spec)
+	case "E009":
+		return E009(spec)
+	case "E010":
+		return E010(spec)
+	case "E011":
+		return E011(spec)
+	case "E012":
+		return E012(spec)
+	case "E013":
+		return E013(spec)
+	case "E014":
+		return E014(spec)
+	case "E015":
+		return E015(spec)
+	case "E016":
+		return E016(spec)
+	case "E017":
+		return E017(spec)
+	case "E018":
+		return E018(spec)
+	case "E019":
+		return E019(spec)
+	case "E020":
+		return E020(spec)
+	case "E021":
+		return E021(spec)
+	case "E022":
+		return E022(spec)
+	case "E023":
+		return E023(spec)
+	case "E024":
+		return E024(spec)
+	case "E025":
+		return E025(spec)
+	case "E026":
+		return E026(spec)
+	case "E027":
+		return E027(spec)
+	case "E028":
+		return E028(spec)
+	case "E029":
+		return E029(spec)
+	case "E030":
+		return E030(spec)
+	case "E031":
+		return E031(spec)
+	case "E032":
+		return E032(spec)
+	case "E033":
+		return E033(spec)
+	case "E034":
+		return E034(spec)
+	case "E035":
+		return E035(spec)
+	case "E036":
+		return E036(spec)
+	case "E037":
+		return E037(spec)
+	case "E038":
+		return E038(spec)
+	case "E039":
+		return E039(spec)
+	case "E040":
+		return E040(spec)
+	case "E041":
+		return E041(spec)
+	case "E042":
+		return E042(spec)
+	case "E043":
+		return E043(spec)
+	case "E044":
+		return E044(spec)
+	case "E045":
+		return E045(spec)
+	case "E046":
+		return E046(spec)
+	case "E047":
+		return E047(spec)
+	case "E048":
+		return E048(spec)
+	case "E049":
+		return E049(spec)
+	case "E050":
+		return E050(spec)
+	case "E051":
+		return E051(spec)
+	case "E052":
+		return E052(spec)
+	case "E053":
+		return E053(spec)
+	case "E054":
+		return E054(spec)
+	case "E055":
+		return E055(spec)
+	case "E056":
+		return E056(spec)
+	case "E057":
+		return E057(spec)
+	case "E058":
+		return E058(spec)
+	case "E059":
+		return E059(spec)
+	case "E060":
+		return E060(spec)
+	case "E061":
+		return E061(spec)
+	case "E062":
+		return E062(spec)
+	case "E063":
+		return E063(spec)
+	case "E064":
+		return E064(spec)
+	case "E066":
+		return E066(spec)
+	case "E067":
+		return E067(spec)
+	case "E068":
+		return E068(spec)
+	case "E069":
+		return E069(spec)
+	case "E070":
+		return E070(spec)
+	case "E071":
+		return E071(spec)
+	case "E072":
+		return E072(spec)
+	case "E073":
+		return E073(spec)
+	case "E074":
+		return E074(spec)
+	case "E075":
+		return E075(spec)
+	case "E076":
+		return E076(spec)
+	case "E077":
+		return E077(spec)
+	case "E078":
+		return E078(spec)
+	case "E079":
+		return E079(spec)
+	case "E080":
+		return E080(spec)
+	case "E081":
+		return E081(spec)
+	case "E082":
+		return E082(spec)
+	case "E083":
+		return E083(spec)
+	case "E084":
+		return E084(spec)
+	case "E085":
+		return E085(spec)
+	case "E086":
+		return E086(spec)
+	case "E087":
+		return E087(spec)
+	case "E088":
+		return E088(spec)
+	case "E089":
+		return E089(spec)
+	case "E090":
+		return E090(spec)
+	case "E091":
+		return E091(spec)
+	case "E092":
+		return E092(spec)
+	case "E093":
+		return E093(spec)
+	case "E094":
+		return E094(spec)
+	case "E095":
+		return E095(spec)
+	case "E096":
+		return E096(spec)
+	case "E097":
+		return E097(spec)
+	case "E098":
+		return E098(spec)
+	case "E099":
+		return E099(spec)
+	case "E100":
+		return E100(spec)
+	case "E101":
+		return E101(spec)
+	case "E102":
+		return E102(spec)
+	case "E103":
+		return E103(spec)
+	case "E104":
+		return E104(spec)
+	case "E105":
+		return E105(spec)
+	case "E106":
+		return E106(spec)
+	case "E107":
+		return E107(spec)
+	case "E108":
+		return E108(spec)
+	case "E110":
+		return E110(spec)
+	case "E111":
+		return E111(spec)
+	case "E112":
+		return E112(spec)
+	case "W001":
+		return W001(spec)
+	case "W002":
+		return W002(spec)
+	case "W003":
+		return W003(spec)
+	case "W004":
+		return W004(spec)
+	case "W005":
+		return W005(spec)
+	case "W007":
+		return W007(spec)
+	case "W008":
+		return W008(spec)
+	case "W009":
+		return W009(spec)
+	case "W010":
+		return W010(spec)
+	case "W011":
+		return W011(spec)
+	case "W012":
+		return W012(spec)
+	case "W013":
+		return W013(spec)
+	case "W014":
+		return W014(spec)
+	case "W015":
+		return W015(spec)
+	case "W016":
+		return W016(spec)
+	default:
+		return nil
+	}
+}